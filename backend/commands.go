@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+	"github.com/natneam/crypto-wallet-app/backend/internal/db"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+)
+
+const usage = `usage:
+  backend [flags]                run the API server, see backend -help for the flags
+  backend audit list [flags]     print audit entries as JSON lines, newest first
+  backend audit verify           check the audit log hash chain
+  backend migrate [up]           apply pending database migrations
+  backend migrate status         list migrations and when they were applied`
+
+// runCommand runs a maintenance subcommand instead of the server.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "audit":
+		return runAuditCommand(args[1:])
+	case "migrate":
+		return runMigrateCommand(args[1:])
+	}
+	return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+}
+
+func runAuditCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing audit subcommand\n%s", usage)
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return err
+	}
+	dbClient, err := db.Connect(cfg.Mongo.URI)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Disconnect(context.Background())
+	repository := repositories.NewRepository(dbClient, cfg.Mongo.Database)
+
+	switch args[0] {
+	case "list":
+		var query models.AuditQuery
+		var since, until string
+		flags := flag.NewFlagSet("audit list", flag.ExitOnError)
+		flags.StringVar(&query.UserID, "user", "", "only entries of this user id")
+		flags.StringVar(&query.Action, "action", "", "only entries with this action")
+		flags.StringVar(&query.Target, "target", "", "only entries with this target")
+		flags.StringVar(&since, "since", "", "only entries at or after this RFC 3339 time")
+		flags.StringVar(&until, "until", "", "only entries before this RFC 3339 time")
+		flags.Int64Var(&query.Limit, "limit", 100, "maximum number of entries, 0 for all")
+		flags.Parse(args[1:])
+		if query.Since, err = parseOptionalTime(since); err != nil {
+			return err
+		}
+		if query.Until, err = parseOptionalTime(until); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		entries, err := repository.ListAuditEntries(ctx, query)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "verify":
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		verification, err := services.VerifyAuditChain(ctx, repository)
+		if err != nil {
+			return err
+		}
+		if !verification.Valid {
+			return fmt.Errorf("audit log broken at entry %d after %d valid entries: %s",
+				verification.BrokenAt, verification.Entries, verification.Error)
+		}
+		fmt.Printf("audit log valid: %d entries, head hash %s\n", verification.Entries, verification.HeadHash)
+		return nil
+	}
+	return fmt.Errorf("unknown audit subcommand %q\n%s", args[0], usage)
+}
+
+func runMigrateCommand(args []string) error {
+	subcommand := "up"
+	if len(args) > 0 {
+		subcommand = args[0]
+	}
+	if subcommand != "up" && subcommand != "status" {
+		return fmt.Errorf("unknown migrate subcommand %q\n%s", subcommand, usage)
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return err
+	}
+	dbClient, err := db.Connect(cfg.Mongo.URI)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	if subcommand == "status" {
+		states, err := db.Migrations(ctx, dbClient, cfg.Mongo.Database)
+		if err != nil {
+			return err
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-25s  %s\n", state.Version, applied, state.Description)
+		}
+		return nil
+	}
+
+	applied, err := db.Migrate(ctx, dbClient, cfg.Mongo.Database)
+	for _, migration := range applied {
+		fmt.Printf("applied %d: %s\n", migration.Version, migration.Description)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("database is up to date")
+	}
+	return nil
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q, expected RFC 3339: %v", value, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,371 @@
+package e2e
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestSendTransaction(t *testing.T) {
+	h := New(t)
+	token := h.SignUpAndLogin("alice")
+
+	var wallet models.Wallet
+	if status := h.Do(http.MethodPost, "/api/wallet", token, map[string]string{"name": "main"}, &wallet); status != http.StatusOK {
+		t.Fatalf("create wallet returned %d", status)
+	}
+	from := common.HexToAddress(wallet.PublicKey)
+	h.Fund(from, ether(10))
+
+	var fetched models.Wallet
+	if status := h.Do(http.MethodGet, "/api/wallet/"+from.Hex(), token, nil, &fetched); status != http.StatusOK {
+		t.Fatalf("get wallet returned %d", status)
+	}
+	if fetched.Balance != "10.000000 ETH" {
+		t.Errorf("wallet balance = %q, want 10.000000 ETH", fetched.Balance)
+	}
+
+	to := common.HexToAddress("0x00000000000000000000000000000000000000e2")
+	value := ether(1)
+	var result models.TransactionResult
+	status := h.Do(http.MethodPost, "/api/sign-transaction", token, map[string]string{
+		"fromAddress": from.Hex(),
+		"toAddress":   to.Hex(),
+		"value":       value.String(),
+	}, &result)
+	if status != http.StatusOK {
+		t.Fatalf("sign-transaction returned %d", status)
+	}
+	if result.Status != models.TransactionStatusSuccess {
+		t.Errorf("transaction status = %q, want %q", result.Status, models.TransactionStatusSuccess)
+	}
+
+	// The receipt and balances on chain match the result
+	receipt := h.WaitMined(common.HexToHash(result.TransactionHash))
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Errorf("receipt status = %d, want success", receipt.Status)
+	}
+	if receipt.BlockNumber.Uint64() != result.BlockNumber {
+		t.Errorf("block number = %d, receipt has %d", result.BlockNumber, receipt.BlockNumber.Uint64())
+	}
+	if got := h.Balance(to); got.Cmp(value) != 0 {
+		t.Errorf("recipient balance = %s, want %s", got, value)
+	}
+	fee := new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+	want := new(big.Int).Sub(new(big.Int).Sub(ether(10), value), fee)
+	if got := h.Balance(from); got.Cmp(want) != 0 {
+		t.Errorf("sender balance = %s, want %s", got, want)
+	}
+
+	// The wallet and transaction were stored for the user
+	ctx := context.Background()
+	stored, err := h.Stores.Wallets.GetWallet(ctx, from.Hex())
+	if err != nil {
+		t.Fatalf("wallet was not stored: %v", err)
+	}
+	if stored.UserID == "" || stored.UserID != result.UserID {
+		t.Errorf("wallet owner = %q, transaction user = %q", stored.UserID, result.UserID)
+	}
+	history, err := h.Stores.Transactions.FindTransactions(ctx, from.Hex(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].TransactionHash != result.TransactionHash {
+		t.Errorf("stored transactions = %+v, want %s", history, result.TransactionHash)
+	}
+
+	verification, err := services.VerifyAuditChain(ctx, h.Repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Valid {
+		t.Errorf("audit chain is invalid: %+v", verification)
+	}
+
+	// The transaction was recorded in the metrics
+	resp, err := h.Admin.Client().Get(h.Admin.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	exposition, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, metric := range []string{`wallet_transactions_total{status="success"}`, "wallet_transaction_confirmation_seconds_count"} {
+		if !strings.Contains(string(exposition), metric) {
+			t.Errorf("metrics do not include %s", metric)
+		}
+	}
+}
+
+func TestSendFromOtherUsersWallet(t *testing.T) {
+	h := New(t)
+	alice := h.SignUpAndLogin("alice")
+	mallory := h.SignUpAndLogin("mallory")
+
+	var wallet models.Wallet
+	if status := h.Do(http.MethodPost, "/api/wallet", alice, map[string]string{"name": "main"}, &wallet); status != http.StatusOK {
+		t.Fatalf("create wallet returned %d", status)
+	}
+	from := common.HexToAddress(wallet.PublicKey)
+	h.Fund(from, ether(10))
+
+	status := h.Do(http.MethodPost, "/api/sign-transaction", mallory, map[string]string{
+		"fromAddress": from.Hex(),
+		"toAddress":   "0x00000000000000000000000000000000000000e2",
+		"value":       ether(1).String(),
+	}, nil)
+	if status == http.StatusOK {
+		t.Fatal("sending from another user's wallet succeeded")
+	}
+	if got := h.Balance(from); got.Cmp(ether(10)) != 0 {
+		t.Errorf("wallet balance = %s, want unchanged", got)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	h := New(t)
+
+	if status := h.Do(http.MethodGet, "/healthz", "", nil, nil); status != http.StatusOK {
+		t.Errorf("healthz returned %d", status)
+	}
+
+	// Not ready until the server has started
+	if status := h.Do(http.MethodGet, "/readyz", "", nil, nil); status != http.StatusServiceUnavailable {
+		t.Errorf("readyz before start returned %d, want 503", status)
+	}
+
+	h.Service.SetReady(true)
+	var readiness models.Readiness
+	if status := h.Do(http.MethodGet, "/readyz", "", nil, &readiness); status != http.StatusOK {
+		t.Fatalf("readyz returned %d: %+v", status, readiness)
+	}
+	for _, name := range []string{"ethereum", "kms", "mongodb"} {
+		if !slices.ContainsFunc(readiness.Checks, func(c models.HealthCheck) bool { return c.Name == name && c.Healthy }) {
+			t.Errorf("check %s missing or unhealthy in %+v", name, readiness.Checks)
+		}
+	}
+
+	var status models.ServiceStatus
+	if code := h.DoAdmin(http.MethodGet, "/status", &status); code != http.StatusOK {
+		t.Fatalf("status returned %d", code)
+	}
+	if status.Chain == nil || status.Chain.ChainID != "1337" {
+		t.Errorf("chain status = %+v (%s), want chain 1337", status.Chain, status.ChainError)
+	}
+	if status.Version.Version == "" || status.StorageBackend != h.StorageBackend {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Operator routes are not served on the public API
+	for _, path := range []string{"/status", "/metrics"} {
+		if code := h.Do(http.MethodGet, path, "", nil, nil); code != http.StatusNotFound {
+			t.Errorf("public %s returned %d, want 404", path, code)
+		}
+	}
+}
+
+func TestScheduleRunResumesAfterCrash(t *testing.T) {
+	h := New(t)
+	token := h.SignUpAndLogin("alice")
+
+	var wallet models.Wallet
+	if status := h.Do(http.MethodPost, "/api/wallet", token, map[string]string{"name": "main"}, &wallet); status != http.StatusOK {
+		t.Fatalf("create wallet returned %d", status)
+	}
+	from := common.HexToAddress(wallet.PublicKey)
+	h.Fund(from, ether(10))
+
+	to := common.HexToAddress("0x00000000000000000000000000000000000000e3")
+	value := ether(1)
+	runAt := time.Now().Add(time.Second)
+	var schedule models.Schedule
+	status := h.Do(http.MethodPost, "/api/schedules", token, map[string]any{
+		"name":        "rent",
+		"fromAddress": from.Hex(),
+		"toAddress":   to.Hex(),
+		"value":       value.String(),
+		"runAt":       runAt,
+	}, &schedule)
+	if status != http.StatusCreated {
+		t.Fatalf("create schedule returned %d", status)
+	}
+
+	// A scheduler signed and broadcast the transfer, then crashed before
+	// recording the run
+	ctx := context.Background()
+	stored, err := h.Stores.Wallets.GetWallet(ctx, from.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := h.Backend.Client()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := types.NewTransaction(0, to, value, 21000, gasPrice, nil)
+	signer := types.LatestSignerForChainID(chainID)
+	signature, err := h.Keys.SignDigest(ctx, stored.KMSKeyID, signer.Hash(tx).Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedTx, err := tx.WithSignature(signer, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Repo.SetPendingRun(ctx, schedule.ID, models.PendingRun{
+		StartedAt:       time.Now().UTC(),
+		TransactionHash: signedTx.Hash().Hex(),
+		RawTransaction:  hexutil.Encode(raw),
+		Nonce:           0,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendTransaction(ctx, signedTx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next scheduler to claim the schedule finishes the run without
+	// sending the transfer again
+	time.Sleep(time.Until(runAt))
+	if runs, err := h.Service.RunDueSchedules(ctx); err != nil || runs != 1 {
+		t.Fatalf("RunDueSchedules = %d, %v, want 1 run", runs, err)
+	}
+	if got := h.Balance(to); got.Cmp(value) != 0 {
+		t.Errorf("recipient balance = %s, want %s", got, value)
+	}
+
+	var runs []models.ScheduleRun
+	if status := h.Do(http.MethodGet, "/api/schedules/"+schedule.ID+"/runs", token, nil, &runs); status != http.StatusOK {
+		t.Fatalf("list runs returned %d", status)
+	}
+	if len(runs) != 1 || !runs[0].Success || runs[0].TransactionHash != signedTx.Hash().Hex() {
+		t.Errorf("runs = %+v, want one successful run of %s", runs, signedTx.Hash().Hex())
+	}
+	var finished models.Schedule
+	if status := h.Do(http.MethodGet, "/api/schedules/"+schedule.ID, token, nil, &finished); status != http.StatusOK {
+		t.Fatalf("get schedule returned %d", status)
+	}
+	if finished.Status != models.ScheduleStatusCompleted {
+		t.Errorf("schedule status = %q, want %q", finished.Status, models.ScheduleStatusCompleted)
+	}
+}
+
+func TestStepUpRequired(t *testing.T) {
+	h := New(t)
+	token := h.SignUpAndLogin("alice")
+
+	var wallet models.Wallet
+	if status := h.Do(http.MethodPost, "/api/wallet", token, map[string]string{"name": "main"}, &wallet); status != http.StatusOK {
+		t.Fatalf("create wallet returned %d", status)
+	}
+	from := wallet.PublicKey
+	h.Fund(common.HexToAddress(from), ether(10))
+
+	var setup models.TOTPSetup
+	if status := h.Do(http.MethodPost, "/api/2fa/setup", token, nil, &setup); status != http.StatusOK {
+		t.Fatalf("2fa setup returned %d", status)
+	}
+	code, err := totp.GenerateCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := h.Do(http.MethodPost, "/api/2fa/enable", token, map[string]string{"code": code}, nil); status != http.StatusOK {
+		t.Fatalf("2fa enable returned %d", status)
+	}
+
+	address := common.HexToAddress("0x00000000000000000000000000000000000000e4").Hex()
+	transfer := map[string]string{"fromAddress": from, "toAddress": address, "value": "1"}
+	routes := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{http.MethodPost, "/api/sign-transaction", transfer},
+		{http.MethodPost, "/api/contracts/transact", transfer},
+		{http.MethodPost, "/api/nfts/transfer", transfer},
+		{http.MethodPost, "/api/allowances/approve", transfer},
+		{http.MethodPost, "/api/allowances/revoke", transfer},
+		{http.MethodPost, "/api/sign-message", map[string]string{"address": from, "message": "hello"}},
+		{http.MethodPost, "/api/sign-typed-data", map[string]string{"address": from}},
+		{http.MethodPost, "/api/safes", map[string]any{"owners": []string{from}, "threshold": 1}},
+		{http.MethodPost, "/api/safes/" + address + "/transactions", map[string]string{"to": address}},
+		{http.MethodPost, "/api/safes/" + address + "/transactions/1/confirm", map[string]string{"address": from}},
+		{http.MethodPost, "/api/safes/" + address + "/transactions/1/execute", map[string]string{"address": from}},
+		{http.MethodPost, "/api/smart-accounts/" + address + "/operations", map[string]any{"calls": []map[string]string{{"to": address}}}},
+		{http.MethodPost, "/api/schedules", transfer},
+		{http.MethodPost, "/api/schedules/1/resume", nil},
+		{http.MethodPost, "/api/transactions/confirm", map[string]string{}},
+		{http.MethodPost, "/api/api-keys", map[string]any{"name": "ci", "scopes": []string{models.ScopeWalletsRead}}},
+		{http.MethodPost, "/api/webauthn/transaction-confirmation", map[string]bool{"enabled": false}},
+		{http.MethodPost, "/api/2fa/disable", map[string]string{}},
+		{http.MethodPost, "/api/2fa/recovery-codes", map[string]string{}},
+		{http.MethodDelete, "/api/webauthn/credentials/1", nil},
+		{http.MethodPost, "/api/organisations/1/members", map[string]string{"username": "bob", "role": "admin"}},
+		{http.MethodPut, "/api/organisations/1/members/1", map[string]string{"role": "admin"}},
+		{http.MethodDelete, "/api/organisations/1/members/1", nil},
+	}
+	for _, route := range routes {
+		if status := h.Do(route.method, route.path, token, route.body, nil); status != http.StatusForbidden {
+			t.Errorf("%s %s without a code returned %d, want 403", route.method, route.path, status)
+		}
+	}
+
+	if got := h.Balance(common.HexToAddress(address)); got.Sign() != 0 {
+		t.Errorf("recipient balance = %s, want nothing sent", got)
+	}
+}
+
+func TestStepUpGuessesAreThrottled(t *testing.T) {
+	h := New(t)
+	token := h.SignUpAndLogin("alice")
+
+	var setup models.TOTPSetup
+	if status := h.Do(http.MethodPost, "/api/2fa/setup", token, nil, &setup); status != http.StatusOK {
+		t.Fatalf("2fa setup returned %d", status)
+	}
+	code, err := totp.GenerateCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := h.Do(http.MethodPost, "/api/2fa/enable", token, map[string]string{"code": code}, nil); status != http.StatusOK {
+		t.Fatalf("2fa enable returned %d", status)
+	}
+
+	// Wrong codes are answered with 403 until the username is delayed
+	body := map[string]string{"name": "ci"}
+	for i := 0; i < 3; i++ {
+		if status := h.DoStepUp(http.MethodPost, "/api/api-keys", token, "bad-code", body, nil); status != http.StatusForbidden {
+			t.Fatalf("wrong code %d returned %d, want 403", i+1, status)
+		}
+	}
+	if status := h.DoStepUp(http.MethodPost, "/api/api-keys", token, "bad-code", body, nil); status != http.StatusTooManyRequests {
+		t.Fatalf("step up after 3 wrong codes returned %d, want 429", status)
+	}
+	// Codes in the request body count against the same limit
+	if status := h.Do(http.MethodPost, "/api/2fa/disable", token, map[string]string{"code": "bad-code"}, nil); status != http.StatusTooManyRequests {
+		t.Fatalf("2fa disable after 3 wrong codes returned %d, want 429", status)
+	}
+}
@@ -0,0 +1,386 @@
+// Package e2e boots the API against a simulated Ethereum chain, a local
+// signer and a MongoDB database or the memory store, and drives it over HTTP.
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+	"github.com/natneam/crypto-wallet-app/backend/internal/db"
+	"github.com/natneam/crypto-wallet-app/backend/internal/handlers"
+	"github.com/natneam/crypto-wallet-app/backend/internal/kms"
+	"github.com/natneam/crypto-wallet-app/backend/internal/mailer"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/memory"
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// blockInterval is how often the simulated chain mines a block.
+const blockInterval = 100 * time.Millisecond
+
+// Harness is a running API backed by a simulated chain.
+type Harness struct {
+	t      *testing.T
+	Server *httptest.Server
+	// Admin serves the operator routes, like the admin port
+	Admin   *httptest.Server
+	Service *services.Service
+	Backend *simulated.Backend
+	Repo    repositories.Store
+	Stores  repositories.Stores
+	// StorageBackend is "mongo" or "memory"
+	StorageBackend string
+	Keys           *kms.LocalKeyManager
+	Mail           *CaptureMailer
+
+	faucet *ecdsa.PrivateKey
+}
+
+// New starts a harness on a fresh database of the MongoDB server at
+// MONGO_TEST_URI, or on the memory store if it is not set. Everything is torn
+// down when the test finishes.
+func New(t *testing.T) *Harness {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	cfg := config.Defaults()
+
+	var repo repositories.Store
+	var stores repositories.Stores
+	if uri := os.Getenv("MONGO_TEST_URI"); uri != "" {
+		repository := newMongoRepository(t, uri)
+		repo, stores = repository, repository.Stores()
+	} else {
+		store := memory.NewStore()
+		repo, stores = store, store.Stores()
+		cfg.Storage.Backend = "memory"
+	}
+
+	// The faucet account funds the wallets created by the tests
+	faucet, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		crypto.PubkeyToAddress(faucet.PublicKey): {Balance: ether(1000)},
+	})
+	// The genesis block is dated 1970, so mine one now for the readiness check
+	backend.Commit()
+	stop := make(chan struct{})
+	var mining sync.WaitGroup
+	mining.Add(1)
+	go func() {
+		defer mining.Done()
+		ticker := time.NewTicker(blockInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				backend.Commit()
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		mining.Wait()
+		backend.Close()
+	})
+
+	cfg.JWT.Secret = "e2e-signing-key-of-at-least-32-bytes"
+	webAuthn, err := cfg.WebAuthn.RelyingParty()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := kms.NewLocalKeyManager()
+	mail := &CaptureMailer{}
+	service := services.NewService(services.Dependencies{
+		Config:   &cfg,
+		Repo:     repo,
+		Stores:   stores,
+		Web3:     backend.Client(),
+		Keys:     keys,
+		WebAuthn: webAuthn,
+		Mailer:   mail,
+	})
+
+	router := gin.New()
+	handlers.NewHandler(router, service)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	adminRouter := gin.New()
+	handlers.NewAdminHandler(adminRouter, service)
+	admin := httptest.NewServer(adminRouter)
+	t.Cleanup(admin.Close)
+
+	return &Harness{
+		t:       t,
+		Server:  server,
+		Admin:   admin,
+		Service: service,
+		Backend: backend,
+		Repo:    repo,
+		Stores:  stores,
+		Keys:    keys,
+		Mail:    mail,
+		faucet:  faucet,
+
+		StorageBackend: cfg.Storage.Backend,
+	}
+}
+
+// newMongoRepository connects to the MongoDB server at uri and returns a
+// repository on a fresh database, dropped when the test finishes.
+func newMongoRepository(t *testing.T, uri string) *repositories.Repository {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	database := fmt.Sprintf("e2e_%d", time.Now().UnixNano())
+	if err := db.InitDatabase(client, database); err != nil {
+		t.Fatalf("failed to initialise database: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		client.Database(database).Drop(ctx)
+		client.Disconnect(ctx)
+	})
+
+	return repositories.NewRepository(client, database)
+}
+
+// Do sends a JSON request to the API and decodes the response into out,
+// returning the status code. token is sent as a bearer token if set.
+func (h *Harness) Do(method, path, token string, body, out any) int {
+	h.t.Helper()
+	return h.do(h.Server, method, path, token, nil, body, out)
+}
+
+// DoStepUp is Do with a two-factor code in the X-TOTP-Code header.
+func (h *Harness) DoStepUp(method, path, token, code string, body, out any) int {
+	h.t.Helper()
+	return h.do(h.Server, method, path, token, map[string]string{"X-TOTP-Code": code}, body, out)
+}
+
+// DoAdmin is Do for the operator routes.
+func (h *Harness) DoAdmin(method, path string, out any) int {
+	h.t.Helper()
+	return h.do(h.Admin, method, path, "", nil, nil, out)
+}
+
+func (h *Harness) do(server *httptest.Server, method, path, token string, headers map[string]string, body, out any) int {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		h.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			h.t.Fatalf("%s %s: failed to decode %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// SignUpAndLogin creates a user, verifies its email address with the link
+// from the verification email and logs in, returning the access token.
+func (h *Harness) SignUpAndLogin(username string) string {
+	h.t.Helper()
+
+	password := "correct horse battery staple"
+	status := h.Do(http.MethodPost, "/api/signup", "", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"password": password,
+	}, nil)
+	if status != http.StatusCreated {
+		h.t.Fatalf("signup returned %d", status)
+	}
+
+	token := h.Mail.Token(h.t, username+"@example.com", "/verify-email")
+	if status := h.Do(http.MethodPost, "/api/verify-email", "", map[string]string{"token": token}, nil); status != http.StatusOK {
+		h.t.Fatalf("verify-email returned %d", status)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	status = h.Do(http.MethodPost, "/api/login", "", map[string]string{
+		"username": username,
+		"password": password,
+	}, &login)
+	if status != http.StatusOK || login.Token == "" {
+		h.t.Fatalf("login returned %d", status)
+	}
+	return login.Token
+}
+
+// Fund sends value wei from the faucet to address and waits for the
+// transfer to be mined.
+func (h *Harness) Fund(address common.Address, value *big.Int) {
+	h.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := h.Backend.Client()
+	from := crypto.PubkeyToAddress(h.faucet.PublicKey)
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+
+	tx, err := types.SignTx(types.NewTransaction(nonce, address, value, 21000, gasPrice, nil),
+		types.LatestSignerForChainID(chainID), h.faucet)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		h.t.Fatalf("failed to fund %s: %v", address.Hex(), err)
+	}
+	h.WaitMined(tx.Hash())
+}
+
+// WaitMined waits for a transaction to be mined and returns its receipt.
+func (h *Harness) WaitMined(hash common.Hash) *types.Receipt {
+	h.t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for {
+		receipt, err := h.Backend.Client().TransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt
+		}
+		if err != ethereum.NotFound {
+			h.t.Fatalf("failed to get receipt of %s: %v", hash.Hex(), err)
+		}
+		select {
+		case <-ctx.Done():
+			h.t.Fatalf("transaction %s was not mined", hash.Hex())
+		case <-time.After(blockInterval):
+		}
+	}
+}
+
+// Balance returns the balance of address at the latest block.
+func (h *Harness) Balance(address common.Address) *big.Int {
+	h.t.Helper()
+
+	balance, err := h.Backend.Client().BalanceAt(context.Background(), address, nil)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return balance
+}
+
+// CaptureMailer keeps sent emails so tests can follow the links in them.
+type CaptureMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *CaptureMailer) Send(ctx context.Context, message mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Token returns the token of the last link to path sent to an address.
+func (m *CaptureMailer) Token(t *testing.T, to string, path string) string {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To != to {
+			continue
+		}
+		for _, field := range strings.Fields(m.messages[i].Body) {
+			link, err := url.Parse(field)
+			if err != nil || !strings.HasSuffix(link.Path, path) {
+				continue
+			}
+			if token := link.Query().Get("token"); token != "" {
+				return token
+			}
+		}
+	}
+	t.Fatalf("no %s link was sent to %s", path, to)
+	return ""
+}
+
+// ether converts a whole number of ether to wei.
+func ether(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1e18))
+}
@@ -1,13 +1,42 @@
 package config
 
 import (
-	"log"
+	"fmt"
 
-	"github.com/joho/godotenv"
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
-func Load() {
-	if err := godotenv.Load(); err != nil {
-		log.Printf("No .env file found. Using default or environment variables.")
+// JWTKeys holds the HMAC keys used to sign and verify access tokens, indexed
+// by key id. New tokens are signed with ActiveKID; the other keys are kept so
+// tokens issued before a rotation stay valid until they expire.
+type JWTKeys struct {
+	Keys      map[string][]byte
+	ActiveKID string
+}
+
+// SigningKeys returns the configured keys, or the single Secret under the
+// "default" key id when no Keys are set.
+func (j JWT) SigningKeys() JWTKeys {
+	if len(j.Keys) == 0 {
+		return JWTKeys{Keys: map[string][]byte{"default": []byte(j.Secret)}, ActiveKID: "default"}
+	}
+
+	keys := JWTKeys{Keys: map[string][]byte{}, ActiveKID: j.ActiveKID}
+	for kid, secret := range j.Keys {
+		keys.Keys[kid] = []byte(secret)
+	}
+	return keys
+}
+
+// RelyingParty returns the WebAuthn relying party.
+func (w WebAuthn) RelyingParty() (*webauthn.WebAuthn, error) {
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          w.RPID,
+		RPDisplayName: w.RPName,
+		RPOrigins:     w.RPOrigins,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebAuthn configuration: %v", err)
 	}
+	return webAuthn, nil
 }
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider is the identity provider used for single sign-on.
+type OIDCProvider struct {
+	Issuer   string
+	OAuth2   oauth2.Config
+	Verifier *oidc.IDTokenVerifier
+
+	GroupsClaim string
+	GroupRoles  []OIDCGroupRole
+	AdminGroups []string
+}
+
+// OIDCGroupRole maps an identity provider group to an organisation role.
+type OIDCGroupRole struct {
+	Group          string `json:"group"`
+	OrganisationID string `json:"organisationId"`
+	Role           string `json:"role"`
+}
+
+// Provider discovers the identity provider at IssuerURL. Single sign-on is
+// disabled, and nil returned, when it is not set.
+func (o OIDC) Provider() (*OIDCProvider, error) {
+	if o.IssuerURL == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, o.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %v", err)
+	}
+
+	return &OIDCProvider{
+		Issuer: o.IssuerURL,
+		OAuth2: oauth2.Config{
+			ClientID:     o.ClientID,
+			ClientSecret: o.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			RedirectURL:  o.RedirectURL,
+			Scopes:       o.Scopes,
+		},
+		Verifier:    provider.Verifier(&oidc.Config{ClientID: o.ClientID}),
+		GroupsClaim: o.GroupsClaim,
+		GroupRoles:  o.GroupRoles,
+		AdminGroups: o.AdminGroups,
+	}, nil
+}
@@ -0,0 +1,601 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/joho/godotenv"
+)
+
+// Config is the configuration of the server and the commands. Each setting
+// is read from, in increasing priority: its default, the JSON file named by
+// -config or CONFIG_FILE, its environment variable (a .env file is loaded
+// into the environment first) and its command-line flag.
+type Config struct {
+	Port           int      `json:"port"`
+	TrustedProxies []string `json:"trustedProxies"`
+
+	// AdminPort serves /status and /metrics, which reveal the deployment and
+	// must not be reachable through the public API.
+	AdminPort int `json:"adminPort"`
+
+	// On SIGTERM the instance reports itself not ready for DrainDelay, so
+	// load balancers stop sending it requests, then waits up to
+	// ShutdownTimeout for in-flight requests and background work to finish.
+	DrainDelay      Duration `json:"drainDelay"`
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+
+	// AppURL is the frontend base URL used in email links.
+	AppURL     string `json:"appUrl"`
+	TOTPIssuer string `json:"totpIssuer"`
+
+	Mongo     Mongo     `json:"mongo"`
+	Storage   Storage   `json:"storage"`
+	Ethereum  Ethereum  `json:"ethereum"`
+	Contracts Contracts `json:"contracts"`
+	KMS       KMS       `json:"kms"`
+	JWT       JWT       `json:"jwt"`
+	WebAuthn  WebAuthn  `json:"webauthn"`
+	OIDC      OIDC      `json:"oidc"`
+	Mail      Mail      `json:"mail"`
+	Tracing   Tracing   `json:"tracing"`
+}
+
+// Duration is a time.Duration written as a string such as "30s" in
+// configuration files.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("durations are strings such as \"30s\": %v", err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+type Mongo struct {
+	URI      string `json:"uri"`
+	Database string `json:"database"`
+}
+
+// Storage selects where users, wallets and transactions are kept: "mongo"
+// or "postgres". Every other store is in MongoDB either way.
+type Storage struct {
+	Backend     string `json:"backend"`
+	PostgresURL string `json:"postgresUrl"`
+}
+
+type Ethereum struct {
+	RPCURL       string `json:"rpcUrl"`
+	BundlerURL   string `json:"bundlerUrl"`
+	PaymasterURL string `json:"paymasterUrl"`
+
+	// MaxBlockAge is how old the node's head block may be before the
+	// instance reports itself not ready.
+	MaxBlockAge Duration `json:"maxBlockAge"`
+}
+
+// Contracts overrides the addresses of the Safe{Wallet} and ERC-4337
+// contracts. Empty addresses use the canonical deployments.
+type Contracts struct {
+	SafeProxyFactory    string `json:"safeProxyFactory"`
+	SafeSingleton       string `json:"safeSingleton"`
+	SafeFallbackHandler string `json:"safeFallbackHandler"`
+	EntryPoint          string `json:"entryPoint"`
+	AccountFactory      string `json:"accountFactory"`
+}
+
+// KMS selects where wallet keys are kept: "aws" or "local".
+type KMS struct {
+	Provider string `json:"provider"`
+}
+
+// JWT holds the access token signing keys by key id. New tokens are signed
+// with ActiveKID; the other keys are kept so tokens issued before a rotation
+// stay valid until they expire. A single Secret may be set instead of Keys.
+type JWT struct {
+	Secret    string            `json:"secret"`
+	Keys      map[string]string `json:"keys"`
+	ActiveKID string            `json:"activeKid"`
+}
+
+// WebAuthn configures the passkey relying party.
+type WebAuthn struct {
+	RPID      string   `json:"rpId"`
+	RPName    string   `json:"rpName"`
+	RPOrigins []string `json:"rpOrigins"`
+}
+
+// OIDC configures single sign-on with the identity provider at IssuerURL,
+// which is off when IssuerURL is empty. RedirectURL is the frontend page the
+// provider returns to, which posts the code to /api/login/oidc/finish.
+//
+// GroupsClaim names the ID token claim listing the user's groups. GroupRoles
+// give members of a group a role in an organisation, and members of
+// AdminGroups are administrators.
+type OIDC struct {
+	IssuerURL    string          `json:"issuerUrl"`
+	ClientID     string          `json:"clientId"`
+	ClientSecret string          `json:"clientSecret"`
+	RedirectURL  string          `json:"redirectUrl"`
+	Scopes       []string        `json:"scopes"`
+	GroupsClaim  string          `json:"groupsClaim"`
+	GroupRoles   []OIDCGroupRole `json:"groupRoles"`
+	AdminGroups  []string        `json:"adminGroups"`
+}
+
+// Mail selects how emails are sent: "console" logs them, "file" writes them
+// to Dir and "smtp" sends them through the SMTP server.
+type Mail struct {
+	Mailer       string `json:"mailer"`
+	From         string `json:"from"`
+	Dir          string `json:"dir"`
+	SMTPHost     string `json:"smtpHost"`
+	SMTPPort     int    `json:"smtpPort"`
+	SMTPUsername string `json:"smtpUsername"`
+	SMTPPassword string `json:"smtpPassword"`
+}
+
+// Tracing exports traces over OTLP/HTTP to Endpoint, such as
+// http://localhost:4318 for a local collector. Tracing is off when Endpoint
+// is empty. SampleRatio is the fraction of new traces that are recorded.
+type Tracing struct {
+	Endpoint    string  `json:"endpoint"`
+	ServiceName string  `json:"serviceName"`
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// Defaults returns the configuration used for settings that are not set.
+func Defaults() Config {
+	return Config{
+		Port:            8085,
+		AdminPort:       9090,
+		DrainDelay:      Duration{10 * time.Second},
+		ShutdownTimeout: Duration{75 * time.Second},
+		AppURL:          "http://localhost:3000",
+		TOTPIssuer:      "Crypto Wallet",
+		Mongo:           Mongo{Database: "walletdb"},
+		Storage:         Storage{Backend: "mongo"},
+		Ethereum:        Ethereum{MaxBlockAge: Duration{2 * time.Minute}},
+		KMS:             KMS{Provider: "aws"},
+		Mail:            Mail{Mailer: "console", From: "no-reply@localhost", Dir: "mail", SMTPPort: 587},
+		Tracing:         Tracing{ServiceName: "crypto-wallet-backend", SampleRatio: 1},
+		WebAuthn: WebAuthn{
+			RPID:      "localhost",
+			RPName:    "Crypto Wallet",
+			RPOrigins: []string{"http://localhost:3000"},
+		},
+		OIDC: OIDC{
+			RedirectURL: "http://localhost:3000/oidc/callback",
+			Scopes:      []string{"openid", "profile", "email"},
+			GroupsClaim: "groups",
+		},
+	}
+}
+
+// setting is a configuration value settable from the environment and, if
+// flag is set, from the command line.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+func stringSetting(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func intSetting(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func floatSetting(field func(c *Config) *float64) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+func durationSetting(field func(c *Config) *Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s", value)
+		}
+		field(c).Duration = duration
+		return nil
+	}
+}
+
+func listSetting(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		var values []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		*field(c) = values
+		return nil
+	}
+}
+
+// jwtKeysSetting parses "kid1:secret1,kid2:secret2".
+func jwtKeysSetting(c *Config, value string) error {
+	keys := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || kid == "" || secret == "" {
+			return fmt.Errorf("invalid entry %q, expected kid:secret", entry)
+		}
+		keys[kid] = secret
+	}
+	c.JWT.Keys = keys
+	return nil
+}
+
+// oidcScopesSetting accepts scopes separated by commas or spaces.
+func oidcScopesSetting(c *Config, value string) error {
+	c.OIDC.Scopes = strings.Fields(strings.ReplaceAll(value, ",", " "))
+	return nil
+}
+
+// oidcGroupRolesSetting parses "group=organisationId:role,...".
+func oidcGroupRolesSetting(c *Config, value string) error {
+	var groupRoles []OIDCGroupRole
+	for _, entry := range strings.Split(value, ",") {
+		group, target, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		organisationID, role, ok2 := strings.Cut(target, ":")
+		if !ok || !ok2 {
+			return fmt.Errorf("invalid entry %q, expected group=organisationId:role", entry)
+		}
+		groupRoles = append(groupRoles, OIDCGroupRole{Group: group, OrganisationID: organisationID, Role: role})
+	}
+	c.OIDC.GroupRoles = groupRoles
+	return nil
+}
+
+var settings = []setting{
+	{"PORT", "port", "HTTP port to listen on", intSetting(func(c *Config) *int { return &c.Port })},
+	{"ADMIN_PORT", "admin-port", "port serving /status and /metrics, kept off the public API", intSetting(func(c *Config) *int { return &c.AdminPort })},
+	{"TRUSTED_PROXIES", "trusted-proxies", "comma separated proxies whose forwarded headers are trusted", listSetting(func(c *Config) *[]string { return &c.TrustedProxies })},
+	{"DRAIN_DELAY", "drain-delay", "how long to report not ready before shutting down", durationSetting(func(c *Config) *Duration { return &c.DrainDelay })},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long to wait for requests and background work when shutting down", durationSetting(func(c *Config) *Duration { return &c.ShutdownTimeout })},
+	{"APP_URL", "app-url", "frontend base URL used in email links", stringSetting(func(c *Config) *string { return &c.AppURL })},
+	{"TOTP_ISSUER", "totp-issuer", "issuer shown in authenticator apps", stringSetting(func(c *Config) *string { return &c.TOTPIssuer })},
+	{"MONGO_URI", "mongo-uri", "MongoDB connection string", stringSetting(func(c *Config) *string { return &c.Mongo.URI })},
+	{"MONGO_DATABASE", "mongo-database", "MongoDB database name", stringSetting(func(c *Config) *string { return &c.Mongo.Database })},
+	{"STORAGE_BACKEND", "storage-backend", "where users, wallets and transactions are kept: mongo or postgres (everything else stays in MongoDB)", stringSetting(func(c *Config) *string { return &c.Storage.Backend })},
+	{"POSTGRES_URL", "postgres-url", "PostgreSQL connection string", stringSetting(func(c *Config) *string { return &c.Storage.PostgresURL })},
+	{"SEPOLIA_URL", "rpc-url", "Ethereum JSON-RPC endpoint", stringSetting(func(c *Config) *string { return &c.Ethereum.RPCURL })},
+	{"BUNDLER_URL", "bundler-url", "ERC-4337 bundler endpoint", stringSetting(func(c *Config) *string { return &c.Ethereum.BundlerURL })},
+	{"PAYMASTER_URL", "paymaster-url", "ERC-4337 paymaster endpoint", stringSetting(func(c *Config) *string { return &c.Ethereum.PaymasterURL })},
+	{"MAX_BLOCK_AGE", "max-block-age", "how old the node's head block may be before readiness fails", durationSetting(func(c *Config) *Duration { return &c.Ethereum.MaxBlockAge })},
+	{"SAFE_PROXY_FACTORY_ADDRESS", "safe-proxy-factory", "Safe proxy factory contract, the canonical v1.4.1 one if empty", stringSetting(func(c *Config) *string { return &c.Contracts.SafeProxyFactory })},
+	{"SAFE_SINGLETON_ADDRESS", "safe-singleton", "Safe singleton contract, the canonical v1.4.1 one if empty", stringSetting(func(c *Config) *string { return &c.Contracts.SafeSingleton })},
+	{"SAFE_FALLBACK_HANDLER_ADDRESS", "safe-fallback-handler", "Safe fallback handler contract, the canonical v1.4.1 one if empty", stringSetting(func(c *Config) *string { return &c.Contracts.SafeFallbackHandler })},
+	{"ENTRYPOINT_ADDRESS", "entrypoint", "ERC-4337 EntryPoint contract, the canonical v0.6 one if empty", stringSetting(func(c *Config) *string { return &c.Contracts.EntryPoint })},
+	{"ACCOUNT_FACTORY_ADDRESS", "account-factory", "ERC-4337 account factory contract, the canonical SimpleAccount one if empty", stringSetting(func(c *Config) *string { return &c.Contracts.AccountFactory })},
+	{"KMS_PROVIDER", "kms-provider", "where wallet keys are kept: aws or local", stringSetting(func(c *Config) *string { return &c.KMS.Provider })},
+	{"JWT_ACTIVE_KID", "jwt-active-kid", "id of the JWT_KEYS key new access tokens are signed with", stringSetting(func(c *Config) *string { return &c.JWT.ActiveKID })},
+	{"WEBAUTHN_RP_ID", "webauthn-rp-id", "WebAuthn relying party id, the frontend's domain", stringSetting(func(c *Config) *string { return &c.WebAuthn.RPID })},
+	{"WEBAUTHN_RP_NAME", "webauthn-rp-name", "WebAuthn relying party name shown by authenticators", stringSetting(func(c *Config) *string { return &c.WebAuthn.RPName })},
+	{"WEBAUTHN_RP_ORIGINS", "webauthn-rp-origins", "comma separated origins passkeys may be used from", listSetting(func(c *Config) *[]string { return &c.WebAuthn.RPOrigins })},
+	{"OIDC_ISSUER_URL", "oidc-issuer-url", "OIDC identity provider for single sign-on, off if empty", stringSetting(func(c *Config) *string { return &c.OIDC.IssuerURL })},
+	{"OIDC_CLIENT_ID", "oidc-client-id", "OIDC client id", stringSetting(func(c *Config) *string { return &c.OIDC.ClientID })},
+	{"OIDC_REDIRECT_URL", "oidc-redirect-url", "frontend page the identity provider returns to", stringSetting(func(c *Config) *string { return &c.OIDC.RedirectURL })},
+	{"OIDC_SCOPES", "oidc-scopes", "OIDC scopes requested, separated by commas or spaces", oidcScopesSetting},
+	{"OIDC_GROUPS_CLAIM", "oidc-groups-claim", "ID token claim listing the user's groups", stringSetting(func(c *Config) *string { return &c.OIDC.GroupsClaim })},
+	{"OIDC_GROUP_ROLES", "oidc-group-roles", "organisation roles of groups as group=organisationId:role,...", oidcGroupRolesSetting},
+	{"OIDC_ADMIN_GROUPS", "oidc-admin-groups", "comma separated groups whose members are administrators", listSetting(func(c *Config) *[]string { return &c.OIDC.AdminGroups })},
+	{"MAILER", "mailer", "how emails are sent: console, file or smtp", stringSetting(func(c *Config) *string { return &c.Mail.Mailer })},
+	{"MAIL_FROM", "mail-from", "sender address of emails", stringSetting(func(c *Config) *string { return &c.Mail.From })},
+	{"MAIL_DIR", "mail-dir", "directory the file mailer writes to", stringSetting(func(c *Config) *string { return &c.Mail.Dir })},
+	{"SMTP_HOST", "smtp-host", "SMTP server host", stringSetting(func(c *Config) *string { return &c.Mail.SMTPHost })},
+	{"SMTP_PORT", "smtp-port", "SMTP server port", intSetting(func(c *Config) *int { return &c.Mail.SMTPPort })},
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", "otlp-endpoint", "OTLP/HTTP collector traces are exported to, tracing is off if empty", stringSetting(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"OTEL_SERVICE_NAME", "service-name", "service name traces are reported under", stringSetting(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"TRACE_SAMPLE_RATIO", "trace-sample-ratio", "fraction of new traces that are recorded", floatSetting(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	// Credentials are not accepted as flags, which other users can read
+	{"SMTP_USERNAME", "", "", stringSetting(func(c *Config) *string { return &c.Mail.SMTPUsername })},
+	{"SMTP_PASSWORD", "", "", stringSetting(func(c *Config) *string { return &c.Mail.SMTPPassword })},
+	{"JWT_SECRET", "", "", stringSetting(func(c *Config) *string { return &c.JWT.Secret })},
+	{"JWT_KEYS", "", "", jwtKeysSetting},
+	{"OIDC_CLIENT_SECRET", "", "", stringSetting(func(c *Config) *string { return &c.OIDC.ClientSecret })},
+}
+
+// Load reads and validates the configuration, taking flags from args.
+func Load(args []string) (*Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Printf("No .env file found. Using default or environment variables.")
+	}
+
+	// Flags are applied last, so their values are kept until then
+	flags := flag.NewFlagSet("backend", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "JSON configuration file")
+	flagValues := map[string]string{}
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		name := s.flag
+		flags.Func(name, s.usage+" ($"+s.env+")", func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	cfg := Defaults()
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.env); ok && value != "" {
+			if err := s.set(&cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", s.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := flagValues[s.flag]; ok && s.flag != "" {
+			if err := s.set(&cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %v", s.flag, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, configError(errs)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %v", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %v", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Port < 1 || c.Port > 65535 {
+		invalid("PORT %d is not a valid port", c.Port)
+	}
+	if c.AdminPort < 1 || c.AdminPort > 65535 {
+		invalid("ADMIN_PORT %d is not a valid port", c.AdminPort)
+	} else if c.AdminPort == c.Port {
+		invalid("ADMIN_PORT must differ from PORT")
+	}
+	if c.DrainDelay.Duration < 0 {
+		invalid("DRAIN_DELAY must not be negative")
+	}
+	if c.ShutdownTimeout.Duration <= 0 {
+		invalid("SHUTDOWN_TIMEOUT must be positive")
+	}
+	if err := checkURL(c.AppURL, "http", "https"); err != nil {
+		invalid("APP_URL: %v", err)
+	}
+
+	if c.Mongo.URI == "" {
+		invalid("MONGO_URI must be set, whatever the STORAGE_BACKEND")
+	} else if err := checkURL(c.Mongo.URI, "mongodb", "mongodb+srv"); err != nil {
+		invalid("MONGO_URI: %v", err)
+	}
+	if c.Mongo.Database == "" || strings.ContainsAny(c.Mongo.Database, `/\. "$`) {
+		invalid("MONGO_DATABASE %q is not a valid database name", c.Mongo.Database)
+	}
+
+	switch c.Storage.Backend {
+	case "mongo":
+	case "postgres":
+		if c.Storage.PostgresURL == "" {
+			invalid("POSTGRES_URL must be set when STORAGE_BACKEND=postgres")
+		} else if err := checkURL(c.Storage.PostgresURL, "postgres", "postgresql"); err != nil {
+			invalid("POSTGRES_URL: %v", err)
+		}
+	default:
+		invalid("unknown STORAGE_BACKEND %q, expected mongo or postgres", c.Storage.Backend)
+	}
+
+	if c.Ethereum.RPCURL == "" {
+		invalid("SEPOLIA_URL must be set")
+	} else if err := checkURL(c.Ethereum.RPCURL, "http", "https", "ws", "wss"); err != nil {
+		invalid("SEPOLIA_URL: %v", err)
+	}
+	if c.Ethereum.BundlerURL != "" {
+		if err := checkURL(c.Ethereum.BundlerURL, "http", "https", "ws", "wss"); err != nil {
+			invalid("BUNDLER_URL: %v", err)
+		}
+	}
+	if c.Ethereum.PaymasterURL != "" {
+		if c.Ethereum.BundlerURL == "" {
+			invalid("PAYMASTER_URL needs BUNDLER_URL to be set")
+		} else if err := checkURL(c.Ethereum.PaymasterURL, "http", "https", "ws", "wss"); err != nil {
+			invalid("PAYMASTER_URL: %v", err)
+		}
+	}
+
+	if c.Ethereum.MaxBlockAge.Duration <= 0 {
+		invalid("MAX_BLOCK_AGE must be positive")
+	}
+
+	for _, contract := range []struct{ env, address string }{
+		{"SAFE_PROXY_FACTORY_ADDRESS", c.Contracts.SafeProxyFactory},
+		{"SAFE_SINGLETON_ADDRESS", c.Contracts.SafeSingleton},
+		{"SAFE_FALLBACK_HANDLER_ADDRESS", c.Contracts.SafeFallbackHandler},
+		{"ENTRYPOINT_ADDRESS", c.Contracts.EntryPoint},
+		{"ACCOUNT_FACTORY_ADDRESS", c.Contracts.AccountFactory},
+	} {
+		if contract.address != "" && !common.IsHexAddress(contract.address) {
+			invalid("%s %q is not an address", contract.env, contract.address)
+		}
+	}
+
+	if c.KMS.Provider != "aws" && c.KMS.Provider != "local" {
+		invalid("unknown KMS_PROVIDER %q, expected aws or local", c.KMS.Provider)
+	}
+
+	if len(c.JWT.Keys) == 0 && c.JWT.Secret == "" {
+		invalid("JWT_SECRET or JWT_KEYS must be set")
+	} else {
+		if _, ok := c.JWT.Keys[c.JWT.ActiveKID]; len(c.JWT.Keys) > 0 && !ok {
+			invalid("JWT_ACTIVE_KID %q is not one of the JWT_KEYS", c.JWT.ActiveKID)
+		}
+		for kid, secret := range c.JWT.SigningKeys().Keys {
+			if len(secret) < 32 {
+				invalid("JWT key %q must be at least 32 bytes", kid)
+			}
+		}
+	}
+
+	if c.WebAuthn.RPID == "" {
+		invalid("WEBAUTHN_RP_ID must be set")
+	}
+	if c.WebAuthn.RPName == "" {
+		invalid("WEBAUTHN_RP_NAME must be set")
+	}
+	if len(c.WebAuthn.RPOrigins) == 0 {
+		invalid("WEBAUTHN_RP_ORIGINS must be set")
+	}
+	for _, origin := range c.WebAuthn.RPOrigins {
+		if err := checkURL(origin, "http", "https"); err != nil {
+			invalid("WEBAUTHN_RP_ORIGINS %q: %v", origin, err)
+		}
+	}
+
+	if c.OIDC.IssuerURL != "" {
+		if err := checkURL(c.OIDC.IssuerURL, "http", "https"); err != nil {
+			invalid("OIDC_ISSUER_URL: %v", err)
+		}
+		if c.OIDC.ClientID == "" {
+			invalid("OIDC_CLIENT_ID must be set when OIDC_ISSUER_URL is")
+		}
+		if err := checkURL(c.OIDC.RedirectURL, "http", "https"); err != nil {
+			invalid("OIDC_REDIRECT_URL: %v", err)
+		}
+		if c.OIDC.GroupsClaim == "" {
+			invalid("OIDC_GROUPS_CLAIM must be set when OIDC_ISSUER_URL is")
+		}
+		for _, groupRole := range c.OIDC.GroupRoles {
+			if groupRole.Group == "" || groupRole.OrganisationID == "" {
+				invalid("OIDC_GROUP_ROLES entries need a group and an organisation id")
+			}
+			if !slices.Contains(models.OrganisationRoles, groupRole.Role) {
+				invalid("invalid role %q in OIDC_GROUP_ROLES", groupRole.Role)
+			}
+		}
+	}
+
+	switch c.Mail.Mailer {
+	case "console", "file":
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			invalid("SMTP_HOST must be set when MAILER=smtp")
+		}
+		if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+			invalid("SMTP_PORT %d is not a valid port", c.Mail.SMTPPort)
+		}
+	default:
+		invalid("unknown MAILER %q, expected console, file or smtp", c.Mail.Mailer)
+	}
+
+	if c.Tracing.Endpoint != "" {
+		if err := checkURL(c.Tracing.Endpoint, "http", "https"); err != nil {
+			invalid("OTEL_EXPORTER_OTLP_ENDPOINT: %v", err)
+		}
+		if c.Tracing.ServiceName == "" {
+			invalid("OTEL_SERVICE_NAME must be set when tracing is on")
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("TRACE_SAMPLE_RATIO %v must be between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	if len(errs) > 0 {
+		return configError(errs)
+	}
+	return nil
+}
+
+// checkURL checks that value is an absolute URL with one of the schemes.
+func checkURL(value string, schemes ...string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme && parsed.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected a %s URL", strings.Join(schemes, ", "))
+}
+
+func configError(errs []error) error {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = "  - " + err.Error()
+	}
+	return errors.New("invalid configuration:\n" + strings.Join(messages, "\n"))
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(file, []byte(`{
+		"port": 9000,
+		"mongo": {"uri": "mongodb://file:27017", "database": "fromfile"},
+		"ethereum": {"rpcUrl": "http://file:8545"}
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("MONGO_DATABASE", "fromenv")
+	t.Setenv("PORT", "9001")
+	t.Setenv("JWT_SECRET", "a-signing-key-of-at-least-32-bytes")
+
+	cfg, err := Load([]string{"-port", "9002", "-kms-provider", "local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9002 {
+		t.Errorf("port = %d, the flag should win", cfg.Port)
+	}
+	if cfg.Mongo.Database != "fromenv" {
+		t.Errorf("database = %q, the environment should win over the file", cfg.Mongo.Database)
+	}
+	if cfg.Mongo.URI != "mongodb://file:27017" || cfg.Ethereum.RPCURL != "http://file:8545" {
+		t.Errorf("file settings were not loaded: %+v", cfg)
+	}
+	if cfg.KMS.Provider != "local" || cfg.Mail.Mailer != "console" {
+		t.Errorf("kms = %q, mailer = %q", cfg.KMS.Provider, cfg.Mail.Mailer)
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	cfg := Defaults()
+	cfg.Port = 0
+	cfg.Storage.Backend = "postgres"
+	cfg.Mail.Mailer = "smtp"
+	cfg.Tracing.SampleRatio = 2
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"PORT", "MONGO_URI", "SEPOLIA_URL", "POSTGRES_URL", "JWT_SECRET", "SMTP_HOST", "TRACE_SAMPLE_RATIO"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadAuthSettings(t *testing.T) {
+	t.Setenv("MONGO_URI", "mongodb://localhost:27017")
+	t.Setenv("SEPOLIA_URL", "http://localhost:8545")
+	t.Setenv("JWT_KEYS", "old:old-signing-key-of-at-least-32-bytes, new:new-signing-key-of-at-least-32-bytes")
+	t.Setenv("JWT_ACTIVE_KID", "new")
+	t.Setenv("OIDC_ISSUER_URL", "http://localhost:8080/default")
+	t.Setenv("OIDC_CLIENT_ID", "wallet")
+	t.Setenv("OIDC_SCOPES", "openid email")
+	t.Setenv("OIDC_GROUP_ROLES", "finance=org1:viewer")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := cfg.JWT.SigningKeys()
+	if keys.ActiveKID != "new" || len(keys.Keys) != 2 || string(keys.Keys["old"]) != "old-signing-key-of-at-least-32-bytes" {
+		t.Errorf("signing keys = %+v", keys)
+	}
+	if strings.Join(cfg.OIDC.Scopes, " ") != "openid email" || cfg.OIDC.GroupsClaim != "groups" {
+		t.Errorf("oidc = %+v", cfg.OIDC)
+	}
+	if len(cfg.OIDC.GroupRoles) != 1 || cfg.OIDC.GroupRoles[0] != (OIDCGroupRole{Group: "finance", OrganisationID: "org1", Role: "viewer"}) {
+		t.Errorf("group roles = %+v", cfg.OIDC.GroupRoles)
+	}
+
+	t.Setenv("JWT_ACTIVE_KID", "missing")
+	t.Setenv("OIDC_GROUP_ROLES", "finance=org1:superuser")
+	t.Setenv("ENTRYPOINT_ADDRESS", "0x1234")
+	_, err = Load(nil)
+	for _, want := range []string{"JWT_ACTIVE_KID", "OIDC_GROUP_ROLES", "ENTRYPOINT_ADDRESS"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+}
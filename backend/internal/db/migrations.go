@@ -0,0 +1,372 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migration is a versioned change to the database. Migrations run once, in
+// order of version, and are recorded in the schema_migrations collection.
+// Released migrations must not be edited; changes go in a new migration.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// MigrationState is a migration and when it was applied, if it was.
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type migrationRecord struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedat"`
+}
+
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create the indexes of users, sessions and the other collections",
+		Up:          createInitialIndexes,
+	},
+	{
+		Version:     2,
+		Description: "index wallets by user and address",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("wallets").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "publickey", Value: 1}}},
+				{Keys: bson.M{"publickey": 1}},
+			})
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "backfill transaction creation times from their ids",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("transactions").UpdateMany(ctx,
+				bson.M{"createdat": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"createdat": bson.M{"$toDate": "$_id"}}}}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "index transactions by hash, user and time",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("transactions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.M{"transactionhash": 1}},
+				{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "createdat", Value: -1}}},
+				{Keys: bson.D{{Key: "from", Value: 1}, {Key: "method", Value: 1}}},
+				{Keys: bson.D{{Key: "userophash", Value: 1}, {Key: "userid", Value: 1}}},
+			})
+			return err
+		},
+	},
+	{
+		Version:     5,
+		Description: "mark accounts created before email verification as active",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.M{"status": bson.M{"$in": bson.A{nil, ""}}},
+				bson.M{"$set": bson.M{"status": models.UserStatusActive}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     6,
+		Description: "index safes by address and owner",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("safes").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.M{"address": 1}},
+				{Keys: bson.M{"owners": 1}},
+			})
+			return err
+		},
+	},
+	{
+		Version:     7,
+		Description: "index smart accounts by owner and schedules by source wallet",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("smart_accounts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.M{"address": 1}},
+				{Keys: bson.M{"owneraddress": 1}},
+			})
+			if err != nil {
+				return err
+			}
+			_, err = db.Collection("schedules").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.M{"fromaddress": 1}})
+			return err
+		},
+	},
+}
+
+// Migrations returns every migration with the time it was applied.
+func Migrations(ctx context.Context, client *mongo.Client, database string) ([]MigrationState, error) {
+	applied, err := appliedMigrations(ctx, client.Database(database))
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			state.AppliedAt = &record.AppliedAt
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// Migrate applies the migrations that have not been applied yet and returns
+// them. Instances migrating at the same time wait for each other.
+func Migrate(ctx context.Context, client *mongo.Client, database string) ([]Migration, error) {
+	db := client.Database(database)
+
+	release, err := lockMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := migration.Up(ctx, db); err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %v", migration.Version, migration.Description, err)
+		}
+		_, err := db.Collection("schema_migrations").InsertOne(ctx, migrationRecord{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC(),
+		})
+		if err != nil {
+			return ran, fmt.Errorf("failed to record migration %d: %v", migration.Version, err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+func appliedMigrations(ctx context.Context, db *mongo.Database) (map[int]migrationRecord, error) {
+	cursor, err := db.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %v", err)
+	}
+	var records []migrationRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %v", err)
+	}
+
+	applied := make(map[int]migrationRecord, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// migrationLockTTL bounds how long a crashed instance keeps the migration
+// lock.
+const migrationLockTTL = 15 * time.Minute
+
+// lockMigrations takes the migration lock, waiting while another instance
+// holds it, and returns a function releasing it.
+func lockMigrations(ctx context.Context, db *mongo.Database) (func(), error) {
+	collection := db.Collection("schema_migrations_lock")
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s/%s", hostname, primitive.NewObjectID().Hex())
+
+	for {
+		now := time.Now()
+		_, err := collection.UpdateOne(ctx,
+			bson.M{"_id": "lock", "expiresat": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": owner, "expiresat": now.Add(migrationLockTTL)}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			break
+		}
+		// The upsert conflicts with the lock document while it is held
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to lock migrations: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.New("timed out waiting for another instance to finish migrating")
+		case <-time.After(time.Second):
+		}
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		collection.DeleteOne(ctx, bson.M{"_id": "lock", "owner": owner})
+	}, nil
+}
+
+// createInitialIndexes creates the indexes InitDatabase created before
+// migrations were versioned.
+func createInitialIndexes(ctx context.Context, db *mongo.Database) error {
+	// Get a handle to the 'users' collection
+	usersCollection := db.Collection("users")
+
+	// Create unique indexes
+	_, err := usersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.M{"email": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"username": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Index used by the scheduler to find due schedules
+	_, err = db.Collection("schedules").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextrunat", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Indexes used to look up sessions by refresh token
+	_, err = db.Collection("sessions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"refreshtokenhash": 1}},
+		{Keys: bson.M{"previousrefreshtokenhash": 1}},
+		{Keys: bson.M{"userid": 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Passkeys are looked up by user handle and credential id
+	_, err = usersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.M{"webauthnhandle": 1},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"webauthnhandle": bson.M{"$type": "binData"}}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	_, err = db.Collection("webauthn_credentials").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"credential.id": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"userid": 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Unfinished WebAuthn ceremonies are removed once expired
+	_, err = db.Collection("webauthn_challenges").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expiresat": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// API keys are looked up by the hash of the presented key
+	_, err = db.Collection("api_keys").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"keyhash": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.M{"userid": 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Each user has at most one role per organisation
+	_, err = db.Collection("organisation_members").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "organisationid", Value: 1}, {Key: "userid", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.M{"userid": 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	_, err = db.Collection("wallets").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.M{"organisationid": 1},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Email tokens are looked up by hash and removed a day after expiring
+	_, err = db.Collection("user_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"tokenhash": 1}},
+		{Keys: bson.M{"userid": 1}},
+		{Keys: bson.M{"expiresat": 1}, Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Failed login counters are dropped a day after the last failure
+	_, err = db.Collection("login_attempts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"lockeduntil": 1}},
+		{Keys: bson.M{"lastfailureat": 1}, Options: options.Index().SetExpireAfterSeconds(24 * 60 * 60)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Each identity provider account is linked to at most one user, and
+	// unfinished single sign-on logins are removed once expired
+	_, err = usersCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "oidcissuer", Value: 1}, {Key: "oidcsubject", Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"oidcsubject": bson.M{"$gt": ""}}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+	_, err = db.Collection("oidc_states").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expiresat": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	// Audit entries are chained by sequence number
+	_, err = db.Collection("audit_log").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"sequence": 1}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "sequence", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "sequence", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+	return nil
+}
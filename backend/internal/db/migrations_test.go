@@ -0,0 +1,14 @@
+package db
+
+import "testing"
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("migration %q has version %d, expected %d", migration.Description, migration.Version, i+1)
+		}
+		if migration.Up == nil {
+			t.Fatalf("migration %d has no Up function", migration.Version)
+		}
+	}
+}
@@ -4,16 +4,17 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
-func Connect() (*mongo.Client, error) {
-	clientOptions := options.Client().ApplyURI(os.Getenv("MONGO_URI"))
+// Connect connects to MongoDB, tracing every command as a child of the span
+// in its context.
+func Connect(uri string) (*mongo.Client, error) {
+	clientOptions := options.Client().ApplyURI(uri).SetMonitor(otelmongo.NewMonitor())
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -30,30 +31,17 @@ func Connect() (*mongo.Client, error) {
 	return client, nil
 }
 
-func InitDatabase(client *mongo.Client) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// InitDatabase applies the pending migrations to the database.
+func InitDatabase(client *mongo.Client, database string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
-	// Get a handle to the 'walletdb' database
-	db := client.Database("walletdb")
-
-	// Get a handle to the 'users' collection
-	usersCollection := db.Collection("users")
-
-	// Create unique indexes
-	_, err := usersCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
-		{
-			Keys:    bson.M{"email": 1},
-			Options: options.Index().SetUnique(true),
-		},
-		{
-			Keys:    bson.M{"username": 1},
-			Options: options.Index().SetUnique(true),
-		},
-	})
-
+	applied, err := Migrate(ctx, client, database)
 	if err != nil {
-		return fmt.Errorf("failed to create indexes: %v", err)
+		return err
+	}
+	for _, migration := range applied {
+		fmt.Printf("Applied migration %d: %s\n", migration.Version, migration.Description)
 	}
 
 	fmt.Println("Database initialized successfully")
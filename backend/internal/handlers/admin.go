@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lists the usernames and IPs currently locked out of logging in
+func (h *Handler) ListLockedLogins(c *gin.Context) {
+	lockouts, err := h.service.ListLockedLogins(requestContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// clears the login failures of a username or an IP
+func (h *Handler) UnlockLogin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	var request models.UnlockLoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.service.UnlockLogin(requestContext(c), request, userID.(string)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Login unlocked"})
+}
+
+// lists audit log entries, newest first, filtered by the query parameters
+func (h *Handler) ListAuditEntries(c *gin.Context) {
+	var query models.AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	entries, err := h.service.ListAuditEntries(requestContext(c), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// checks the audit log hash chain
+func (h *Handler) VerifyAuditLog(c *gin.Context) {
+	verification, err := h.service.VerifyAuditLog(requestContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
+
+// respondLoginThrottled answers 429 with a Retry-After header when err is a
+// login throttling error, and reports whether it did.
+func respondLoginThrottled(c *gin.Context, err error) bool {
+	var throttled *services.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": throttled.Error()})
+	return true
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// lists the ERC-20 allowances granted by a wallet; extra pairs can be checked
+// with the "tokens" and "spenders" query parameters
+func (h *Handler) ListAllowances(c *gin.Context) {
+	walletAddress := c.Param("address")
+	userID, _ := c.Get("user_id")
+
+	if !common.IsHexAddress(walletAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address", "address": walletAddress})
+		return
+	}
+
+	allowances, err := h.service.ListAllowances(
+		requestContext(c),
+		common.HexToAddress(walletAddress).Hex(),
+		userID.(string),
+		splitQueryList(c.Query("tokens")),
+		splitQueryList(c.Query("spenders")),
+	)
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, allowances)
+}
+
+// grants a spender an exact ERC-20 allowance
+func (h *Handler) ApproveAllowance(c *gin.Context) {
+	var request models.AllowanceRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.ApproveAllowance(requestContext(c), request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// revokes a spender's ERC-20 allowance
+func (h *Handler) RevokeAllowance(c *gin.Context) {
+	var request models.AllowanceRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.RevokeAllowance(requestContext(c), request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lists the current user's API keys
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	apiKeys, err := h.service.ListAPIKeys(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeys)
+}
+
+// creates an API key; the key itself is only returned in this response
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var request models.APIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	apiKey, err := h.service.CreateAPIKey(requestContext(c), request, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// revokes an API key
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.RevokeAPIKey(requestContext(c), c.Param("id"), userID.(string)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// performs a read-only contract call and returns the decoded outputs
+func (h *Handler) CallContract(c *gin.Context) {
+	var request models.ContractCallRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	result, err := h.service.CallContract(requestContext(c), request)
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// signs and sends a state-changing contract call
+func (h *Handler) SendContractTransaction(c *gin.Context) {
+	var request models.ContractCallRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.SendContractTransaction(requestContext(c), request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
 	"github.com/natneam/crypto-wallet-app/backend/internal/middlewares"
 	"github.com/natneam/crypto-wallet-app/backend/internal/models"
 	"github.com/natneam/crypto-wallet-app/backend/internal/services"
@@ -17,22 +21,126 @@ type Handler struct {
 	service *services.Service
 }
 
+// NewAdminHandler registers the operator routes, which are served on the
+// admin port rather than the public API.
+func NewAdminHandler(r *gin.Engine, service *services.Service) {
+	handler := &Handler{
+		service: service,
+	}
+
+	r.GET("/status", handler.Status)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
+
 func NewHandler(r *gin.Engine, service *services.Service) {
 	handler := &Handler{
 		service: service,
 	}
 
+	// Probes
+	r.GET("/healthz", handler.Liveness)
+	r.GET("/readyz", handler.Readiness)
+
 	// Public routes
 	r.POST("/api/signup", handler.SignUp)
 	r.POST("/api/login", handler.Login)
-
-	// Protected routes
+	r.POST("/api/login/2fa", handler.CompleteLogin)
+	r.POST("/api/signup/passkey/begin", handler.BeginPasskeySignUp)
+	r.POST("/api/signup/passkey/finish", handler.FinishPasskeySignUp)
+	r.POST("/api/login/passkey/begin", handler.BeginPasskeyLogin)
+	r.POST("/api/login/passkey/finish", handler.FinishPasskeyLogin)
+	r.POST("/api/login/oidc/begin", handler.BeginOIDCLogin)
+	r.POST("/api/login/oidc/finish", handler.FinishOIDCLogin)
+	r.POST("/api/token/refresh", handler.RefreshToken)
+	r.POST("/api/verify-email", handler.VerifyEmail)
+	r.POST("/api/verify-email/resend", handler.ResendVerificationEmail)
+	r.POST("/api/password/forgot", handler.RequestPasswordReset)
+	r.POST("/api/password/reset", handler.ResetPassword)
+
+	// Protected routes, open to user sessions and to API keys with the scope
 	protected := r.Group("/api")
 	protected.Use(middlewares.AuthMiddleware(service))
-	protected.GET("/wallets", handler.ListWallets)
-	protected.GET("/wallet/:address", handler.GetWallet)
-	protected.POST("/sign-transaction", handler.SignAndSendTransaction)
-	protected.POST("/wallet", handler.CreateWallet)
+	scope := middlewares.RequireScope
+	// Actions that move funds, sign, grant access or weaken the account's
+	// protection ask users with two-factor authentication for a code. Disabling 2FA and
+	// replacing recovery codes check the code in their request body.
+	stepUp := middlewares.RequireTOTP(service)
+
+	// Account management needs a user session
+	account := protected.Group("", middlewares.RequireUserSession())
+	account.POST("/logout", handler.Logout)
+	account.POST("/logout-all", handler.LogoutAll)
+	account.POST("/2fa/setup", handler.SetupTOTP)
+	account.POST("/2fa/enable", handler.EnableTOTP)
+	account.POST("/2fa/disable", handler.DisableTOTP)
+	account.POST("/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
+	account.POST("/webauthn/register/begin", handler.BeginWebAuthnRegistration)
+	account.POST("/webauthn/register/finish", handler.FinishWebAuthnRegistration)
+	account.GET("/webauthn/credentials", handler.ListWebAuthnCredentials)
+	account.DELETE("/webauthn/credentials/:id", stepUp, handler.DeleteWebAuthnCredential)
+	account.POST("/webauthn/transaction-confirmation", stepUp, handler.SetWebAuthnTransactionConfirmation)
+	account.GET("/api-keys", handler.ListAPIKeys)
+	account.POST("/api-keys", stepUp, handler.CreateAPIKey)
+	account.DELETE("/api-keys/:id", handler.RevokeAPIKey)
+	account.GET("/organisations", handler.ListOrganisations)
+	account.POST("/organisations", handler.CreateOrganisation)
+	account.GET("/organisations/:id", handler.GetOrganisation)
+	account.GET("/organisations/:id/members", handler.ListOrganisationMembers)
+	account.POST("/organisations/:id/members", stepUp, handler.AddOrganisationMember)
+	account.PUT("/organisations/:id/members/:userId", stepUp, handler.UpdateOrganisationMember)
+	account.DELETE("/organisations/:id/members/:userId", stepUp, handler.RemoveOrganisationMember)
+	account.POST("/transactions/prepare", handler.PrepareTransaction)
+	account.POST("/transactions/confirm", stepUp, handler.ConfirmTransaction)
+
+	// Administration
+	admin := account.Group("/admin", middlewares.RequireAdmin(service))
+	admin.GET("/login-lockouts", handler.ListLockedLogins)
+	admin.POST("/login-lockouts/unlock", handler.UnlockLogin)
+	admin.GET("/audit", handler.ListAuditEntries)
+	admin.GET("/audit/verify", handler.VerifyAuditLog)
+
+	protected.GET("/wallets", scope(models.ScopeWalletsRead), handler.ListWallets)
+	protected.GET("/wallet/:address", scope(models.ScopeWalletsRead, ":address"), handler.GetWallet)
+	protected.POST("/sign-transaction", scope(models.ScopeTxSend, "fromAddress"), stepUp, handler.SignAndSendTransaction)
+	protected.POST("/wallet", scope(models.ScopeWalletsWrite), handler.CreateWallet)
+	protected.GET("/wallet/:address/nfts", scope(models.ScopeWalletsRead, ":address"), handler.ListNFTs)
+	protected.GET("/nfts/:contract/:tokenId/uri", scope(models.ScopeWalletsRead), handler.GetNFTMetadataURI)
+	protected.POST("/nfts/transfer", scope(models.ScopeTxSend, "fromAddress"), stepUp, handler.TransferNFT)
+	protected.GET("/wallet/:address/allowances", scope(models.ScopeWalletsRead, ":address"), handler.ListAllowances)
+	protected.POST("/allowances/approve", scope(models.ScopeTxSend, "fromAddress"), stepUp, handler.ApproveAllowance)
+	protected.POST("/allowances/revoke", scope(models.ScopeTxSend, "fromAddress"), stepUp, handler.RevokeAllowance)
+	protected.POST("/sign-message", scope(models.ScopeMessagesSign, "address"), stepUp, handler.SignPersonalMessage)
+	protected.POST("/sign-typed-data", scope(models.ScopeMessagesSign, "address"), stepUp, handler.SignTypedData)
+	protected.POST("/verify-signature", scope(models.ScopeWalletsRead), handler.VerifySignature)
+	protected.GET("/safes", scope(models.ScopeWalletsRead), handler.ListSafes)
+	protected.POST("/safes", scope(models.ScopeTxSend, "fromAddress"), stepUp, handler.CreateSafe)
+	protected.GET("/safes/:address", scope(models.ScopeWalletsRead, ":address"), handler.GetSafe)
+	protected.GET("/safes/:address/transactions", scope(models.ScopeWalletsRead, ":address"), handler.ListSafeTransactions)
+	protected.POST("/safes/:address/transactions", scope(models.ScopeTxSend, ":address"), stepUp, handler.ProposeSafeTransaction)
+	protected.POST("/safes/:address/transactions/:id/confirm", scope(models.ScopeTxSend, ":address", "address"), stepUp, handler.ConfirmSafeTransaction)
+	protected.POST("/safes/:address/transactions/:id/execute", scope(models.ScopeTxSend, ":address", "address"), stepUp, handler.ExecuteSafeTransaction)
+	protected.GET("/smart-accounts", scope(models.ScopeWalletsRead), handler.ListSmartAccounts)
+	protected.POST("/smart-accounts", scope(models.ScopeWalletsWrite, "ownerAddress"), handler.CreateSmartAccount)
+	protected.GET("/smart-accounts/:address", scope(models.ScopeWalletsRead, ":address"), handler.GetSmartAccount)
+	protected.POST("/smart-accounts/:address/operations", scope(models.ScopeTxSend, ":address"), stepUp, handler.SendUserOperation)
+	protected.GET("/smart-accounts/:address/operations/:hash", scope(models.ScopeWalletsRead, ":address"), handler.GetUserOperation)
+	protected.POST("/contracts/call", scope(models.ScopeWalletsRead, "fromAddress"), handler.CallContract)
+	protected.POST("/contracts/transact", scope(models.ScopeTxSend, "fromAddress"), stepUp, handler.SendContractTransaction)
+	protected.GET("/schedules", scope(models.ScopeSchedulesRead), handler.ListSchedules)
+	protected.POST("/schedules", scope(models.ScopeSchedulesWrite, "fromAddress"), stepUp, handler.CreateSchedule)
+	protected.GET("/schedules/:id", scope(models.ScopeSchedulesRead), handler.GetSchedule)
+	protected.DELETE("/schedules/:id", scope(models.ScopeSchedulesWrite), handler.DeleteSchedule)
+	protected.GET("/schedules/:id/runs", scope(models.ScopeSchedulesRead), handler.ListScheduleRuns)
+	protected.POST("/schedules/:id/pause", scope(models.ScopeSchedulesWrite), handler.PauseSchedule)
+	protected.POST("/schedules/:id/resume", scope(models.ScopeSchedulesWrite), stepUp, handler.ResumeSchedule)
+}
+
+// requestContext carries the request's trace and passkey assertion into the
+// services. It is not cancelled when the client disconnects, so work such as
+// a transaction that was already sent is still finished and recorded.
+func requestContext(c *gin.Context) context.Context {
+	ctx := context.WithoutCancel(c.Request.Context())
+	return services.WithWebAuthnAssertion(ctx, c.GetHeader("X-WebAuthn-Assertion"))
 }
 
 // creates a new wallet and stores it in the database
@@ -55,9 +163,9 @@ func (h *Handler) CreateWallet(c *gin.Context) {
 	var wallet models.Wallet
 	var err error
 
-	wallet, err = h.service.CreateWallet(walletName, userId.(string))
+	wallet, err = h.service.CreateWallet(requestContext(c), walletName, jsonData["organisation_id"], userId.(string))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+		c.JSON(permissionStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -80,7 +188,7 @@ func (h *Handler) GetWallet(c *gin.Context) {
 		return
 	}
 
-	wallet, err := h.service.GetWallet(walletAddress, userID.(string))
+	wallet, err := h.service.GetWallet(requestContext(c), walletAddress, userID.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
 		return
@@ -92,15 +200,32 @@ func (h *Handler) GetWallet(c *gin.Context) {
 // lists all wallets
 func (h *Handler) ListWallets(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	wallets, err := h.service.ListWallets(userID.(string))
+	wallets, err := h.service.ListWallets(requestContext(c), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list wallets"})
 		return
 	}
 
+	// API keys restricted to wallets only see those wallets
+	wallets = slices.DeleteFunc(wallets, func(wallet models.Wallet) bool {
+		return !apiKeyAllows(c, wallet.PublicKey)
+	})
+
 	c.JSON(http.StatusOK, wallets)
 }
 
+// apiKeyAllows reports whether the request may see address: always for user
+// sessions and unrestricted API keys, otherwise when the key lists it.
+func apiKeyAllows(c *gin.Context, address string) bool {
+	value, ok := c.Get("api_key")
+	if !ok || len(value.(models.APIKey).Wallets) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(value.(models.APIKey).Wallets, func(allowed string) bool {
+		return strings.EqualFold(allowed, address)
+	})
+}
+
 // signs and sends a transaction
 func (h *Handler) SignAndSendTransaction(c *gin.Context) {
 	var transaction models.TransactionRequest
@@ -122,11 +247,11 @@ func (h *Handler) SignAndSendTransaction(c *gin.Context) {
 	value := transaction.Value
 	userID, _ := c.Get("user_id")
 
-	result, err := h.service.SignAndSendTransaction(fromAddress, toAddress, value, userID.(string))
+	result, err := h.service.SignAndSendTransaction(requestContext(c), fromAddress, toAddress, value, userID.(string))
 
 	if err != nil {
 		fmt.Println(err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondSigningError(c, err)
 		return
 	}
 
@@ -145,13 +270,13 @@ func (h *Handler) SignUp(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.SignUp(input.Username, input.Email, input.Password); err != nil {
+	if err := h.service.SignUp(requestContext(c), input.Username, input.Email, input.Password); err != nil {
 		fmt.Println(`Error: `, err)
 		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully, check your email to verify your address"})
 }
 
 func (h *Handler) Login(c *gin.Context) {
@@ -165,11 +290,61 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.service.Login(input.Username, input.Password)
+	result, err := h.service.Login(requestContext(c), input.Username, input.Password, c.ClientIP())
+	if respondLoginThrottled(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, result)
+}
+
+// exchanges a refresh token for a new token pair
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.RefreshSession(requestContext(c), input.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// revokes the current session
+func (h *Handler) Logout(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+	if err := h.service.Logout(requestContext(c), sessionID.(string), userID.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// revokes every session of the current user
+func (h *Handler) LogoutAll(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.LogoutAll(requestContext(c), userID.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked"})
 }
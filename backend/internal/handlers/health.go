@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reports that the process is running, without checking its dependencies
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// reports whether the instance can serve requests: it is not shutting down
+// and MongoDB, the node and KMS are reachable
+func (h *Handler) Readiness(c *gin.Context) {
+	if !h.service.Ready() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+		return
+	}
+
+	readiness := h.service.CheckReadiness()
+	if !readiness.Ready {
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+	c.JSON(http.StatusOK, readiness)
+}
+
+// shows the build, chain head and dependency state for operators
+func (h *Handler) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Status())
+}
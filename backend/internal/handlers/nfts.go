@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// lists the NFTs a wallet holds in the collections given by the "contracts"
+// query parameter; ERC-1155 collections also need "tokenIds"
+func (h *Handler) ListNFTs(c *gin.Context) {
+	walletAddress := c.Param("address")
+	userID, _ := c.Get("user_id")
+
+	if !common.IsHexAddress(walletAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address", "address": walletAddress})
+		return
+	}
+
+	contracts := splitQueryList(c.Query("contracts"))
+	if len(contracts) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing contracts"})
+		return
+	}
+	tokenIds := splitQueryList(c.Query("tokenIds"))
+
+	nfts, err := h.service.ListNFTs(requestContext(c), common.HexToAddress(walletAddress).Hex(), userID.(string), contracts, tokenIds)
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, nfts)
+}
+
+// retrieves the metadata URI of a token
+func (h *Handler) GetNFTMetadataURI(c *gin.Context) {
+	uri, err := h.service.GetNFTMetadataURI(requestContext(c), c.Param("contract"), c.Param("tokenId"))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokenUri": uri})
+}
+
+// transfers an ERC-721 or ERC-1155 token
+func (h *Handler) TransferNFT(c *gin.Context) {
+	var request models.NFTTransferRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.TransferNFT(requestContext(c), request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// splitQueryList splits a comma-separated query parameter, dropping empty items.
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// starts a single sign-on login and returns the identity provider URL to
+// redirect the browser to
+func (h *Handler) BeginOIDCLogin(c *gin.Context) {
+	authorization, err := h.service.BeginOIDCLogin(requestContext(c))
+	if errors.Is(err, services.ErrOIDCDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, authorization)
+}
+
+// finishes a single sign-on login with the code the identity provider
+// returned to the frontend
+func (h *Handler) FinishOIDCLogin(c *gin.Context) {
+	var request models.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	result, err := h.service.FinishOIDCLogin(requestContext(c), request, c.ClientIP())
+	if errors.Is(err, services.ErrOIDCDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lists the organisations the current user belongs to
+func (h *Handler) ListOrganisations(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	organisations, err := h.service.ListOrganisations(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, organisations)
+}
+
+// creates an organisation owned by the current user
+func (h *Handler) CreateOrganisation(c *gin.Context) {
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	organisation, err := h.service.CreateOrganisation(requestContext(c), input.Name, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, organisation)
+}
+
+// gets an organisation
+func (h *Handler) GetOrganisation(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	organisation, err := h.service.GetOrganisation(requestContext(c), c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusNotFound), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, organisation)
+}
+
+// lists the members of an organisation
+func (h *Handler) ListOrganisationMembers(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	members, err := h.service.ListOrganisationMembers(requestContext(c), c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// adds a user to an organisation
+func (h *Handler) AddOrganisationMember(c *gin.Context) {
+	var request models.OrganisationMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	member, err := h.service.AddOrganisationMember(requestContext(c), c.Param("id"), request, userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// changes the role of a member
+func (h *Handler) UpdateOrganisationMember(c *gin.Context) {
+	var request models.OrganisationMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := h.service.UpdateOrganisationMember(requestContext(c), c.Param("id"), c.Param("userId"), request.Role, userID.(string)); err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member updated"})
+}
+
+// removes a member from an organisation
+func (h *Handler) RemoveOrganisationMember(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.RemoveOrganisationMember(requestContext(c), c.Param("id"), c.Param("userId"), userID.(string)); err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// permissionStatus maps permission errors to 403 and other errors to fallback.
+func permissionStatus(err error, fallback int) int {
+	if errors.Is(err, services.ErrPermissionDenied) {
+		return http.StatusForbidden
+	}
+	return fallback
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// deploys a new Safe multisig owned by KMS wallets
+func (h *Handler) CreateSafe(c *gin.Context) {
+	var request models.SafeCreateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	safe, err := h.service.CreateSafe(requestContext(c), request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, safe)
+}
+
+// lists all Safes
+func (h *Handler) ListSafes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	safes, err := h.service.ListSafes(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list safes"})
+		return
+	}
+
+	// API keys restricted to wallets only see the listed addresses
+	safes = slices.DeleteFunc(safes, func(safe models.SafeWallet) bool {
+		return !apiKeyAllows(c, safe.Address)
+	})
+
+	c.JSON(http.StatusOK, safes)
+}
+
+// retrieves a Safe by its address
+func (h *Handler) GetSafe(c *gin.Context) {
+	safeAddress, ok := safeAddressParam(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	safe, err := h.service.GetSafe(requestContext(c), safeAddress, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Safe not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, safe)
+}
+
+// lists the transactions proposed for a Safe
+func (h *Handler) ListSafeTransactions(c *gin.Context) {
+	safeAddress, ok := safeAddressParam(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	transactions, err := h.service.ListSafeTransactions(requestContext(c), safeAddress, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Safe not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transactions)
+}
+
+// proposes a Safe transaction
+func (h *Handler) ProposeSafeTransaction(c *gin.Context) {
+	safeAddress, ok := safeAddressParam(c)
+	if !ok {
+		return
+	}
+
+	var request models.SafeTransactionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	transaction, err := h.service.ProposeSafeTransaction(requestContext(c), safeAddress, request, userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, transaction)
+}
+
+// adds an owner signature to a proposed Safe transaction
+func (h *Handler) ConfirmSafeTransaction(c *gin.Context) {
+	safeAddress, ok := safeAddressParam(c)
+	if !ok {
+		return
+	}
+
+	var request models.SafeSignerRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	transaction, err := h.service.ConfirmSafeTransaction(requestContext(c), safeAddress, c.Param("id"), request.Address, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// executes a Safe transaction that reached its signature threshold
+func (h *Handler) ExecuteSafeTransaction(c *gin.Context) {
+	safeAddress, ok := safeAddressParam(c)
+	if !ok {
+		return
+	}
+
+	var request models.SafeSignerRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	userID, _ := c.Get("user_id")
+	transaction, err := h.service.ExecuteSafeTransaction(requestContext(c), safeAddress, c.Param("id"), request.Address, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// safeAddressParam validates the :address path parameter and returns it in
+// checksum form, writing a 400 response when it is invalid.
+func safeAddressParam(c *gin.Context) (string, bool) {
+	address := c.Param("address")
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid safe address", "address": address})
+		return "", false
+	}
+	return common.HexToAddress(address).Hex(), true
+}
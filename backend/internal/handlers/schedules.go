@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// creates a one-off or recurring transfer schedule
+func (h *Handler) CreateSchedule(c *gin.Context) {
+	var request models.ScheduleRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	schedule, err := h.service.CreateSchedule(requestContext(c), request, userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// lists all schedules
+func (h *Handler) ListSchedules(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	schedules, err := h.service.ListSchedules(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// retrieves a schedule by its id
+func (h *Handler) GetSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	schedule, err := h.service.GetSchedule(requestContext(c), c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// lists the run history of a schedule
+func (h *Handler) ListScheduleRuns(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	runs, err := h.service.ListScheduleRuns(requestContext(c), c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// pauses an active schedule
+func (h *Handler) PauseSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	schedule, err := h.service.PauseSchedule(requestContext(c), c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// resumes a paused schedule
+func (h *Handler) ResumeSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	schedule, err := h.service.ResumeSchedule(requestContext(c), c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// deletes a schedule
+func (h *Handler) DeleteSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.DeleteSchedule(requestContext(c), c.Param("id"), userID.(string)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// signs an EIP-191 personal message
+func (h *Handler) SignPersonalMessage(c *gin.Context) {
+	var request models.SignMessageRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.SignPersonalMessage(requestContext(c), request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// signs EIP-712 typed data
+func (h *Handler) SignTypedData(c *gin.Context) {
+	var request models.SignTypedDataRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.SignTypedData(requestContext(c), request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// verifies a personal message or typed data signature
+func (h *Handler) VerifySignature(c *gin.Context) {
+	var request models.VerifySignatureRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	result, err := h.service.VerifySignature(request)
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
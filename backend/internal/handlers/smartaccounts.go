@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// creates an ERC-4337 smart account owned by a KMS wallet
+func (h *Handler) CreateSmartAccount(c *gin.Context) {
+	var request models.SmartAccountCreateRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	account, err := h.service.CreateSmartAccount(requestContext(c), request, userID.(string))
+	if err != nil {
+		c.JSON(permissionStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, account)
+}
+
+// lists all smart accounts
+func (h *Handler) ListSmartAccounts(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	accounts, err := h.service.ListSmartAccounts(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list smart accounts"})
+		return
+	}
+
+	// API keys restricted to wallets only see the listed addresses
+	accounts = slices.DeleteFunc(accounts, func(account models.SmartAccount) bool {
+		return !apiKeyAllows(c, account.Address)
+	})
+
+	c.JSON(http.StatusOK, accounts)
+}
+
+// retrieves a smart account by its address
+func (h *Handler) GetSmartAccount(c *gin.Context) {
+	address, ok := smartAccountAddressParam(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	account, err := h.service.GetSmartAccount(requestContext(c), address, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Smart account not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
+}
+
+// signs and submits a user operation to the bundler
+func (h *Handler) SendUserOperation(c *gin.Context) {
+	address, ok := smartAccountAddressParam(c)
+	if !ok {
+		return
+	}
+
+	var request models.UserOperationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.SendUserOperation(requestContext(c), address, request, userID.(string))
+	if err != nil {
+		respondSigningError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, result)
+}
+
+// retrieves the status of a user operation
+func (h *Handler) GetUserOperation(c *gin.Context) {
+	address, ok := smartAccountAddressParam(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.GetUserOperation(requestContext(c), address, c.Param("hash"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User operation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// smartAccountAddressParam validates the :address path parameter and returns
+// it in checksum form, writing a 400 response when it is invalid.
+func smartAccountAddressParam(c *gin.Context) (string, bool) {
+	address := c.Param("address")
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid smart account address", "address": address})
+		return "", false
+	}
+	return common.HexToAddress(address).Hex(), true
+}
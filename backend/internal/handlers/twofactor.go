@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// totpCodeRequest carries a TOTP or recovery code. The code may also be
+// sent in the X-TOTP-Code header, as for other steps up.
+type totpCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// totpCode returns the code from the request body or the X-TOTP-Code header.
+func totpCode(c *gin.Context, input totpCodeRequest) string {
+	if input.Code != "" {
+		return input.Code
+	}
+	return c.GetHeader("X-TOTP-Code")
+}
+
+// completes a login with the mfa token and a TOTP or recovery code
+func (h *Handler) CompleteLogin(c *gin.Context) {
+	var input struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.service.CompleteLogin(requestContext(c), input.MFAToken, input.Code, c.ClientIP())
+	if respondLoginThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// starts TOTP enrolment and returns the secret and provisioning URI
+func (h *Handler) SetupTOTP(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	setup, err := h.service.SetupTOTP(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// confirms TOTP enrolment and returns the recovery codes
+func (h *Handler) EnableTOTP(c *gin.Context) {
+	var input totpCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	codes, err := h.service.EnableTOTP(requestContext(c), userID.(string), totpCode(c, input))
+	if err != nil {
+		c.JSON(secondFactorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// turns off two-factor authentication
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	var input totpCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	err := h.service.DisableTOTP(requestContext(c), userID.(string), totpCode(c, input), c.ClientIP())
+	if respondLoginThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(secondFactorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// replaces the recovery codes
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var input totpCodeRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	codes, err := h.service.RegenerateRecoveryCodes(requestContext(c), userID.(string), totpCode(c, input), c.ClientIP())
+	if respondLoginThrottled(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(secondFactorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+func secondFactorStatus(err error) int {
+	if errors.Is(err, services.ErrSecondFactorRequired) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
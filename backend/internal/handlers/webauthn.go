@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// starts a passkey-only sign up
+func (h *Handler) BeginPasskeySignUp(c *gin.Context) {
+	var input struct {
+		Username string `json:"username" binding:"required"`
+		Email    string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ceremony, err := h.service.BeginPasskeySignUp(requestContext(c), input.Username, input.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// registers the passkey and creates the account
+func (h *Handler) FinishPasskeySignUp(c *gin.Context) {
+	var request models.WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if err := h.service.FinishPasskeySignUp(requestContext(c), request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully, check your email to verify your address"})
+}
+
+// starts a passkey login
+func (h *Handler) BeginPasskeyLogin(c *gin.Context) {
+	ceremony, err := h.service.BeginPasskeyLogin(requestContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// verifies the passkey assertion and logs the user in
+func (h *Handler) FinishPasskeyLogin(c *gin.Context) {
+	var request models.WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	tokens, err := h.service.FinishPasskeyLogin(requestContext(c), request, c.ClientIP())
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// starts registering a passkey for the current user
+func (h *Handler) BeginWebAuthnRegistration(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	ceremony, err := h.service.BeginWebAuthnRegistration(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// stores the registered passkey
+func (h *Handler) FinishWebAuthnRegistration(c *gin.Context) {
+	var request models.WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	credential, err := h.service.FinishWebAuthnRegistration(requestContext(c), request, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+// lists the current user's passkeys
+func (h *Handler) ListWebAuthnCredentials(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	credentials, err := h.service.ListWebAuthnCredentials(requestContext(c), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// removes a passkey
+func (h *Handler) DeleteWebAuthnCredential(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	if err := h.service.DeleteWebAuthnCredential(requestContext(c), c.Param("id"), userID.(string)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey removed"})
+}
+
+// turns passkey confirmation of transactions on or off
+func (h *Handler) SetWebAuthnTransactionConfirmation(c *gin.Context) {
+	var input struct {
+		Required *bool `json:"required" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	if err := h.service.SetWebAuthnTransactionConfirmation(requestContext(c), userID.(string), *input.Required); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"require_webauthn_for_transactions": *input.Required})
+}
+
+// builds a transaction, optionally with calldata, and returns the passkey
+// assertion options bound to its hash
+func (h *Handler) PrepareTransaction(c *gin.Context) {
+	var transaction models.TransactionPrepareRequest
+	if err := c.ShouldBindJSON(&transaction); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	if !common.IsHexAddress(transaction.FromAddress) || !common.IsHexAddress(transaction.ToAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address format"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	ceremony, err := h.service.PrepareTransaction(requestContext(c), transaction, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// verifies the passkey assertion and signs and sends the prepared transaction
+func (h *Handler) ConfirmTransaction(c *gin.Context) {
+	var request models.WebAuthnFinishRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.service.ConfirmTransaction(requestContext(c), request, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondSigningError writes the error of a request that signs with a wallet
+// key. When the user must confirm the signature with a passkey, the response
+// carries the ceremony; the request is then sent again with the assertion in
+// the X-WebAuthn-Assertion header.
+func respondSigningError(c *gin.Context, err error) {
+	var confirmation *services.ConfirmationRequiredError
+	if errors.As(err, &confirmation) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":             err.Error(),
+			"webauthn_required": true,
+			"ceremony":          confirmation.Ceremony,
+		})
+		return
+	}
+	c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+}
+
+func transactionErrorStatus(err error) int {
+	if errors.Is(err, services.ErrTransactionConfirmationRequired) {
+		return http.StatusForbidden
+	}
+	return permissionStatus(err, http.StatusBadRequest)
+}
@@ -0,0 +1,38 @@
+package kms
+
+import (
+	"context"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
+	"github.com/natneam/crypto-wallet-app/backend/internal/tracing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// instrumentedKeyManager traces the calls to another key manager and records
+// the latency and errors of its signatures in the metrics.
+type instrumentedKeyManager struct {
+	KeyManager
+	provider string
+}
+
+func (m instrumentedKeyManager) CreateKey(ctx context.Context) (string, common.Address, error) {
+	ctx, span := tracing.StartClient(ctx, "kms CreateKey", attribute.String("kms.provider", m.provider))
+	keyID, address, err := m.KeyManager.CreateKey(ctx)
+	tracing.End(span, err)
+	return keyID, address, err
+}
+
+func (m instrumentedKeyManager) SignDigest(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	ctx, span := tracing.StartClient(ctx, "kms SignDigest",
+		attribute.String("kms.provider", m.provider),
+		attribute.String("kms.key_id", keyID),
+	)
+	started := time.Now()
+	signature, err := m.KeyManager.SignDigest(ctx, keyID, digest)
+	metrics.ObserveSign(m.provider, time.Since(started), err)
+	tracing.End(span, err)
+	return signature, err
+}
@@ -2,20 +2,122 @@ package kms
 
 import (
 	"context"
+	"encoding/asn1"
 	"fmt"
+	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
-func NewKMSClient() (*kms.Client, error) {
+// KeyManager creates secp256k1 wallet keys and signs digests with them
+// without exposing the private keys.
+type KeyManager interface {
+	// CreateKey creates a key and returns its id and Ethereum address.
+	CreateKey(ctx context.Context) (string, common.Address, error)
+
+	// SignDigest signs a 32-byte digest and returns a 65-byte Ethereum
+	// signature [R || S || V] with V in {0, 1}.
+	SignDigest(ctx context.Context, keyID string, digest []byte) ([]byte, error)
+
+	// Ping checks that the key store can be reached.
+	Ping(ctx context.Context) error
+}
+
+// NewKeyManager returns the key manager for provider: "aws" or "local",
+// which keeps keys in memory for development. Signatures are recorded in the
+// metrics.
+func NewKeyManager(provider string) (KeyManager, error) {
+	switch provider {
+	case "", "aws":
+		manager, err := NewAWSKeyManager()
+		if err != nil {
+			return nil, err
+		}
+		return instrumentedKeyManager{KeyManager: manager, provider: "aws"}, nil
+	case "local":
+		fmt.Println("Using local in-memory keys, wallets will not survive a restart")
+		return instrumentedKeyManager{KeyManager: NewLocalKeyManager(), provider: "local"}, nil
+	default:
+		return nil, fmt.Errorf("unknown KMS_PROVIDER %q, expected aws or local", provider)
+	}
+}
+
+// AWSKeyManager keeps wallet keys in AWS KMS.
+type AWSKeyManager struct {
+	client *kms.Client
+
+	// publicKeys caches the public key of each key id used to sign
+	publicKeys sync.Map
+}
+
+func NewAWSKeyManager() (*AWSKeyManager, error) {
 	// Create a new AWS session with explicit credentials and region
 	awsCfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
 		fmt.Println("Error loading AWS configuration:", err)
-		return &kms.Client{}, err
+		return nil, err
 	}
 	kmsClient := kms.NewFromConfig(awsCfg)
 	fmt.Println("KMS client created")
-	return kmsClient, nil
+	return &AWSKeyManager{client: kmsClient}, nil
+}
+
+// Ping lists a single key, which needs the same connectivity and credentials
+// as signing.
+func (m *AWSKeyManager) Ping(ctx context.Context) error {
+	_, err := m.client.ListKeys(ctx, &kms.ListKeysInput{Limit: aws.Int32(1)})
+	if err != nil {
+		return fmt.Errorf("failed to reach KMS: %v", err)
+	}
+	return nil
+}
+
+func (m *AWSKeyManager) CreateKey(ctx context.Context) (string, common.Address, error) {
+	// Create a new KMS key
+	createKeyOutput, err := m.client.CreateKey(ctx, &kms.CreateKeyInput{
+		Description: aws.String("Ethereum wallet key"),
+		KeyUsage:    types.KeyUsageTypeSignVerify,
+		KeySpec:     types.KeySpecEccSecgP256k1, // Ethereum uses secp256k1 curve
+	})
+	if err != nil {
+		return "", common.Address{}, fmt.Errorf("failed to create KMS key: %v", err)
+	}
+	keyID := *createKeyOutput.KeyMetadata.KeyId
+
+	// Get the public key
+	getPublicKeyOutput, err := m.client.GetPublicKey(ctx, &kms.GetPublicKeyInput{KeyId: &keyID})
+	if err != nil {
+		return "", common.Address{}, fmt.Errorf("failed to get public key: %v", err)
+	}
+
+	// Parse the ASN.1 DER encoded public key
+	var publicKey struct {
+		Algorithm struct {
+			Algorithm  asn1.ObjectIdentifier
+			Parameters asn1.RawValue
+		}
+		PublicKey asn1.BitString
+	}
+	_, err = asn1.Unmarshal(getPublicKeyOutput.PublicKey, &publicKey)
+	if err != nil {
+		return "", common.Address{}, fmt.Errorf("failed to parse public key: %v", err)
+	}
+
+	// Extract the actual public key bytes
+	publicKeyBytes := publicKey.PublicKey.Bytes
+	if len(publicKeyBytes) == 65 && publicKeyBytes[0] == 4 {
+		// Remove the leading 0x04 byte (uncompressed point indicator)
+		publicKeyBytes = publicKeyBytes[1:]
+	} else if len(publicKeyBytes) != 64 {
+		return "", common.Address{}, fmt.Errorf("unexpected public key length after parsing: %d", len(publicKeyBytes))
+	}
+
+	// Calculate the Ethereum address
+	hash := crypto.Keccak256(publicKeyBytes)
+	return keyID, common.BytesToAddress(hash[12:]), nil
 }
@@ -0,0 +1,60 @@
+package kms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+)
+
+// LocalKeyManager keeps wallet keys in memory. It is meant for tests and
+// local development against a development chain; keys are lost on restart.
+type LocalKeyManager struct {
+	mu   sync.RWMutex
+	keys map[string]*ecdsa.PrivateKey
+}
+
+func NewLocalKeyManager() *LocalKeyManager {
+	return &LocalKeyManager{keys: map[string]*ecdsa.PrivateKey{}}
+}
+
+func (m *LocalKeyManager) CreateKey(ctx context.Context) (string, common.Address, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return "", common.Address{}, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return m.AddKey(key), crypto.PubkeyToAddress(key.PublicKey), nil
+}
+
+func (m *LocalKeyManager) Ping(ctx context.Context) error {
+	return nil
+}
+
+// AddKey stores an existing private key and returns its key id.
+func (m *LocalKeyManager) AddKey(key *ecdsa.PrivateKey) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keyID := "local-" + uuid.NewString()
+	m.keys[keyID] = key
+	return keyID
+}
+
+func (m *LocalKeyManager) SignDigest(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	m.mu.RLock()
+	key, ok := m.keys[keyID]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %s", keyID)
+	}
+
+	return crypto.Sign(digest, key)
+}
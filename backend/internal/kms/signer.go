@@ -0,0 +1,94 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	ethawskmssigner "github.com/welthee/go-ethereum-aws-kms-tx-signer/v2"
+)
+
+var (
+	secp256k1N     = crypto.S256().Params().N
+	secp256k1HalfN = new(big.Int).Div(secp256k1N, big.NewInt(2))
+)
+
+// SignDigest signs a 32-byte digest with a KMS secp256k1 key and returns a
+// 65-byte Ethereum signature [R || S || V] with V in {0, 1}.
+func (m *AWSKeyManager) SignDigest(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	client := m.client
+
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	publicKey, err := m.publicKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.Sign(ctx, &kms.SignInput{
+		KeyId:            &keyID,
+		Message:          digest,
+		MessageType:      types.MessageTypeDigest,
+		SigningAlgorithm: types.SigningAlgorithmSpecEcdsaSha256,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with KMS: %v", err)
+	}
+
+	return ethereumSignature(digest, output.Signature, publicKey)
+}
+
+// publicKey returns the uncompressed public key of a KMS key. Keys never
+// change, so they are fetched once per key id.
+func (m *AWSKeyManager) publicKey(ctx context.Context, keyID string) ([]byte, error) {
+	if publicKey, ok := m.publicKeys.Load(keyID); ok {
+		return publicKey.([]byte), nil
+	}
+
+	publicKey, err := ethawskmssigner.GetPubKeyCtx(ctx, m.client, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key: %v", err)
+	}
+	publicKeyBytes := crypto.FromECDSAPub(publicKey)
+	m.publicKeys.Store(keyID, publicKeyBytes)
+	return publicKeyBytes, nil
+}
+
+// ethereumSignature converts an ASN.1 DER encoded ECDSA signature of digest
+// into a 65-byte Ethereum signature that recovers publicKey.
+func ethereumSignature(digest []byte, der []byte, publicKey []byte) ([]byte, error) {
+	var derSignature struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &derSignature); err != nil {
+		return nil, fmt.Errorf("failed to parse signature: %v", err)
+	}
+
+	// Ethereum only accepts signatures in the lower half of the curve order (EIP-2)
+	s := derSignature.S
+	if s.Cmp(secp256k1HalfN) > 0 {
+		s = new(big.Int).Sub(secp256k1N, s)
+	}
+
+	signature := make([]byte, 65)
+	derSignature.R.FillBytes(signature[0:32])
+	s.FillBytes(signature[32:64])
+
+	// KMS does not return the recovery id, so find the one that recovers our key
+	for _, v := range []byte{0, 1} {
+		signature[64] = v
+		recovered, err := crypto.Ecrecover(digest, signature)
+		if err == nil && bytes.Equal(recovered, publicKey) {
+			return signature, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to determine signature recovery id")
+}
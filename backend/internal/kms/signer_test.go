@@ -0,0 +1,85 @@
+package kms
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// derSignature encodes r and s the way KMS returns them.
+func derSignature(t *testing.T, r, s *big.Int) []byte {
+	t.Helper()
+
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestEthereumSignature(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		publicKey := crypto.FromECDSAPub(&key.PublicKey)
+		digest := crypto.Keccak256([]byte{byte(i)})
+
+		// crypto.Sign returns a low-S signature with the recovery id
+		want, err := crypto.Sign(digest, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := new(big.Int).SetBytes(want[0:32])
+		s := new(big.Int).SetBytes(want[32:64])
+		highS := new(big.Int).Sub(secp256k1N, s)
+
+		for name, der := range map[string][]byte{
+			"low s":  derSignature(t, r, s),
+			"high s": derSignature(t, r, highS),
+		} {
+			signature, err := ethereumSignature(digest, der, publicKey)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !bytes.Equal(signature, want) {
+				t.Errorf("%s: signature = %x, want %x", name, signature, want)
+			}
+			if new(big.Int).SetBytes(signature[32:64]).Cmp(secp256k1HalfN) > 0 {
+				t.Errorf("%s: s was not normalised", name)
+			}
+			recovered, err := crypto.Ecrecover(digest, signature)
+			if err != nil || !bytes.Equal(recovered, publicKey) {
+				t.Errorf("%s: signature does not recover the key: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestEthereumSignatureOtherKey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := crypto.Keccak256([]byte("digest"))
+	signature, err := crypto.Sign(digest, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der := derSignature(t, new(big.Int).SetBytes(signature[0:32]), new(big.Int).SetBytes(signature[32:64]))
+	if _, err := ethereumSignature(digest, der, crypto.FromECDSAPub(&other.PublicKey)); err == nil {
+		t.Error("signature was accepted for another key")
+	}
+	if _, err := ethereumSignature(digest, []byte("not der"), crypto.FromECDSAPub(&key.PublicKey)); err == nil {
+		t.Error("malformed signature was accepted")
+	}
+}
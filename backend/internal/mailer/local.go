@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ConsoleMailer logs messages instead of sending them, for development.
+type ConsoleMailer struct {
+	from string
+}
+
+func NewConsoleMailer(from string) *ConsoleMailer {
+	return &ConsoleMailer{from: from}
+}
+
+func (m *ConsoleMailer) Send(ctx context.Context, message Message) error {
+	log.Printf("Email to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// FileMailer writes each message to an .eml file in a directory, for
+// development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %v", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	if err := os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, message), 0o600); err != nil {
+		return fmt.Errorf("failed to write email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New returns the mailer selected by the configuration: "smtp" sends
+// through the SMTP server, "file" writes messages to a directory, and
+// "console" logs them.
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST must be set when MAILER=smtp")
+		}
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "file":
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case "", "console":
+		return NewConsoleMailer(cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER: %s", cfg.Mailer)
+	}
+}
@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "wallet"
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve API requests, by route and status code.",
+		// Sending a transaction waits for it to be mined, so the buckets go
+		// up to a minute
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route", "status"})
+
+	kmsSignDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kms_sign_duration_seconds",
+		Help:      "Time taken to sign a digest with a wallet key.",
+	}, []string{"provider"})
+
+	kmsSignErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kms_sign_errors_total",
+		Help:      "Digests the key manager failed to sign.",
+	}, []string{"provider"})
+
+	rpcCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_calls_total",
+		Help:      "JSON-RPC calls sent, by node and method.",
+	}, []string{"node", "method"})
+
+	rpcErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_errors_total",
+		Help:      "JSON-RPC HTTP requests that failed or got a non-2xx response, by node.",
+	}, []string{"node"})
+
+	transactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_total",
+		Help:      "Transactions sent, by outcome: success, reverted or failed.",
+	}, []string{"status"})
+
+	confirmationDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "transaction_confirmation_seconds",
+		Help:      "Time from broadcasting a transaction to getting its receipt.",
+		Buckets:   []float64{1, 2, 5, 10, 15, 20, 30, 45, 60, 120},
+	})
+)
+
+// TransactionStatusFailed labels transactions that were signed but could not
+// be sent or whose receipt never arrived.
+const TransactionStatusFailed = "failed"
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records a served API request. route is the route pattern,
+// such as /api/wallet/:address, so that addresses do not become labels.
+func ObserveRequest(method string, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	requestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveSign records a signature made with a key of the provider.
+func ObserveSign(provider string, duration time.Duration, err error) {
+	kmsSignDuration.WithLabelValues(provider).Observe(duration.Seconds())
+	if err != nil {
+		kmsSignErrors.WithLabelValues(provider).Inc()
+	}
+}
+
+// CountRPCCall records a JSON-RPC call sent to node.
+func CountRPCCall(node string, method string) {
+	rpcCalls.WithLabelValues(node, method).Inc()
+}
+
+// CountRPCError records a failed JSON-RPC request to node.
+func CountRPCError(node string) {
+	rpcErrors.WithLabelValues(node).Inc()
+}
+
+// CountTransaction records the outcome of sending a transaction.
+func CountTransaction(status string) {
+	transactions.WithLabelValues(status).Inc()
+}
+
+// ObserveConfirmation records how long a transaction took to be mined.
+func ObserveConfirmation(duration time.Duration) {
+	confirmationDuration.Observe(duration.Seconds())
+}
+
+// walletCollector reports the number of stored wallets, counted when the
+// metrics are scraped.
+type walletCollector struct {
+	count func(ctx context.Context) (int64, error)
+	desc  *prometheus.Desc
+}
+
+// RegisterWalletCount reports the number of wallets returned by count on
+// every scrape. It must only be called once.
+func RegisterWalletCount(count func(ctx context.Context) (int64, error)) {
+	prometheus.MustRegister(&walletCollector{
+		count: count,
+		desc:  prometheus.NewDesc(namespace+"_wallets", "Wallets stored.", nil, nil),
+	})
+}
+
+func (c *walletCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *walletCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := c.count(ctx)
+	if err != nil {
+		// The gauge is left out of this scrape rather than reported as zero
+		log.Printf("Failed to count wallets: %v", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count))
+}
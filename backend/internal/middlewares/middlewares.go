@@ -1,9 +1,20 @@
 package middlewares
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
 	"github.com/natneam/crypto-wallet-app/backend/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +25,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-TOTP-Code, X-WebAuthn-Assertion, X-API-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -26,9 +37,39 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+// MetricsMiddleware records the latency and status code of every request
+// by route.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+		metrics.ObserveRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(started))
+	}
+}
+
+// AuthMiddleware accepts either a Bearer access token or an API key, given in
+// the X-API-Key header or as "Authorization: ApiKey {key}".
 func AuthMiddleware(service *services.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		apiKey := c.GetHeader("X-API-Key")
+		if key, ok := strings.CutPrefix(authHeader, "ApiKey "); ok {
+			apiKey = key
+		}
+		if apiKey != "" {
+			key, err := service.ValidateAPIKey(c.Request.Context(), apiKey, c.ClientIP())
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+				c.Abort()
+				return
+			}
+
+			c.Set("user_id", key.UserID)
+			c.Set("api_key", key)
+			c.Next()
+			return
+		}
+
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			c.Abort()
@@ -42,7 +83,7 @@ func AuthMiddleware(service *services.Service) gin.HandlerFunc {
 			return
 		}
 
-		userID, err := service.ValidateToken(parts[1])
+		userID, sessionID, err := service.ValidateToken(c.Request.Context(), parts[1])
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
@@ -50,6 +91,198 @@ func AuthMiddleware(service *services.Service) gin.HandlerFunc {
 		}
 
 		c.Set("user_id", userID)
+		c.Set("session_id", sessionID)
+		c.Next()
+	}
+}
+
+// RequireTOTP asks for a TOTP or recovery code in the X-TOTP-Code header
+// before sensitive actions, for users that have two-factor authentication enabled.
+// Wrong codes are throttled like failed logins and answered with 429.
+func RequireTOTP(service *services.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// API keys are used without a person present and carry their own restrictions
+		if _, ok := c.Get("api_key"); ok {
+			c.Next()
+			return
+		}
+
+		userID, _ := c.Get("user_id")
+		err := service.VerifyStepUp(c.Request.Context(), userID.(string), c.GetHeader("X-TOTP-Code"), c.ClientIP())
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": throttled.Error()})
+			c.Abort()
+			return
+		}
+		if errors.Is(err, services.ErrSecondFactorRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "totp_required": true})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScope checks that requests authenticated with an API key have the
+// scope. For keys restricted to wallets, every wallet named by walletFields
+// must be allowed; fields starting with ":" are route parameters, the others
+// JSON body fields. Safes and smart accounts count as wallets, so a restricted
+// key must list their address. Keys restricted to wallets cannot act through
+// routes that do not name the wallet.
+func RequireScope(scope string, walletFields ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get("api_key")
+		if !ok {
+			c.Next()
+			return
+		}
+		apiKey := value.(models.APIKey)
+
+		if !slices.Contains(apiKey.Scopes, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("API key is missing the %s scope", scope)})
+			c.Abort()
+			return
+		}
+
+		if len(apiKey.Wallets) > 0 {
+			wallets, err := requestWallets(c, walletFields)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				c.Abort()
+				return
+			}
+
+			readOnly := scope == models.ScopeWalletsRead || scope == models.ScopeSchedulesRead
+			if len(wallets) == 0 && !readOnly {
+				c.JSON(http.StatusForbidden, gin.H{"error": "API key is restricted to specific wallets"})
+				c.Abort()
+				return
+			}
+			for _, wallet := range wallets {
+				if !slices.ContainsFunc(apiKey.Wallets, func(allowed string) bool { return strings.EqualFold(allowed, wallet) }) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "API key is not allowed to use wallet " + wallet})
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireUserSession rejects API keys on routes that manage the account itself.
+func RequireUserSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a user session"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requestWallets reads the wallet addresses named by fields from the route
+// parameters and the JSON body, leaving the body readable for the handler.
+// Handlers bind the body with encoding/json, which matches keys regardless of
+// case and keeps the last duplicate, so a body naming a field more than once
+// is rejected rather than checked against a value the handler would not use.
+func requestWallets(c *gin.Context, fields []string) ([]string, error) {
+	var wallets []string
+	var body []bodyField
+
+	for _, field := range fields {
+		if param, ok := strings.CutPrefix(field, ":"); ok {
+			if value := c.Param(param); value != "" {
+				wallets = append(wallets, value)
+			}
+			continue
+		}
+
+		if body == nil {
+			raw, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				return nil, err
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+			if body, err = readBodyFields(raw); err != nil {
+				return nil, err
+			}
+		}
+
+		var matches []json.RawMessage
+		for _, candidate := range body {
+			if strings.EqualFold(candidate.key, field) {
+				matches = append(matches, candidate.value)
+			}
+		}
+		if len(matches) > 1 {
+			return nil, fmt.Errorf("field %s is given more than once", field)
+		}
+		var value string
+		if len(matches) == 1 && json.Unmarshal(matches[0], &value) == nil && value != "" {
+			wallets = append(wallets, value)
+		}
+	}
+
+	return wallets, nil
+}
+
+type bodyField struct {
+	key   string
+	value json.RawMessage
+}
+
+// readBodyFields returns the top-level fields of a JSON object in order,
+// keeping duplicates.
+func readBodyFields(raw []byte) ([]bodyField, error) {
+	fields := []bodyField{}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return fields, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("request body is not a JSON object")
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, bodyField{key: token.(string), value: value})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// RequireAdmin only lets administrators through.
+func RequireAdmin(service *services.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		isAdmin, err := service.IsAdmin(c.Request.Context(), userID.(string))
+		if err != nil || !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Administrator access required"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireScopeChecksTheBoundWallet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const allowed = "0x1111111111111111111111111111111111111111"
+	const other = "0x2222222222222222222222222222222222222222"
+
+	var boundFrom string
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("api_key", models.APIKey{Scopes: []string{models.ScopeTxSend}, Wallets: []string{allowed}})
+	})
+	router.POST("/send", RequireScope(models.ScopeTxSend, "fromAddress"), func(c *gin.Context) {
+		var request struct {
+			FromAddress string `json:"fromAddress"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		boundFrom = request.FromAddress
+		c.Status(http.StatusOK)
+	})
+
+	for _, test := range []struct {
+		name string
+		body string
+		want int
+	}{
+		{"allowed wallet", `{"fromAddress":"` + allowed + `"}`, http.StatusOK},
+		{"other wallet", `{"fromAddress":"` + other + `"}`, http.StatusForbidden},
+		{"other wallet in another case", `{"FromAddress":"` + other + `"}`, http.StatusForbidden},
+		{"case-variant duplicate", `{"fromAddress":"` + allowed + `","FROMADDRESS":"` + other + `"}`, http.StatusBadRequest},
+		{"exact duplicate", `{"fromAddress":"` + allowed + `","fromAddress":"` + other + `"}`, http.StatusBadRequest},
+		{"not an object", `["` + allowed + `"]`, http.StatusBadRequest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			boundFrom = ""
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/send", strings.NewReader(test.body))
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.want {
+				t.Errorf("status = %d, want %d", recorder.Code, test.want)
+			}
+			if boundFrom != "" && !strings.EqualFold(boundFrom, allowed) {
+				t.Errorf("handler ran for wallet %s", boundFrom)
+			}
+		})
+	}
+}
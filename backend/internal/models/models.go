@@ -1,11 +1,27 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
 type TransactionRequest struct {
 	FromAddress string `json:"fromAddress"`
 	ToAddress   string `json:"toAddress"`
 	Value       string `json:"value"`
 }
 
+// TransactionPrepareRequest is a transaction to confirm with a passkey before
+// it is sent. Data is optional hex calldata, so contract calls can be
+// confirmed as well as transfers.
+type TransactionPrepareRequest struct {
+	TransactionRequest
+	Data string `json:"data"`
+}
+
 type TransactionResult struct {
 	TransactionHash string `json:"transactionHash"`
 	From            string `json:"from"`
@@ -14,8 +30,117 @@ type TransactionResult struct {
 	Value           string `json:"value"`
 	GasUsed         uint64 `json:"gasUsed"`
 	BlockNumber     uint64 `json:"blockNumber"`
+	Status          string `json:"status"`
+	Method          string `json:"method,omitempty"`
+	Data            string `json:"data,omitempty"`
+	UserOpHash      string `json:"userOpHash,omitempty"`
+	Nonce           uint64 `json:"nonce,omitempty"`
 	ID              string `json:"id" bson:"_id,omitempty"`
 	UserID          string `json:"user_id"`
+
+	// CreatedAt is when the transaction was recorded, set by the store.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Transaction statuses, taken from the receipt of a mined transaction.
+const (
+	TransactionStatusPending  = "pending"
+	TransactionStatusSuccess  = "success"
+	TransactionStatusReverted = "reverted"
+)
+
+// ContractCallRequest describes a smart-contract function call. Method is the
+// function name when ABI is given, or a full function signature otherwise,
+// e.g. "balanceOf(address)(uint256)". Value (in wei) and FromAddress are only
+// used for state-changing calls.
+type ContractCallRequest struct {
+	FromAddress     string            `json:"fromAddress"`
+	ContractAddress string            `json:"contractAddress"`
+	ABI             string            `json:"abi"`
+	Method          string            `json:"method"`
+	Args            []json.RawMessage `json:"args"`
+	Value           string            `json:"value"`
+}
+
+// ContractCallResult holds the decoded outputs of a read-only contract call.
+type ContractCallResult struct {
+	Outputs []interface{} `json:"outputs"`
+}
+
+// NFT is a non-fungible (ERC-721) or semi-fungible (ERC-1155) token held by a wallet.
+type NFT struct {
+	ContractAddress string `json:"contractAddress"`
+	Standard        string `json:"standard"`
+	TokenID         string `json:"tokenId"`
+	Balance         string `json:"balance"`
+	TokenURI        string `json:"tokenUri,omitempty"`
+}
+
+// NFTTransferRequest is the payload for an ERC-721 or ERC-1155 safeTransferFrom.
+// Amount is only used for ERC-1155 and defaults to 1; Data is optional hex.
+type NFTTransferRequest struct {
+	FromAddress     string `json:"fromAddress"`
+	ToAddress       string `json:"toAddress"`
+	ContractAddress string `json:"contractAddress"`
+	Standard        string `json:"standard"`
+	TokenID         string `json:"tokenId"`
+	Amount          string `json:"amount"`
+	Data            string `json:"data"`
+}
+
+// Allowance is the amount of an ERC-20 token a spender may transfer from a wallet.
+type Allowance struct {
+	TokenAddress   string `json:"tokenAddress"`
+	SpenderAddress string `json:"spenderAddress"`
+	Allowance      string `json:"allowance"`
+	Symbol         string `json:"symbol,omitempty"`
+	Decimals       uint8  `json:"decimals"`
+}
+
+// AllowanceRequest is the payload for granting or revoking an ERC-20 approval.
+// Amount is in the token's base units and is ignored on revoke.
+type AllowanceRequest struct {
+	FromAddress    string `json:"fromAddress"`
+	TokenAddress   string `json:"tokenAddress"`
+	SpenderAddress string `json:"spenderAddress"`
+	Amount         string `json:"amount"`
+}
+
+// SignMessageRequest is the payload for an EIP-191 personal_sign. Encoding is
+// "utf8" (default) or "hex" for raw bytes given as 0x-prefixed hex.
+type SignMessageRequest struct {
+	Address  string `json:"address"`
+	Message  string `json:"message"`
+	Encoding string `json:"encoding"`
+}
+
+// SignTypedDataRequest is the payload for an EIP-712 typed data signature.
+type SignTypedDataRequest struct {
+	Address   string             `json:"address"`
+	TypedData apitypes.TypedData `json:"typedData"`
+}
+
+// SignatureResult holds a 65-byte signature with V in {27, 28} and the signed hash.
+type SignatureResult struct {
+	Address   string `json:"address"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+}
+
+// VerifySignatureRequest checks a signature over either a personal message or
+// EIP-712 typed data.
+type VerifySignatureRequest struct {
+	Address   string              `json:"address"`
+	Signature string              `json:"signature"`
+	Message   string              `json:"message"`
+	Encoding  string              `json:"encoding"`
+	TypedData *apitypes.TypedData `json:"typedData"`
+}
+
+// VerifySignatureResult reports whether a signature was made by the given address.
+type VerifySignatureResult struct {
+	Valid            bool   `json:"valid"`
+	RecoveredAddress string `json:"recoveredAddress"`
 }
 
 // Wallet represents a user wallet.
@@ -26,6 +151,123 @@ type Wallet struct {
 	Balance   string `json:"balance"`
 	KMSKeyID  string `json:"kms_key_id"`
 	UserID    string `json:"user_id"`
+
+	// OrganisationID is set for wallets owned by an organisation, whose
+	// members act on them according to their role.
+	OrganisationID string `json:"organisation_id,omitempty"`
+
+	// NextNonce is one past the nonce of the last transaction recorded from
+	// the wallet.
+	NextNonce uint64 `json:"-"`
+}
+
+// SafeWallet is a Safe{Wallet} multisig whose owners are KMS wallets.
+type SafeWallet struct {
+	ID               string    `json:"id,omitempty" bson:"_id,omitempty"`
+	Name             string    `json:"name"`
+	Address          string    `json:"address"`
+	Owners           []string  `json:"owners"`
+	Threshold        uint64    `json:"threshold"`
+	Balance          string    `json:"balance"`
+	DeploymentTxHash string    `json:"deployment_tx_hash"`
+	UserID           string    `json:"user_id"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// SafeCreateRequest is the payload for deploying a new Safe. The deployment
+// is paid by FromAddress, which defaults to the first owner.
+type SafeCreateRequest struct {
+	Name        string   `json:"name"`
+	Owners      []string `json:"owners"`
+	Threshold   uint64   `json:"threshold"`
+	FromAddress string   `json:"fromAddress"`
+}
+
+// Safe transaction statuses.
+const (
+	SafeTransactionProposed  = "proposed"
+	SafeTransactionExecuting = "executing"
+	SafeTransactionExecuted  = "executed"
+	SafeTransactionFailed    = "failed"
+)
+
+// SafeTransactionRequest is the payload for proposing a Safe transaction.
+// Nonce defaults to the Safe's current on-chain nonce.
+type SafeTransactionRequest struct {
+	To        string `json:"to"`
+	Value     string `json:"value"`
+	Data      string `json:"data"`
+	Operation uint8  `json:"operation"`
+	Nonce     string `json:"nonce"`
+}
+
+// SafeSignature is an owner's signature over a Safe transaction hash.
+type SafeSignature struct {
+	Owner     string `json:"owner"`
+	Signature string `json:"signature"`
+}
+
+// SafeTransaction is a proposed Safe transaction collecting owner signatures.
+// Refund parameters (safeTxGas, baseGas, gasPrice, gasToken, refundReceiver)
+// are always zero: the executor pays the gas.
+type SafeTransaction struct {
+	ID              string          `json:"id,omitempty" bson:"_id,omitempty"`
+	SafeAddress     string          `json:"safeAddress"`
+	To              string          `json:"to"`
+	Value           string          `json:"value"`
+	Data            string          `json:"data"`
+	Operation       uint8           `json:"operation"`
+	Nonce           string          `json:"nonce"`
+	SafeTxHash      string          `json:"safeTxHash"`
+	Signatures      []SafeSignature `json:"signatures"`
+	Status          string          `json:"status"`
+	ExecutionTxHash string          `json:"executionTxHash,omitempty"`
+	UserID          string          `json:"user_id"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// SafeSignerRequest names the wallet that confirms or executes a Safe transaction.
+type SafeSignerRequest struct {
+	Address string `json:"address"`
+}
+
+// SmartAccount is an ERC-4337 smart-contract account owned by a KMS wallet.
+// It is deployed counterfactually with the first user operation.
+type SmartAccount struct {
+	ID           string    `json:"id,omitempty" bson:"_id,omitempty"`
+	Name         string    `json:"name"`
+	Address      string    `json:"address"`
+	OwnerAddress string    `json:"owner_address"`
+	Salt         string    `json:"salt"`
+	Factory      string    `json:"factory"`
+	EntryPoint   string    `json:"entry_point"`
+	Deployed     bool      `json:"deployed" bson:"-"`
+	Balance      string    `json:"balance"`
+	UserID       string    `json:"user_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SmartAccountCreateRequest is the payload for creating a smart account.
+// Salt defaults to 0 and distinguishes several accounts of the same owner.
+type SmartAccountCreateRequest struct {
+	Name         string `json:"name"`
+	OwnerAddress string `json:"ownerAddress"`
+	Salt         string `json:"salt"`
+}
+
+// UserOperationCall is a single call made by a smart account.
+type UserOperationCall struct {
+	To    string `json:"to"`
+	Value string `json:"value"`
+	Data  string `json:"data"`
+}
+
+// UserOperationRequest is the payload for sending a user operation. Several
+// calls are batched into one operation; Sponsored asks the configured
+// paymaster to pay for gas.
+type UserOperationRequest struct {
+	Calls     []UserOperationCall `json:"calls"`
+	Sponsored bool                `json:"sponsored"`
 }
 
 // User represents a user account.
@@ -36,4 +278,441 @@ type User struct {
 	PasswordHash string `json:"password_hash"`
 	CreatedAt    string `json:"created_at"`
 	UpdatedAt    string `json:"updated_at"`
+
+	// Status is empty for accounts created before email verification existed,
+	// which are treated as active.
+	Status  string `json:"status,omitempty"`
+	IsAdmin bool   `json:"is_admin,omitempty"`
+
+	// TOTP two-factor authentication. The pending secret is kept until the
+	// first code is confirmed; recovery codes are stored hashed.
+	TOTPEnabled        bool     `json:"totp_enabled"`
+	TOTPSecret         string   `json:"-"`
+	TOTPPendingSecret  string   `json:"-"`
+	TOTPLastStep       int64    `json:"-"`
+	RecoveryCodeHashes []string `json:"-"`
+
+	// WebAuthn user handle, and whether transactions need a passkey
+	// assertion over their hash before they are signed.
+	WebAuthnHandle                 []byte `json:"-"`
+	RequireWebAuthnForTransactions bool   `json:"require_webauthn_for_transactions"`
+
+	// Identity provider account linked for single sign-on.
+	OIDCIssuer  string `json:"-"`
+	OIDCSubject string `json:"-"`
+}
+
+// Schedule statuses.
+const (
+	ScheduleStatusActive    = "active"
+	ScheduleStatusPaused    = "paused"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusFailed    = "failed"
+)
+
+// ScheduleRequest is the payload for creating a scheduled transfer. Exactly
+// one of RunAt (one-off) or Cron (recurring) must be set.
+type ScheduleRequest struct {
+	Name        string     `json:"name"`
+	FromAddress string     `json:"fromAddress"`
+	ToAddress   string     `json:"toAddress"`
+	Value       string     `json:"value"`
+	RunAt       *time.Time `json:"runAt"`
+	Cron        string     `json:"cron"`
+}
+
+// Schedule represents a one-off or recurring transfer executed by the scheduler.
+type Schedule struct {
+	ID          string      `json:"id,omitempty" bson:"_id,omitempty"`
+	Name        string      `json:"name"`
+	FromAddress string      `json:"fromAddress"`
+	ToAddress   string      `json:"toAddress"`
+	Value       string      `json:"value"`
+	RunAt       *time.Time  `json:"runAt,omitempty"`
+	Cron        string      `json:"cron,omitempty"`
+	NextRunAt   time.Time   `json:"nextRunAt"`
+	LastRunAt   *time.Time  `json:"lastRunAt,omitempty"`
+	Status      string      `json:"status"`
+	LockedUntil time.Time   `json:"-"`
+	PendingRun  *PendingRun `json:"-"`
+	UserID      string      `json:"user_id"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// PendingRun is the transaction of a schedule run, recorded before it is
+// broadcast so that a run interrupted by a crash is not sent twice.
+type PendingRun struct {
+	StartedAt       time.Time
+	TransactionHash string
+	RawTransaction  string
+	Nonce           uint64
+}
+
+// ScheduleRun records a single execution of a schedule.
+type ScheduleRun struct {
+	ID              string    `json:"id,omitempty" bson:"_id,omitempty"`
+	ScheduleID      string    `json:"scheduleId"`
+	UserID          string    `json:"user_id"`
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+	Success         bool      `json:"success"`
+	TransactionHash string    `json:"transactionHash,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// User statuses.
+const (
+	UserStatusPendingVerification = "pending_verification"
+	UserStatusActive              = "active"
+)
+
+// User token purposes.
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+)
+
+// UserToken is a single-use token sent by email. Only its hash is stored.
+type UserToken struct {
+	ID        string `bson:"_id,omitempty"`
+	UserID    string
+	Purpose   string
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// Session is a login session backed by a rotating refresh token. Only hashes
+// of refresh tokens are stored.
+type Session struct {
+	ID                       string     `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID                   string     `json:"user_id"`
+	RefreshTokenHash         string     `json:"-"`
+	PreviousRefreshTokenHash string     `json:"-"`
+	CreatedAt                time.Time  `json:"created_at"`
+	LastUsedAt               time.Time  `json:"last_used_at"`
+	ExpiresAt                time.Time  `json:"expires_at"`
+	RevokedAt                *time.Time `json:"revoked_at,omitempty"`
+}
+
+// AuthTokens is returned on login and refresh.
+type AuthTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// LoginResult is returned on login. Users with two-factor authentication get
+// an MFA token to complete the second step instead of session tokens.
+type LoginResult struct {
+	*AuthTokens
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+// TOTPSetup is returned when TOTP enrolment starts.
+type TOTPSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodes are shown once when TOTP is enabled or the codes are regenerated.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// WebAuthnCredential is a passkey registered by a user.
+type WebAuthnCredential struct {
+	ID           string              `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID       string              `json:"user_id"`
+	Name         string              `json:"name"`
+	CredentialID string              `json:"credential_id"`
+	Credential   webauthn.Credential `json:"-"`
+	CreatedAt    time.Time           `json:"created_at"`
+	LastUsedAt   *time.Time          `json:"last_used_at,omitempty"`
+}
+
+// WebAuthn ceremony purposes.
+const (
+	WebAuthnPurposeRegistration = "registration"
+	WebAuthnPurposeSignUp       = "signup"
+	WebAuthnPurposeLogin        = "login"
+	WebAuthnPurposeTransaction  = "transaction"
+	WebAuthnPurposeSignature    = "signature"
+)
+
+// WebAuthnChallenge holds the server side state of a WebAuthn ceremony
+// between its begin and finish requests. Passkey sign-ups carry the pending
+// account, and signature confirmations carry the digest to be signed with the
+// prepared transaction or user operation it is the hash of.
+type WebAuthnChallenge struct {
+	ID            string `bson:"_id,omitempty"`
+	UserID        string
+	Purpose       string
+	Session       webauthn.SessionData
+	Username      string
+	Email         string
+	UserHandle    []byte
+	Digest        string
+	Transaction   *PreparedTransaction
+	UserOperation string
+	ExpiresAt     time.Time
+}
+
+// PreparedTransaction is an unsigned transaction awaiting confirmation.
+type PreparedTransaction struct {
+	From    string
+	RawTx   string
+	ChainID string
+}
+
+// WebAuthnCeremony is returned when a WebAuthn ceremony begins. Options are
+// passed to navigator.credentials.create or get.
+type WebAuthnCeremony struct {
+	ChallengeID     string      `json:"challenge_id"`
+	Options         interface{} `json:"options"`
+	TransactionHash string      `json:"transaction_hash,omitempty"`
+	Digest          string      `json:"digest,omitempty"`
+}
+
+// WebAuthnFinishRequest carries the authenticator response for a ceremony.
+type WebAuthnFinishRequest struct {
+	ChallengeID string          `json:"challenge_id" binding:"required"`
+	Name        string          `json:"name"`
+	Credential  json.RawMessage `json:"credential" binding:"required"`
+}
+
+// API key scopes.
+const (
+	ScopeWalletsRead    = "wallets:read"
+	ScopeWalletsWrite   = "wallets:write"
+	ScopeTxSend         = "tx:send"
+	ScopeMessagesSign   = "messages:sign"
+	ScopeSchedulesRead  = "schedules:read"
+	ScopeSchedulesWrite = "schedules:write"
+)
+
+// APIKeyScopes lists every scope an API key can be granted.
+var APIKeyScopes = []string{
+	ScopeWalletsRead,
+	ScopeWalletsWrite,
+	ScopeTxSend,
+	ScopeMessagesSign,
+	ScopeSchedulesRead,
+	ScopeSchedulesWrite,
+}
+
+// APIKey is a credential for machine-to-machine access on behalf of a user.
+// Only a hash of the key is stored; the prefix identifies it in listings.
+// Empty Wallets or AllowedCIDRs mean no restriction.
+type APIKey struct {
+	ID           string     `json:"id,omitempty" bson:"_id,omitempty"`
+	UserID       string     `json:"user_id"`
+	Name         string     `json:"name"`
+	Prefix       string     `json:"prefix"`
+	KeyHash      string     `json:"-"`
+	Scopes       []string   `json:"scopes"`
+	Wallets      []string   `json:"wallets"`
+	AllowedCIDRs []string   `json:"allowed_cidrs"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+type APIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	Wallets       []string `json:"wallets"`
+	AllowedCIDRs  []string `json:"allowed_cidrs"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// CreatedAPIKey is returned once when a key is created and is the only time
+// the full key is shown.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Organisation owns wallets shared by its members.
+type Organisation struct {
+	ID        string    `json:"id,omitempty" bson:"_id,omitempty"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Role      string    `json:"role,omitempty" bson:"-"`
+}
+
+// Organisation roles.
+const (
+	RoleOwner    = "owner"
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleApprover = "approver"
+	RoleViewer   = "viewer"
+)
+
+// OrganisationRoles lists the roles from most to least privileged.
+var OrganisationRoles = []string{RoleOwner, RoleAdmin, RoleOperator, RoleApprover, RoleViewer}
+
+// OrganisationMember gives a user a role in an organisation.
+type OrganisationMember struct {
+	ID             string    `json:"id,omitempty" bson:"_id,omitempty"`
+	OrganisationID string    `json:"organisation_id"`
+	UserID         string    `json:"user_id"`
+	Username       string    `json:"username"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type OrganisationMemberRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// OIDCState holds the nonce and PKCE verifier of a single sign-on login
+// between the redirect to the identity provider and the callback. It is
+// stored under the hash of the state parameter.
+type OIDCState struct {
+	StateHash    string `bson:"_id"`
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// OIDCAuthorization is where the browser is sent to sign in with the
+// identity provider.
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// LoginAttempt tracks recent failed logins for a username or a client IP.
+// Key is "user:{username}" or "ip:{address}".
+type LoginAttempt struct {
+	Key           string     `json:"key" bson:"_id"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+// AuditEntry records a security relevant action. Entries form a hash chain:
+// each hash covers the entry and the hash of the one before it, so editing
+// or removing an entry breaks every hash after it.
+type AuditEntry struct {
+	ID        string            `json:"-" bson:"_id,omitempty"`
+	Sequence  int64             `json:"sequence"`
+	Action    string            `json:"action"`
+	UserID    string            `json:"user_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	Target    string            `json:"target,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// Audited actions.
+const (
+	AuditLogin                 = "login"
+	AuditLoginFailed           = "login.failed"
+	AuditLoginUnlocked         = "login.unlocked"
+	AuditLogout                = "logout"
+	AuditPasswordReset         = "password.reset"
+	AuditTOTPEnabled           = "totp.enabled"
+	AuditTOTPDisabled          = "totp.disabled"
+	AuditPasskeyAdded          = "passkey.added"
+	AuditPasskeyRemoved        = "passkey.removed"
+	AuditTransactionPolicy     = "policy.transaction_confirmation"
+	AuditAPIKeyCreated         = "api_key.created"
+	AuditAPIKeyRevoked         = "api_key.revoked"
+	AuditOrganisationCreated   = "organisation.created"
+	AuditMemberAdded           = "organisation.member_added"
+	AuditMemberRoleChanged     = "organisation.member_role_changed"
+	AuditMemberRemoved         = "organisation.member_removed"
+	AuditAdminChanged          = "user.admin_changed"
+	AuditWalletCreated         = "wallet.created"
+	AuditTransactionSigned     = "transaction.signed"
+	AuditMessageSigned         = "message.signed"
+	AuditSafeTransactionSigned = "safe_transaction.signed"
+	AuditUserOperationSigned   = "user_operation.signed"
+)
+
+// AuditQuery filters audit entries. Zero fields match everything.
+type AuditQuery struct {
+	UserID string    `form:"user_id"`
+	Action string    `form:"action"`
+	Target string    `form:"target"`
+	Since  time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until  time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit  int64     `form:"limit"`
+}
+
+// AuditVerification is the result of checking the audit hash chain. HeadHash
+// can be recorded elsewhere to also detect entries removed from the end.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Entries  int64  `json:"entries"`
+	HeadHash string `json:"head_hash,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// HealthCheck is the outcome of checking one dependency.
+type HealthCheck struct {
+	Name      string `json:"name"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// Readiness reports whether the instance can serve requests, with the
+// dependency checks it is based on.
+type Readiness struct {
+	Ready   bool          `json:"ready"`
+	Checks  []HealthCheck `json:"checks"`
+	Checked time.Time     `json:"checked_at"`
+}
+
+// ChainStatus describes the head of the chain the node is following. Lag is
+// how long ago the head block was produced.
+type ChainStatus struct {
+	ChainID    string    `json:"chain_id"`
+	HeadBlock  uint64    `json:"head_block"`
+	HeadHash   string    `json:"head_hash"`
+	HeadTime   time.Time `json:"head_time"`
+	LagSeconds float64   `json:"lag_seconds"`
+}
+
+// VersionInfo identifies the running build.
+type VersionInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// ServiceStatus is the detailed state of the instance shown to operators.
+type ServiceStatus struct {
+	Version        VersionInfo  `json:"version"`
+	StartedAt      time.Time    `json:"started_at"`
+	UptimeSeconds  int64        `json:"uptime_seconds"`
+	StorageBackend string       `json:"storage_backend"`
+	KMSProvider    string       `json:"kms_provider"`
+	Readiness      Readiness    `json:"readiness"`
+	Chain          *ChainStatus `json:"chain,omitempty"`
+	ChainError     string       `json:"chain_error,omitempty"`
 }
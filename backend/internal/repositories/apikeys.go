@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *Repository) SaveAPIKey(ctx context.Context, apiKey *models.APIKey) (models.APIKey, error) {
+	collection := r.collection("api_keys")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, apiKey)
+	if err != nil {
+		return *apiKey, fmt.Errorf("failed to insert api key into database: %v", err)
+	}
+	apiKey.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *apiKey, nil
+}
+
+func (r *Repository) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	collection := r.collection("api_keys")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var apiKey models.APIKey
+	err := collection.FindOne(ctx, bson.M{"keyhash": keyHash}).Decode(&apiKey)
+	if err != nil {
+		return apiKey, fmt.Errorf("failed to find api key: %v", err)
+	}
+	return apiKey, nil
+}
+
+func (r *Repository) ListAPIKeys(ctx context.Context, userId string) ([]models.APIKey, error) {
+	collection := r.collection("api_keys")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"userid": userId}, options.Find().SetSort(bson.M{"createdat": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api keys: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	apiKeys := []models.APIKey{}
+	if err := cursor.All(ctx, &apiKeys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %v", err)
+	}
+	return apiKeys, nil
+}
+
+func (r *Repository) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	collection := r.collection("api_keys")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid api key id: %v", err)
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"lastusedat": usedAt}})
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %v", err)
+	}
+	return nil
+}
+
+func (r *Repository) RevokeAPIKey(ctx context.Context, id string, userId string) error {
+	collection := r.collection("api_keys")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid api key id: %v", err)
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "userid": userId, "revokedat": nil},
+		bson.M{"$set": bson.M{"revokedat": time.Now().UTC()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("api key not found")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrAuditSequenceTaken is returned when another writer appended an audit
+// entry with the same sequence number first.
+var ErrAuditSequenceTaken = errors.New("audit sequence already taken")
+
+// The audit collection is append-only: entries are only ever inserted.
+
+// GetLastAuditEntry returns the entry with the highest sequence number, or a
+// zero entry when the log is empty.
+func (r *Repository) GetLastAuditEntry(ctx context.Context) (models.AuditEntry, error) {
+	collection := r.collection("audit_log")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var entry models.AuditEntry
+	err := collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"sequence": -1})).Decode(&entry)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return entry, fmt.Errorf("failed to find last audit entry: %v", err)
+	}
+	return entry, nil
+}
+
+// InsertAuditEntry appends an entry. The unique sequence index makes
+// concurrent writers that chained onto the same entry fail with
+// ErrAuditSequenceTaken, so they can retry on the new head.
+func (r *Repository) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	collection := r.collection("audit_log")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := collection.InsertOne(ctx, entry)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAuditSequenceTaken
+		}
+		return fmt.Errorf("failed to insert audit entry into database: %v", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns the entries matching the query, newest first.
+func (r *Repository) ListAuditEntries(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error) {
+	collection := r.collection("audit_log")
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if query.UserID != "" {
+		filter["userid"] = query.UserID
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.Target != "" {
+		filter["target"] = query.Target
+	}
+	createdAt := bson.M{}
+	if !query.Since.IsZero() {
+		createdAt["$gte"] = query.Since
+	}
+	if !query.Until.IsZero() {
+		createdAt["$lt"] = query.Until
+	}
+	if len(createdAt) > 0 {
+		filter["createdat"] = createdAt
+	}
+
+	findOptions := options.Find().SetSort(bson.M{"sequence": -1})
+	if query.Limit > 0 {
+		findOptions.SetLimit(query.Limit)
+	}
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit entries: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entries: %v", err)
+	}
+	return entries, nil
+}
+
+// WalkAuditEntries calls fn for every entry in sequence order, stopping at
+// the first error fn returns.
+func (r *Repository) WalkAuditEntries(ctx context.Context, fn func(models.AuditEntry) error) error {
+	collection := r.collection("audit_log")
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"sequence": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to find audit entries: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return fmt.Errorf("failed to decode audit entry: %v", err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
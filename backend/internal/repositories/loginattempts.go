@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetLoginAttempts returns the attempt records that exist for the keys.
+func (r *Repository) GetLoginAttempts(ctx context.Context, keys []string) ([]models.LoginAttempt, error) {
+	collection := r.collection("login_attempts")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find login attempts: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	attempts := []models.LoginAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, fmt.Errorf("failed to decode login attempts: %v", err)
+	}
+	return attempts, nil
+}
+
+// RecordLoginFailure counts a failed login for the key and returns the
+// updated record. Failures older than window no longer count, so the counter
+// starts again from one.
+func (r *Repository) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (models.LoginAttempt, error) {
+	collection := r.collection("login_attempts")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	var attempt models.LoginAttempt
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.A{bson.M{"$set": bson.M{
+		"failures": bson.M{"$cond": bson.A{
+			bson.M{"$gt": bson.A{"$lastfailureat", now.Add(-window)}},
+			bson.M{"$add": bson.A{"$failures", 1}},
+			1,
+		}},
+		"lastfailureat": now,
+	}}}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&attempt)
+	if err != nil {
+		return attempt, fmt.Errorf("failed to record login failure: %v", err)
+	}
+	return attempt, nil
+}
+
+// LockLogin blocks logins for the key until the given time.
+func (r *Repository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	collection := r.collection("login_attempts")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": key}, bson.M{"$set": bson.M{"lockeduntil": until}})
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %v", err)
+	}
+	return nil
+}
+
+// ClearLoginAttempts forgets the failures of a key, after a successful login
+// or an admin unlock.
+func (r *Repository) ClearLoginAttempts(ctx context.Context, key string) error {
+	collection := r.collection("login_attempts")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return fmt.Errorf("failed to clear login attempts: %v", err)
+	}
+	return nil
+}
+
+// ListLockedLogins returns the keys that are currently locked.
+func (r *Repository) ListLockedLogins(ctx context.Context) ([]models.LoginAttempt, error) {
+	collection := r.collection("login_attempts")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"lockeduntil": bson.M{"$gt": time.Now().UTC()}},
+		options.Find().SetSort(bson.M{"lockeduntil": -1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find locked logins: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	attempts := []models.LoginAttempt{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, fmt.Errorf("failed to decode login attempts: %v", err)
+	}
+	return attempts, nil
+}
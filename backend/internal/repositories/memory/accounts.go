@@ -0,0 +1,417 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+func (s *Store) CreateSession(ctx context.Context, session *models.Session) (models.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session.ID = newID()
+	s.sessions = append(s.sessions, *session)
+	return *session, nil
+}
+
+func (s *Store) GetSession(ctx context.Context, id string) (models.Session, error) {
+	if err := checkID("session", id); err != nil {
+		return models.Session{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.ID == id {
+			return session, nil
+		}
+	}
+	return models.Session{}, notFound("session")
+}
+
+func (s *Store) GetSessionByRefreshToken(ctx context.Context, tokenHash string) (models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.RefreshTokenHash == tokenHash || session.PreviousRefreshTokenHash == tokenHash {
+			return session, nil
+		}
+	}
+	return models.Session{}, notFound("session")
+}
+
+func (s *Store) RotateSessionToken(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) error {
+	if err := checkID("session", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.sessions {
+		session := &s.sessions[i]
+		if session.ID == id && session.RefreshTokenHash == oldHash && session.RevokedAt == nil {
+			session.RefreshTokenHash = newHash
+			session.PreviousRefreshTokenHash = oldHash
+			session.LastUsedAt = time.Now().UTC()
+			session.ExpiresAt = expiresAt
+			return nil
+		}
+	}
+	return notFound("session")
+}
+
+func (s *Store) RevokeSession(ctx context.Context, id string) error {
+	if err := checkID("session", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.sessions {
+		if s.sessions[i].ID == id && s.sessions[i].RevokedAt == nil {
+			now := time.Now().UTC()
+			s.sessions[i].RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *Store) RevokeUserSessions(ctx context.Context, userId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range s.sessions {
+		if s.sessions[i].UserID == userId && s.sessions[i].RevokedAt == nil {
+			s.sessions[i].RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func cloneAPIKey(apiKey models.APIKey) models.APIKey {
+	apiKey.Scopes = slices.Clone(apiKey.Scopes)
+	apiKey.Wallets = slices.Clone(apiKey.Wallets)
+	apiKey.AllowedCIDRs = slices.Clone(apiKey.AllowedCIDRs)
+	return apiKey
+}
+
+func (s *Store) SaveAPIKey(ctx context.Context, apiKey *models.APIKey) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.apiKeys {
+		if existing.KeyHash == apiKey.KeyHash {
+			return *apiKey, fmt.Errorf("failed to insert api key into database: duplicate key")
+		}
+	}
+	apiKey.ID = newID()
+	s.apiKeys = append(s.apiKeys, cloneAPIKey(*apiKey))
+	return *apiKey, nil
+}
+
+func (s *Store) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, apiKey := range s.apiKeys {
+		if apiKey.KeyHash == keyHash {
+			return cloneAPIKey(apiKey), nil
+		}
+	}
+	return models.APIKey{}, notFound("api key")
+}
+
+func (s *Store) ListAPIKeys(ctx context.Context, userId string) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	apiKeys := []models.APIKey{}
+	for _, apiKey := range s.apiKeys {
+		if apiKey.UserID == userId {
+			apiKeys = append(apiKeys, cloneAPIKey(apiKey))
+		}
+	}
+	slices.SortStableFunc(apiKeys, func(a, b models.APIKey) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return apiKeys, nil
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	if err := checkID("api key", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == id {
+			s.apiKeys[i].LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, id string, userId string) error {
+	if err := checkID("api key", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.apiKeys {
+		apiKey := &s.apiKeys[i]
+		if apiKey.ID == id && apiKey.UserID == userId && apiKey.RevokedAt == nil {
+			now := time.Now().UTC()
+			apiKey.RevokedAt = &now
+			return nil
+		}
+	}
+	return fmt.Errorf("api key not found")
+}
+
+func (s *Store) SaveUserToken(ctx context.Context, token *models.UserToken) (models.UserToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Earlier unused tokens of the same purpose stop working
+	now := time.Now().UTC()
+	for i := range s.userTokens {
+		existing := &s.userTokens[i]
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose && existing.UsedAt == nil {
+			existing.UsedAt = &now
+		}
+	}
+
+	token.ID = newID()
+	s.userTokens = append(s.userTokens, *token)
+	return *token, nil
+}
+
+func (s *Store) ConsumeUserToken(ctx context.Context, tokenHash string, purpose string) (models.UserToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range s.userTokens {
+		token := &s.userTokens[i]
+		if token.TokenHash == tokenHash && token.Purpose == purpose && token.UsedAt == nil && token.ExpiresAt.After(now) {
+			// The MongoDB implementation returns the token as it was
+			consumed := *token
+			token.UsedAt = &now
+			return consumed, nil
+		}
+	}
+	return models.UserToken{}, fmt.Errorf("invalid or expired token")
+}
+
+func (s *Store) GetLoginAttempts(ctx context.Context, keys []string) ([]models.LoginAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	attempts := []models.LoginAttempt{}
+	for _, key := range keys {
+		if attempt, ok := s.loginAttempts[key]; ok {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts, nil
+}
+
+func (s *Store) RecordLoginFailure(ctx context.Context, key string, window time.Duration) (models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	attempt, ok := s.loginAttempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	if attempt.LastFailureAt.After(now.Add(-window)) {
+		attempt.Failures++
+	} else {
+		attempt.Failures = 1
+	}
+	attempt.LastFailureAt = now
+	s.loginAttempts[key] = attempt
+	return attempt, nil
+}
+
+func (s *Store) LockLogin(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.loginAttempts[key]; ok {
+		attempt.LockedUntil = &until
+		s.loginAttempts[key] = attempt
+	}
+	return nil
+}
+
+func (s *Store) ClearLoginAttempts(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.loginAttempts, key)
+	return nil
+}
+
+func (s *Store) ListLockedLogins(ctx context.Context) ([]models.LoginAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now().UTC()
+	attempts := []models.LoginAttempt{}
+	for _, attempt := range s.loginAttempts {
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			attempts = append(attempts, attempt)
+		}
+	}
+	slices.SortFunc(attempts, func(a, b models.LoginAttempt) int { return b.LockedUntil.Compare(*a.LockedUntil) })
+	return attempts, nil
+}
+
+func (s *Store) SaveOIDCState(ctx context.Context, state *models.OIDCState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.oidcStates[state.StateHash]; ok {
+		return fmt.Errorf("failed to insert OIDC state into database: duplicate key")
+	}
+	s.oidcStates[state.StateHash] = *state
+	return nil
+}
+
+func (s *Store) TakeOIDCState(ctx context.Context, stateHash string) (models.OIDCState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.oidcStates[stateHash]
+	if !ok || !state.ExpiresAt.After(time.Now().UTC()) {
+		return models.OIDCState{}, fmt.Errorf("login state not found or expired")
+	}
+	delete(s.oidcStates, stateHash)
+	return state, nil
+}
+
+func (s *Store) SaveWebAuthnCredential(ctx context.Context, credential *models.WebAuthnCredential) (models.WebAuthnCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.webAuthnCredentials {
+		if bytes.Equal(existing.Credential.ID, credential.Credential.ID) {
+			return *credential, fmt.Errorf("credential already registered")
+		}
+	}
+	credential.ID = newID()
+	s.webAuthnCredentials = append(s.webAuthnCredentials, *credential)
+	return *credential, nil
+}
+
+func (s *Store) ListWebAuthnCredentials(ctx context.Context, userId string) ([]models.WebAuthnCredential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	credentials := []models.WebAuthnCredential{}
+	for _, credential := range s.webAuthnCredentials {
+		if credential.UserID == userId {
+			credentials = append(credentials, credential)
+		}
+	}
+	slices.SortStableFunc(credentials, func(a, b models.WebAuthnCredential) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return credentials, nil
+}
+
+func (s *Store) UpdateWebAuthnCredentialUse(ctx context.Context, credential *models.WebAuthnCredential) error {
+	if err := checkID("credential", credential.ID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.webAuthnCredentials {
+		if s.webAuthnCredentials[i].ID == credential.ID {
+			s.webAuthnCredentials[i].Credential = credential.Credential
+			s.webAuthnCredentials[i].LastUsedAt = credential.LastUsedAt
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteWebAuthnCredential(ctx context.Context, id string, userId string) error {
+	if err := checkID("credential", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, credential := range s.webAuthnCredentials {
+		if credential.ID == id && credential.UserID == userId {
+			s.webAuthnCredentials = slices.Delete(s.webAuthnCredentials, i, i+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("credential not found")
+}
+
+func (s *Store) SaveWebAuthnChallenge(ctx context.Context, challenge *models.WebAuthnChallenge) (models.WebAuthnChallenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge.ID = newID()
+	s.webAuthnChallenges = append(s.webAuthnChallenges, *challenge)
+	return *challenge, nil
+}
+
+// findWebAuthnChallenge returns the index of an unexpired challenge, or -1.
+// The caller holds the lock.
+func (s *Store) findWebAuthnChallenge(id string, purpose string) int {
+	now := time.Now().UTC()
+	return slices.IndexFunc(s.webAuthnChallenges, func(challenge models.WebAuthnChallenge) bool {
+		return challenge.ID == id && challenge.Purpose == purpose && challenge.ExpiresAt.After(now)
+	})
+}
+
+func (s *Store) TakeWebAuthnChallenge(ctx context.Context, id string, purpose string) (models.WebAuthnChallenge, error) {
+	if err := checkID("challenge", id); err != nil {
+		return models.WebAuthnChallenge{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findWebAuthnChallenge(id, purpose)
+	if i < 0 {
+		return models.WebAuthnChallenge{}, fmt.Errorf("challenge not found or expired")
+	}
+	challenge := s.webAuthnChallenges[i]
+	s.webAuthnChallenges = slices.Delete(s.webAuthnChallenges, i, i+1)
+	return challenge, nil
+}
+
+func (s *Store) GetWebAuthnChallenge(ctx context.Context, id string, purpose string) (models.WebAuthnChallenge, error) {
+	if err := checkID("challenge", id); err != nil {
+		return models.WebAuthnChallenge{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findWebAuthnChallenge(id, purpose)
+	if i < 0 {
+		return models.WebAuthnChallenge{}, fmt.Errorf("challenge not found or expired")
+	}
+	return s.webAuthnChallenges[i], nil
+}
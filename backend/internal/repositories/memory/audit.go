@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+)
+
+// The audit log is kept in sequence order and is append-only.
+
+func (s *Store) GetLastAuditEntry(ctx context.Context) (models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.auditLog) == 0 {
+		return models.AuditEntry{}, nil
+	}
+	return s.auditLog[len(s.auditLog)-1], nil
+}
+
+func (s *Store) InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, found := slices.BinarySearchFunc(s.auditLog, entry.Sequence, func(existing models.AuditEntry, sequence int64) int {
+		return cmp.Compare(existing.Sequence, sequence)
+	})
+	if found {
+		return repositories.ErrAuditSequenceTaken
+	}
+	entry.ID = newID()
+	s.auditLog = slices.Insert(s.auditLog, i, *entry)
+	return nil
+}
+
+func (s *Store) ListAuditEntries(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	for i := len(s.auditLog) - 1; i >= 0; i-- {
+		entry := s.auditLog[i]
+		switch {
+		case query.UserID != "" && entry.UserID != query.UserID,
+			query.Action != "" && entry.Action != query.Action,
+			query.Target != "" && entry.Target != query.Target,
+			!query.Since.IsZero() && entry.CreatedAt.Before(query.Since),
+			!query.Until.IsZero() && !entry.CreatedAt.Before(query.Until):
+			continue
+		}
+		entries = append(entries, entry)
+		if query.Limit > 0 && int64(len(entries)) == query.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+func (s *Store) WalkAuditEntries(ctx context.Context, fn func(models.AuditEntry) error) error {
+	s.mu.RLock()
+	entries := slices.Clone(s.auditLog)
+	s.mu.RUnlock()
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to find audit entries: %v", err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
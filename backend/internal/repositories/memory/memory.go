@@ -0,0 +1,356 @@
+// Package memory implements every store in memory, for tests and local
+// experiments, so it can back the services without a database. It follows
+// the MongoDB implementation's semantics, which the repotest suite checks for
+// both.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store holds everything in memory. It implements repositories.Store and is
+// safe for concurrent use.
+type Store struct {
+	mu           sync.RWMutex
+	users        []*models.User
+	wallets      []models.Wallet
+	transactions []models.TransactionResult
+
+	sessions            []models.Session
+	apiKeys             []models.APIKey
+	userTokens          []models.UserToken
+	loginAttempts       map[string]models.LoginAttempt
+	oidcStates          map[string]models.OIDCState
+	webAuthnCredentials []models.WebAuthnCredential
+	webAuthnChallenges  []models.WebAuthnChallenge
+	auditLog            []models.AuditEntry
+	organisations       []models.Organisation
+	members             []models.OrganisationMember
+	safes               []models.SafeWallet
+	safeTransactions    []models.SafeTransaction
+	smartAccounts       []models.SmartAccount
+	schedules           []models.Schedule
+	scheduleRuns        []models.ScheduleRun
+}
+
+func NewStore() *Store {
+	return &Store{
+		loginAttempts: map[string]models.LoginAttempt{},
+		oidcStates:    map[string]models.OIDCState{},
+	}
+}
+
+// Ping always succeeds.
+func (s *Store) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Stores returns the store as every repositories store.
+func (s *Store) Stores() repositories.Stores {
+	return repositories.Stores{Users: s, Wallets: s, Transactions: s}
+}
+
+// IDs look like MongoDB object ids, so code parsing them behaves the same.
+func newID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// checkID rejects ids the MongoDB implementation could not parse.
+func checkID(what string, id string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return fmt.Errorf("invalid %s id: %v", what, err)
+	}
+	return nil
+}
+
+func notFound(what string) error {
+	return fmt.Errorf("failed to find %s: %w", what, repositories.ErrNotFound)
+}
+
+func cloneUser(user *models.User) *models.User {
+	clone := *user
+	clone.RecoveryCodeHashes = slices.Clone(user.RecoveryCodeHashes)
+	clone.WebAuthnHandle = slices.Clone(user.WebAuthnHandle)
+	return &clone
+}
+
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return repositories.ErrEmailTaken
+		}
+		if existing.Username == user.Username {
+			return repositories.ErrUsernameTaken
+		}
+	}
+
+	stored := cloneUser(user)
+	stored.ID = newID()
+	s.users = append(s.users, stored)
+	return nil
+}
+
+func (s *Store) findUser(match func(*models.User) bool) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if match(user) {
+			return cloneUser(user), nil
+		}
+	}
+	return nil, notFound("user")
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.findUser(func(user *models.User) bool { return user.Username == username })
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
+	return s.findUser(func(user *models.User) bool { return user.ID == id })
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.findUser(func(user *models.User) bool { return user.Email == email })
+}
+
+func (s *Store) GetUserByWebAuthnHandle(ctx context.Context, handle []byte) (*models.User, error) {
+	return s.findUser(func(user *models.User) bool {
+		return len(handle) > 0 && bytes.Equal(user.WebAuthnHandle, handle)
+	})
+}
+
+func (s *Store) GetUserByOIDCSubject(ctx context.Context, issuer string, subject string) (*models.User, error) {
+	return s.findUser(func(user *models.User) bool {
+		return user.OIDCIssuer == issuer && user.OIDCSubject == subject
+	})
+}
+
+// updateUser applies update to the user if the user also matches filter,
+// like the MongoDB implementation's conditional updates.
+func (s *Store) updateUser(userId string, filter func(*models.User) bool, update func(*models.User)) error {
+	if _, err := primitive.ObjectIDFromHex(userId); err != nil {
+		return fmt.Errorf("invalid user id: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.ID == userId {
+			if filter != nil && !filter(user) {
+				return repositories.ErrUserNotUpdated
+			}
+			update(user)
+			return nil
+		}
+	}
+	return repositories.ErrUserNotUpdated
+}
+
+func (s *Store) SetUserStatus(ctx context.Context, userId string, status string) error {
+	return s.updateUser(userId, nil, func(user *models.User) {
+		user.Status = status
+		user.UpdatedAt = time.Now().Format(time.RFC3339)
+	})
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, userId string, passwordHash string) error {
+	return s.updateUser(userId, nil, func(user *models.User) {
+		user.PasswordHash = passwordHash
+		user.UpdatedAt = time.Now().Format(time.RFC3339)
+	})
+}
+
+func (s *Store) SetUserAdmin(ctx context.Context, userId string, isAdmin bool) error {
+	return s.updateUser(userId, nil, func(user *models.User) { user.IsAdmin = isAdmin })
+}
+
+func (s *Store) SetPendingTOTPSecret(ctx context.Context, userId string, secret string) error {
+	return s.updateUser(userId, nil, func(user *models.User) { user.TOTPPendingSecret = secret })
+}
+
+func (s *Store) EnableTOTP(ctx context.Context, userId string, secret string, step int64, recoveryCodeHashes []string) error {
+	return s.updateUser(userId,
+		func(user *models.User) bool { return user.TOTPPendingSecret == secret },
+		func(user *models.User) {
+			user.TOTPEnabled = true
+			user.TOTPSecret = secret
+			user.TOTPPendingSecret = ""
+			user.TOTPLastStep = step
+			user.RecoveryCodeHashes = slices.Clone(recoveryCodeHashes)
+		})
+}
+
+func (s *Store) DisableTOTP(ctx context.Context, userId string) error {
+	return s.updateUser(userId, nil, func(user *models.User) {
+		user.TOTPEnabled = false
+		user.TOTPSecret = ""
+		user.TOTPPendingSecret = ""
+		user.TOTPLastStep = 0
+		user.RecoveryCodeHashes = []string{}
+	})
+}
+
+func (s *Store) SetRecoveryCodes(ctx context.Context, userId string, recoveryCodeHashes []string) error {
+	return s.updateUser(userId,
+		func(user *models.User) bool { return user.TOTPEnabled },
+		func(user *models.User) { user.RecoveryCodeHashes = slices.Clone(recoveryCodeHashes) })
+}
+
+func (s *Store) AdvanceTOTPStep(ctx context.Context, userId string, step int64) error {
+	return s.updateUser(userId,
+		func(user *models.User) bool { return user.TOTPLastStep < step },
+		func(user *models.User) { user.TOTPLastStep = step })
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	return s.updateUser(userId,
+		func(user *models.User) bool { return slices.Contains(user.RecoveryCodeHashes, codeHash) },
+		func(user *models.User) {
+			user.RecoveryCodeHashes = slices.DeleteFunc(user.RecoveryCodeHashes, func(hash string) bool { return hash == codeHash })
+		})
+}
+
+func (s *Store) SetWebAuthnHandle(ctx context.Context, userId string, handle []byte) error {
+	return s.updateUser(userId,
+		func(user *models.User) bool { return len(user.WebAuthnHandle) == 0 },
+		func(user *models.User) { user.WebAuthnHandle = slices.Clone(handle) })
+}
+
+func (s *Store) SetRequireWebAuthnForTransactions(ctx context.Context, userId string, required bool) error {
+	return s.updateUser(userId, nil, func(user *models.User) { user.RequireWebAuthnForTransactions = required })
+}
+
+func (s *Store) LinkOIDCIdentity(ctx context.Context, userId string, issuer string, subject string) error {
+	return s.updateUser(userId,
+		func(user *models.User) bool { return user.OIDCSubject == "" },
+		func(user *models.User) {
+			user.OIDCIssuer = issuer
+			user.OIDCSubject = subject
+			user.Status = models.UserStatusActive
+		})
+}
+
+func (s *Store) SaveWallet(ctx context.Context, wallet *models.Wallet) (models.Wallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wallet.ID = newID()
+	s.wallets = append(s.wallets, *wallet)
+	return *wallet, nil
+}
+
+func (s *Store) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, wallet := range s.wallets {
+		if wallet.PublicKey == address {
+			return wallet, nil
+		}
+	}
+	return models.Wallet{}, notFound("wallet")
+}
+
+func (s *Store) ListWallets(ctx context.Context, userId string, organisationIds []string) ([]models.Wallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var wallets []models.Wallet
+	for _, wallet := range s.wallets {
+		personal := wallet.UserID == userId && wallet.OrganisationID == ""
+		if personal || slices.Contains(organisationIds, wallet.OrganisationID) {
+			wallets = append(wallets, wallet)
+		}
+	}
+	return wallets, nil
+}
+
+func (s *Store) CountWallets(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.wallets)), nil
+}
+
+func (s *Store) SaveTransaction(ctx context.Context, transaction *models.TransactionResult) (models.TransactionResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction.ID = newID()
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	}
+	s.transactions = append(s.transactions, *transaction)
+
+	// User operations are sent by the bundler with the smart account's nonce
+	if transaction.UserOpHash == "" && transaction.TransactionHash != "" {
+		for i := range s.wallets {
+			if s.wallets[i].PublicKey == transaction.From {
+				s.wallets[i].NextNonce = max(s.wallets[i].NextNonce, transaction.Nonce+1)
+			}
+		}
+	}
+	return *transaction, nil
+}
+
+func (s *Store) GetTransactionByUserOpHash(ctx context.Context, userOpHash string, sender string) (models.TransactionResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, transaction := range s.transactions {
+		if transaction.UserOpHash == userOpHash && transaction.From == sender {
+			return transaction, nil
+		}
+	}
+	return models.TransactionResult{}, notFound("transaction")
+}
+
+func (s *Store) UpdateTransactionReceipt(ctx context.Context, transaction *models.TransactionResult) error {
+	if _, err := primitive.ObjectIDFromHex(transaction.ID); err != nil {
+		return fmt.Errorf("invalid transaction id: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.transactions {
+		if s.transactions[i].ID == transaction.ID {
+			s.transactions[i].TransactionHash = transaction.TransactionHash
+			s.transactions[i].BlockNumber = transaction.BlockNumber
+			s.transactions[i].GasUsed = transaction.GasUsed
+			s.transactions[i].Status = transaction.Status
+		}
+	}
+	return nil
+}
+
+func (s *Store) FindTransactions(ctx context.Context, from string, method string) ([]models.TransactionResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var transactions []models.TransactionResult
+	for _, transaction := range s.transactions {
+		if transaction.From == from && transaction.Method == method {
+			transactions = append(transactions, transaction)
+		}
+	}
+	return transactions, nil
+}
@@ -0,0 +1,18 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/memory"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/repotest"
+)
+
+func TestStore(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repositories.Stores {
+		return memory.NewStore().Stores()
+	})
+	repotest.RunStore(t, func(t *testing.T) repositories.Store {
+		return memory.NewStore()
+	})
+}
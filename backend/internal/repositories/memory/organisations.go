@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+func (s *Store) SaveOrganisation(ctx context.Context, organisation *models.Organisation) (models.Organisation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	organisation.ID = newID()
+	stored := *organisation
+	// The role is the caller's, and is not stored with the organisation
+	stored.Role = ""
+	s.organisations = append(s.organisations, stored)
+	return *organisation, nil
+}
+
+func (s *Store) GetOrganisation(ctx context.Context, id string) (models.Organisation, error) {
+	if err := checkID("organisation", id); err != nil {
+		return models.Organisation{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, organisation := range s.organisations {
+		if organisation.ID == id {
+			return organisation, nil
+		}
+	}
+	return models.Organisation{}, notFound("organisation")
+}
+
+func (s *Store) ListOrganisations(ctx context.Context, ids []string) ([]models.Organisation, error) {
+	for _, id := range ids {
+		if err := checkID("organisation", id); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	organisations := []models.Organisation{}
+	for _, organisation := range s.organisations {
+		if slices.Contains(ids, organisation.ID) {
+			organisations = append(organisations, organisation)
+		}
+	}
+	slices.SortStableFunc(organisations, func(a, b models.Organisation) int { return strings.Compare(a.Name, b.Name) })
+	return organisations, nil
+}
+
+func (s *Store) SaveOrganisationMember(ctx context.Context, member *models.OrganisationMember) (models.OrganisationMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Each user has at most one role per organisation
+	for _, existing := range s.members {
+		if existing.OrganisationID == member.OrganisationID && existing.UserID == member.UserID {
+			return *member, fmt.Errorf("user is already a member")
+		}
+	}
+	member.ID = newID()
+	s.members = append(s.members, *member)
+	return *member, nil
+}
+
+func (s *Store) GetOrganisationMember(ctx context.Context, organisationId string, userId string) (models.OrganisationMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, member := range s.members {
+		if member.OrganisationID == organisationId && member.UserID == userId {
+			return member, nil
+		}
+	}
+	return models.OrganisationMember{}, notFound("member")
+}
+
+func (s *Store) ListOrganisationMembers(ctx context.Context, organisationId string) ([]models.OrganisationMember, error) {
+	return s.findOrganisationMembers(func(member models.OrganisationMember) bool { return member.OrganisationID == organisationId })
+}
+
+func (s *Store) ListMemberships(ctx context.Context, userId string) ([]models.OrganisationMember, error) {
+	return s.findOrganisationMembers(func(member models.OrganisationMember) bool { return member.UserID == userId })
+}
+
+func (s *Store) findOrganisationMembers(match func(models.OrganisationMember) bool) ([]models.OrganisationMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := []models.OrganisationMember{}
+	for _, member := range s.members {
+		if match(member) {
+			members = append(members, member)
+		}
+	}
+	slices.SortStableFunc(members, func(a, b models.OrganisationMember) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return members, nil
+}
+
+func (s *Store) UpdateOrganisationMemberRole(ctx context.Context, organisationId string, userId string, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.members {
+		if s.members[i].OrganisationID == organisationId && s.members[i].UserID == userId {
+			s.members[i].Role = role
+			return nil
+		}
+	}
+	return fmt.Errorf("member not found")
+}
+
+func (s *Store) DeleteOrganisationMember(ctx context.Context, organisationId string, userId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, member := range s.members {
+		if member.OrganisationID == organisationId && member.UserID == userId {
+			s.members = slices.Delete(s.members, i, i+1)
+			return nil
+		}
+	}
+	return fmt.Errorf("member not found")
+}
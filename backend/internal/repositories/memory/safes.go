@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+func cloneSafe(safe models.SafeWallet) models.SafeWallet {
+	safe.Owners = slices.Clone(safe.Owners)
+	return safe
+}
+
+func (s *Store) SaveSafe(ctx context.Context, safe *models.SafeWallet) (models.SafeWallet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	safe.ID = newID()
+	s.safes = append(s.safes, cloneSafe(*safe))
+	return *safe, nil
+}
+
+func (s *Store) GetSafe(ctx context.Context, address string) (models.SafeWallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, safe := range s.safes {
+		if safe.Address == address {
+			return cloneSafe(safe), nil
+		}
+	}
+	return models.SafeWallet{}, notFound("safe")
+}
+
+func (s *Store) ListSafes(ctx context.Context, owners []string) ([]models.SafeWallet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var safes []models.SafeWallet
+	for _, safe := range s.safes {
+		if slices.ContainsFunc(safe.Owners, func(owner string) bool { return slices.Contains(owners, owner) }) {
+			safes = append(safes, cloneSafe(safe))
+		}
+	}
+	return safes, nil
+}
+
+func cloneSafeTransaction(transaction models.SafeTransaction) models.SafeTransaction {
+	transaction.Signatures = slices.Clone(transaction.Signatures)
+	return transaction
+}
+
+func (s *Store) SaveSafeTransaction(ctx context.Context, transaction *models.SafeTransaction) (models.SafeTransaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction.ID = newID()
+	s.safeTransactions = append(s.safeTransactions, cloneSafeTransaction(*transaction))
+	return *transaction, nil
+}
+
+func (s *Store) GetSafeTransaction(ctx context.Context, id string, safeAddress string) (models.SafeTransaction, error) {
+	if err := checkID("safe transaction", id); err != nil {
+		return models.SafeTransaction{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, transaction := range s.safeTransactions {
+		if transaction.ID == id && transaction.SafeAddress == safeAddress {
+			return cloneSafeTransaction(transaction), nil
+		}
+	}
+	return models.SafeTransaction{}, notFound("safe transaction")
+}
+
+func (s *Store) ListSafeTransactions(ctx context.Context, safeAddress string) ([]models.SafeTransaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var transactions []models.SafeTransaction
+	for _, transaction := range s.safeTransactions {
+		if transaction.SafeAddress == safeAddress {
+			transactions = append(transactions, cloneSafeTransaction(transaction))
+		}
+	}
+	slices.SortStableFunc(transactions, func(a, b models.SafeTransaction) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return transactions, nil
+}
+
+func (s *Store) AddSafeSignature(ctx context.Context, id string, signature models.SafeSignature) error {
+	if err := checkID("safe transaction", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.safeTransactions {
+		transaction := &s.safeTransactions[i]
+		if transaction.ID != id || transaction.Status != models.SafeTransactionProposed {
+			continue
+		}
+		signed := slices.ContainsFunc(transaction.Signatures, func(existing models.SafeSignature) bool {
+			return existing.Owner == signature.Owner
+		})
+		if !signed {
+			transaction.Signatures = append(transaction.Signatures, signature)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ClaimSafeTransaction(ctx context.Context, id string) error {
+	return s.updateSafeTransaction(id,
+		func(transaction *models.SafeTransaction) bool {
+			return transaction.Status == models.SafeTransactionProposed
+		},
+		func(transaction *models.SafeTransaction) { transaction.Status = models.SafeTransactionExecuting })
+}
+
+func (s *Store) SetSafeExecutionTxHash(ctx context.Context, id string, executionTxHash string) error {
+	return s.updateSafeTransaction(id,
+		func(transaction *models.SafeTransaction) bool {
+			return transaction.Status == models.SafeTransactionExecuting
+		},
+		func(transaction *models.SafeTransaction) { transaction.ExecutionTxHash = executionTxHash })
+}
+
+func (s *Store) ReleaseSafeTransaction(ctx context.Context, id string) error {
+	return s.updateSafeTransaction(id,
+		func(transaction *models.SafeTransaction) bool {
+			return transaction.Status == models.SafeTransactionExecuting && transaction.ExecutionTxHash == ""
+		},
+		func(transaction *models.SafeTransaction) { transaction.Status = models.SafeTransactionProposed })
+}
+
+func (s *Store) MarkSafeTransactionExecuted(ctx context.Context, id string, status string, executionTxHash string) error {
+	return s.updateSafeTransaction(id,
+		func(transaction *models.SafeTransaction) bool {
+			return transaction.Status == models.SafeTransactionExecuting
+		},
+		func(transaction *models.SafeTransaction) {
+			transaction.Status = status
+			transaction.ExecutionTxHash = executionTxHash
+		})
+}
+
+// updateSafeTransaction applies update to the Safe transaction with the id if
+// it also matches filter. It fails if it does not.
+func (s *Store) updateSafeTransaction(id string, filter func(*models.SafeTransaction) bool, update func(*models.SafeTransaction)) error {
+	if err := checkID("safe transaction", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.safeTransactions {
+		transaction := &s.safeTransactions[i]
+		if transaction.ID == id && filter(transaction) {
+			update(transaction)
+			return nil
+		}
+	}
+	return notFound("safe transaction")
+}
+
+func (s *Store) SaveSmartAccount(ctx context.Context, account *models.SmartAccount) (models.SmartAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account.ID = newID()
+	stored := *account
+	// Deployment is read from the chain, not stored
+	stored.Deployed = false
+	s.smartAccounts = append(s.smartAccounts, stored)
+	return *account, nil
+}
+
+func (s *Store) GetSmartAccount(ctx context.Context, address string) (models.SmartAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, account := range s.smartAccounts {
+		if account.Address == address {
+			return account, nil
+		}
+	}
+	return models.SmartAccount{}, notFound("smart account")
+}
+
+func (s *Store) ListSmartAccounts(ctx context.Context, owners []string) ([]models.SmartAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var accounts []models.SmartAccount
+	for _, account := range s.smartAccounts {
+		if slices.Contains(owners, account.OwnerAddress) {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
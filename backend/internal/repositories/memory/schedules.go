@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+// cloneSchedule copies the fields a schedule points to, so callers cannot
+// change the stored schedule.
+func cloneSchedule(schedule models.Schedule) models.Schedule {
+	if schedule.RunAt != nil {
+		runAt := *schedule.RunAt
+		schedule.RunAt = &runAt
+	}
+	if schedule.LastRunAt != nil {
+		lastRunAt := *schedule.LastRunAt
+		schedule.LastRunAt = &lastRunAt
+	}
+	if schedule.PendingRun != nil {
+		pending := *schedule.PendingRun
+		schedule.PendingRun = &pending
+	}
+	return schedule
+}
+
+func (s *Store) SaveSchedule(ctx context.Context, schedule *models.Schedule) (models.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule.ID = newID()
+	s.schedules = append(s.schedules, cloneSchedule(*schedule))
+	return *schedule, nil
+}
+
+func (s *Store) GetSchedule(ctx context.Context, id string) (models.Schedule, error) {
+	if err := checkID("schedule", id); err != nil {
+		return models.Schedule{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, schedule := range s.schedules {
+		if schedule.ID == id {
+			return cloneSchedule(schedule), nil
+		}
+	}
+	return models.Schedule{}, notFound("schedule")
+}
+
+func (s *Store) ListSchedules(ctx context.Context, wallets []string) ([]models.Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var schedules []models.Schedule
+	for _, schedule := range s.schedules {
+		if slices.Contains(wallets, schedule.FromAddress) {
+			schedules = append(schedules, cloneSchedule(schedule))
+		}
+	}
+	slices.SortStableFunc(schedules, func(a, b models.Schedule) int { return a.NextRunAt.Compare(b.NextRunAt) })
+	return schedules, nil
+}
+
+// updateSchedule applies update to the schedule with the id, failing if there
+// is none.
+func (s *Store) updateSchedule(id string, update func(*models.Schedule)) error {
+	if err := checkID("schedule", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.schedules {
+		if s.schedules[i].ID == id {
+			update(&s.schedules[i])
+			s.schedules[i].UpdatedAt = time.Now().UTC()
+			return nil
+		}
+	}
+	return notFound("schedule")
+}
+
+func (s *Store) UpdateScheduleState(ctx context.Context, id string, status string, nextRunAt time.Time) error {
+	return s.updateSchedule(id, func(schedule *models.Schedule) {
+		schedule.Status = status
+		schedule.NextRunAt = nextRunAt
+	})
+}
+
+func (s *Store) PauseUserSchedules(ctx context.Context, userId string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var paused int64
+	for i := range s.schedules {
+		schedule := &s.schedules[i]
+		if schedule.UserID == userId && schedule.Status == models.ScheduleStatusActive {
+			schedule.Status = models.ScheduleStatusPaused
+			schedule.UpdatedAt = time.Now().UTC()
+			paused++
+		}
+	}
+	return paused, nil
+}
+
+func (s *Store) DeleteSchedule(ctx context.Context, id string) error {
+	if err := checkID("schedule", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, schedule := range s.schedules {
+		if schedule.ID == id {
+			s.schedules = slices.Delete(s.schedules, i, i+1)
+			return nil
+		}
+	}
+	return notFound("schedule")
+}
+
+func (s *Store) ClaimDueSchedule(ctx context.Context, now time.Time, lease time.Duration) (*models.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due *models.Schedule
+	for i := range s.schedules {
+		schedule := &s.schedules[i]
+		if schedule.Status != models.ScheduleStatusActive || schedule.NextRunAt.After(now) || schedule.LockedUntil.After(now) {
+			continue
+		}
+		if due == nil || schedule.NextRunAt.Before(due.NextRunAt) {
+			due = schedule
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+
+	due.LockedUntil = now.Add(lease)
+	claimed := cloneSchedule(*due)
+	return &claimed, nil
+}
+
+func (s *Store) FinishScheduleRun(ctx context.Context, id string, ranAt time.Time, status string, nextRunAt time.Time) error {
+	if err := checkID("schedule", id); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.schedules {
+		schedule := &s.schedules[i]
+		if schedule.ID != id {
+			continue
+		}
+		schedule.LastRunAt = &ranAt
+		schedule.NextRunAt = nextRunAt
+		schedule.LockedUntil = time.Time{}
+		schedule.PendingRun = nil
+		schedule.UpdatedAt = time.Now().UTC()
+		// A pause issued while the run was in flight is preserved
+		if schedule.Status == models.ScheduleStatusActive {
+			schedule.Status = status
+		}
+	}
+	return nil
+}
+
+func (s *Store) SetPendingRun(ctx context.Context, id string, pending models.PendingRun) error {
+	return s.updateSchedule(id, func(schedule *models.Schedule) { schedule.PendingRun = &pending })
+}
+
+func (s *Store) SaveScheduleRun(ctx context.Context, run *models.ScheduleRun) (models.ScheduleRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run.ID = newID()
+	s.scheduleRuns = append(s.scheduleRuns, *run)
+	return *run, nil
+}
+
+func (s *Store) ListScheduleRuns(ctx context.Context, scheduleId string) ([]models.ScheduleRun, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var runs []models.ScheduleRun
+	for _, run := range s.scheduleRuns {
+		if run.ScheduleID == scheduleId {
+			runs = append(runs, run)
+		}
+	}
+	slices.SortStableFunc(runs, func(a, b models.ScheduleRun) int { return b.StartedAt.Compare(a.StartedAt) })
+	return runs, nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (r *Repository) SaveOIDCState(ctx context.Context, state *models.OIDCState) error {
+	collection := r.collection("oidc_states")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := collection.InsertOne(ctx, state)
+	if err != nil {
+		return fmt.Errorf("failed to insert OIDC state into database: %v", err)
+	}
+	return nil
+}
+
+// TakeOIDCState removes and returns an unexpired login state, so each
+// authorization response can only be used once.
+func (r *Repository) TakeOIDCState(ctx context.Context, stateHash string) (models.OIDCState, error) {
+	collection := r.collection("oidc_states")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var state models.OIDCState
+	err := collection.FindOneAndDelete(ctx, bson.M{
+		"_id":       stateHash,
+		"expiresat": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&state)
+	if err != nil {
+		return state, fmt.Errorf("login state not found or expired")
+	}
+	return state, nil
+}
+
+func (r *Repository) GetUserByOIDCSubject(ctx context.Context, issuer string, subject string) (*models.User, error) {
+	collection := r.collection("users")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"oidcissuer": issuer, "oidcsubject": subject}).Decode(&user)
+	if err != nil {
+		return nil, findError("user", err)
+	}
+	return &user, nil
+}
+
+// LinkOIDCIdentity links an identity provider account to a user that has
+// none yet. The provider verified the email, so the account is activated.
+func (r *Repository) LinkOIDCIdentity(ctx context.Context, userId string, issuer string, subject string) error {
+	return r.updateUser(ctx, userId, bson.M{"oidcsubject": bson.M{"$in": bson.A{nil, ""}}}, bson.M{"$set": bson.M{
+		"oidcissuer":  issuer,
+		"oidcsubject": subject,
+		"status":      models.UserStatusActive,
+	}})
+}
+
+func (r *Repository) SetUserAdmin(ctx context.Context, userId string, isAdmin bool) error {
+	return r.updateUser(ctx, userId, bson.M{}, bson.M{"$set": bson.M{"isadmin": isAdmin}})
+}
@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *Repository) SaveOrganisation(ctx context.Context, organisation *models.Organisation) (models.Organisation, error) {
+	collection := r.collection("organisations")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, organisation)
+	if err != nil {
+		return *organisation, fmt.Errorf("failed to insert organisation into database: %v", err)
+	}
+	organisation.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *organisation, nil
+}
+
+func (r *Repository) GetOrganisation(ctx context.Context, id string) (models.Organisation, error) {
+	collection := r.collection("organisations")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var organisation models.Organisation
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return organisation, fmt.Errorf("invalid organisation id: %v", err)
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&organisation)
+	if err != nil {
+		return organisation, fmt.Errorf("failed to find organisation: %v", err)
+	}
+	return organisation, nil
+}
+
+func (r *Repository) ListOrganisations(ctx context.Context, ids []string) ([]models.Organisation, error) {
+	collection := r.collection("organisations")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objectID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid organisation id: %v", err)
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": objectIDs}}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organisations: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	organisations := []models.Organisation{}
+	if err := cursor.All(ctx, &organisations); err != nil {
+		return nil, fmt.Errorf("failed to decode organisations: %v", err)
+	}
+	return organisations, nil
+}
+
+func (r *Repository) SaveOrganisationMember(ctx context.Context, member *models.OrganisationMember) (models.OrganisationMember, error) {
+	collection := r.collection("organisation_members")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, member)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return *member, fmt.Errorf("user is already a member")
+		}
+		return *member, fmt.Errorf("failed to insert member into database: %v", err)
+	}
+	member.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *member, nil
+}
+
+func (r *Repository) GetOrganisationMember(ctx context.Context, organisationId string, userId string) (models.OrganisationMember, error) {
+	collection := r.collection("organisation_members")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var member models.OrganisationMember
+	err := collection.FindOne(ctx, bson.M{"organisationid": organisationId, "userid": userId}).Decode(&member)
+	if err != nil {
+		return member, fmt.Errorf("failed to find member: %v", err)
+	}
+	return member, nil
+}
+
+func (r *Repository) ListOrganisationMembers(ctx context.Context, organisationId string) ([]models.OrganisationMember, error) {
+	return r.findOrganisationMembers(ctx, bson.M{"organisationid": organisationId})
+}
+
+// ListMemberships returns the organisations memberships of a user.
+func (r *Repository) ListMemberships(ctx context.Context, userId string) ([]models.OrganisationMember, error) {
+	return r.findOrganisationMembers(ctx, bson.M{"userid": userId})
+}
+
+func (r *Repository) findOrganisationMembers(ctx context.Context, filter bson.M) ([]models.OrganisationMember, error) {
+	collection := r.collection("organisation_members")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(bson.M{"createdat": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find members: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	members := []models.OrganisationMember{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode members: %v", err)
+	}
+	return members, nil
+}
+
+func (r *Repository) UpdateOrganisationMemberRole(ctx context.Context, organisationId string, userId string, role string) error {
+	collection := r.collection("organisation_members")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"organisationid": organisationId, "userid": userId},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update member: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("member not found")
+	}
+	return nil
+}
+
+func (r *Repository) DeleteOrganisationMember(ctx context.Context, organisationId string, userId string) error {
+	collection := r.collection("organisation_members")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.DeleteOne(ctx, bson.M{"organisationid": organisationId, "userid": userId})
+	if err != nil {
+		return fmt.Errorf("failed to delete member: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("member not found")
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+CREATE TABLE users (
+    id                                text PRIMARY KEY,
+    username                          text NOT NULL,
+    email                             text NOT NULL,
+    password_hash                     text NOT NULL DEFAULT '',
+    created_at                        text NOT NULL DEFAULT '',
+    updated_at                        text NOT NULL DEFAULT '',
+    status                            text NOT NULL DEFAULT '',
+    is_admin                          boolean NOT NULL DEFAULT false,
+    totp_enabled                      boolean NOT NULL DEFAULT false,
+    totp_secret                       text NOT NULL DEFAULT '',
+    totp_pending_secret               text NOT NULL DEFAULT '',
+    totp_last_step                    bigint NOT NULL DEFAULT 0,
+    recovery_code_hashes              text[] NOT NULL DEFAULT '{}',
+    webauthn_handle                   bytea,
+    require_webauthn_for_transactions boolean NOT NULL DEFAULT false,
+    oidc_issuer                       text NOT NULL DEFAULT '',
+    oidc_subject                      text NOT NULL DEFAULT '',
+    CONSTRAINT users_username_key UNIQUE (username),
+    CONSTRAINT users_email_key UNIQUE (email)
+);
+
+CREATE UNIQUE INDEX users_webauthn_handle_key ON users (webauthn_handle) WHERE webauthn_handle IS NOT NULL;
+CREATE UNIQUE INDEX users_oidc_subject_key ON users (oidc_issuer, oidc_subject) WHERE oidc_subject <> '';
@@ -0,0 +1,16 @@
+CREATE TABLE wallets (
+    id              text PRIMARY KEY,
+    name            text NOT NULL DEFAULT '',
+    address         text NOT NULL,
+    balance         text NOT NULL DEFAULT '',
+    kms_key_id      text NOT NULL DEFAULT '',
+    user_id         text NOT NULL DEFAULT '',
+    organisation_id text NOT NULL DEFAULT '',
+    -- next_nonce is one more than the highest nonce recorded for a
+    -- transaction sent from the wallet
+    next_nonce      bigint NOT NULL DEFAULT 0
+);
+
+CREATE INDEX wallets_address_idx ON wallets (address);
+CREATE INDEX wallets_user_id_idx ON wallets (user_id);
+CREATE INDEX wallets_organisation_id_idx ON wallets (organisation_id) WHERE organisation_id <> '';
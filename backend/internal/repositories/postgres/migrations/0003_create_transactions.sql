@@ -0,0 +1,21 @@
+CREATE TABLE transactions (
+    id               text PRIMARY KEY,
+    transaction_hash text NOT NULL DEFAULT '',
+    from_address     text NOT NULL DEFAULT '',
+    to_address       text NOT NULL DEFAULT '',
+    gas_price        text NOT NULL DEFAULT '',
+    value            text NOT NULL DEFAULT '',
+    gas_used         bigint NOT NULL DEFAULT 0,
+    block_number     bigint NOT NULL DEFAULT 0,
+    status           text NOT NULL DEFAULT '',
+    method           text NOT NULL DEFAULT '',
+    data             text NOT NULL DEFAULT '',
+    user_op_hash     text NOT NULL DEFAULT '',
+    nonce            bigint NOT NULL DEFAULT 0,
+    user_id          text NOT NULL DEFAULT '',
+    created_at       timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX transactions_from_method_idx ON transactions (from_address, method);
+CREATE INDEX transactions_user_op_hash_idx ON transactions (user_op_hash, user_id) WHERE user_op_hash <> '';
+CREATE INDEX transactions_user_id_idx ON transactions (user_id, created_at);
@@ -0,0 +1,103 @@
+// Package postgres implements the user, wallet and transaction stores in
+// PostgreSQL, for deployments that want them in a relational database. The
+// other stores have no PostgreSQL implementation and stay in MongoDB. It
+// follows the MongoDB implementation's semantics, which the repotest suite
+// checks.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Store keeps users, wallets and transactions in PostgreSQL.
+type Store struct {
+	pool *pgxpool.Pool
+}
+
+// Open connects to the database at url and applies any pending schema
+// migrations. Queries are traced.
+func Open(url string) (*Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PostgreSQL URL: %v", err)
+	}
+	config.ConnConfig.Tracer = queryTracer{}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %v", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %v", err)
+	}
+
+	if err := Migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &Store{pool: pool}, nil
+}
+
+// Ping checks that the database can be reached.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
+
+func (s *Store) Close() {
+	s.pool.Close()
+}
+
+// Stores returns the store as every repositories store.
+func (s *Store) Stores() repositories.Stores {
+	return repositories.Stores{Users: s, Wallets: s, Transactions: s}
+}
+
+// withTx runs fn in a transaction, committing it if fn succeeds.
+func (s *Store) withTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// IDs look like MongoDB object ids, so the records can be referenced from
+// the collections that stay in MongoDB.
+func newID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+func findError(what string, err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to find %s: %w", what, repositories.ErrNotFound)
+	}
+	return fmt.Errorf("failed to find %s: %v", what, err)
+}
+
+// uniqueViolation returns the constraint a unique violation was raised for.
+func uniqueViolation(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return pgErr.ConstraintName, true
+	}
+	return "", false
+}
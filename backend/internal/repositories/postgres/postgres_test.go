@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/repotest"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration %s has version %d, expected %d", m.Name, m.Version, i+1)
+		}
+	}
+}
+
+// TestStore runs the store conformance suite against the PostgreSQL at
+// POSTGRES_TEST_URL, migrating a new schema for every test.
+func TestStore(t *testing.T) {
+	newStore := testDatabase(t)
+	repotest.Run(t, func(t *testing.T) repositories.Stores {
+		store, _ := newStore(t)
+		return store.Stores()
+	})
+}
+
+// TestSaveTransactionIsAtomic makes the wallet nonce update fail with a
+// trigger and checks that the transaction insert is rolled back with it.
+func TestSaveTransactionIsAtomic(t *testing.T) {
+	newStore := testDatabase(t)
+	store, schema := newStore(t)
+	repotest.RunFailedNonceUpdate(t, store.Stores(), func(t *testing.T) {
+		_, err := store.pool.Exec(context.Background(), `
+			CREATE FUNCTION `+schema+`.reject_update() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'wallet updates are disabled';
+			END
+			$$ LANGUAGE plpgsql;
+			CREATE TRIGGER reject_update BEFORE UPDATE ON `+schema+`.wallets
+				FOR EACH ROW EXECUTE FUNCTION `+schema+`.reject_update();`)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// testDatabase connects to the PostgreSQL at POSTGRES_TEST_URL, skipping the
+// test without one. The returned function opens a store on a new migrated
+// schema and returns it with the schema's name.
+func testDatabase(t *testing.T) func(t *testing.T) (*Store, string) {
+	uri := os.Getenv("POSTGRES_TEST_URL")
+	if uri == "" {
+		t.Skip("POSTGRES_TEST_URL not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	admin, err := pgxpool.New(ctx, uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+
+	count := 0
+	return func(t *testing.T) (*Store, string) {
+		count++
+		schema := fmt.Sprintf("walletdb_test_%d_%d", time.Now().UnixNano(), count)
+		if _, err := admin.Exec(context.Background(), "CREATE SCHEMA "+schema); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE") })
+
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := parsed.Query()
+		query.Set("search_path", schema)
+		parsed.RawQuery = query.Encode()
+
+		store, err := Open(parsed.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(store.Close)
+		return store, schema
+	}
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const transactionColumns = `id, transaction_hash, from_address, to_address, gas_price, value, gas_used,
+	block_number, status, method, data, user_op_hash, nonce, user_id, created_at`
+
+func scanTransaction(row pgx.Row) (models.TransactionResult, error) {
+	var transaction models.TransactionResult
+	var gasUsed, blockNumber, nonce int64
+	err := row.Scan(&transaction.ID, &transaction.TransactionHash, &transaction.From, &transaction.To,
+		&transaction.GasPrice, &transaction.Value, &gasUsed, &blockNumber, &transaction.Status,
+		&transaction.Method, &transaction.Data, &transaction.UserOpHash, &nonce, &transaction.UserID,
+		&transaction.CreatedAt)
+	transaction.CreatedAt = transaction.CreatedAt.UTC()
+	transaction.GasUsed = uint64(gasUsed)
+	transaction.BlockNumber = uint64(blockNumber)
+	transaction.Nonce = uint64(nonce)
+	return transaction, err
+}
+
+// SaveTransaction records a sent transaction. Transactions sent directly
+// from a wallet also advance the wallet's next nonce in the same database
+// transaction, so the history and the nonce never disagree.
+func (s *Store) SaveTransaction(ctx context.Context, transaction *models.TransactionResult) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	transaction.ID = newID()
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	}
+	err := s.withTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `INSERT INTO transactions (`+transactionColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			transaction.ID, transaction.TransactionHash, transaction.From, transaction.To,
+			transaction.GasPrice, transaction.Value, int64(transaction.GasUsed), int64(transaction.BlockNumber),
+			transaction.Status, transaction.Method, transaction.Data, transaction.UserOpHash,
+			int64(transaction.Nonce), transaction.UserID, transaction.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		// User operations are sent by the bundler with the smart account's nonce
+		if transaction.UserOpHash != "" || transaction.TransactionHash == "" {
+			return nil
+		}
+		_, err = tx.Exec(ctx, `UPDATE wallets SET next_nonce = GREATEST(next_nonce, $2)
+			WHERE address = $1`, transaction.From, int64(transaction.Nonce)+1)
+		return err
+	})
+	if err != nil {
+		return models.TransactionResult{}, fmt.Errorf("failed to save transaction: %v", err)
+	}
+	return *transaction, nil
+}
+
+func (s *Store) GetTransactionByUserOpHash(ctx context.Context, userOpHash string, sender string) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	transaction, err := scanTransaction(s.pool.QueryRow(ctx, "SELECT "+transactionColumns+` FROM transactions
+		WHERE user_op_hash = $1 AND from_address = $2 ORDER BY created_at LIMIT 1`, userOpHash, sender))
+	if err != nil {
+		return models.TransactionResult{}, findError("transaction", err)
+	}
+	return transaction, nil
+}
+
+func (s *Store) UpdateTransactionReceipt(ctx context.Context, transaction *models.TransactionResult) error {
+	if _, err := primitive.ObjectIDFromHex(transaction.ID); err != nil {
+		return fmt.Errorf("invalid transaction id: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, `UPDATE transactions
+		SET transaction_hash = $2, block_number = $3, gas_used = $4, status = $5
+		WHERE id = $1`, transaction.ID, transaction.TransactionHash, int64(transaction.BlockNumber),
+		int64(transaction.GasUsed), transaction.Status)
+	if err != nil {
+		return fmt.Errorf("failed to update transaction: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) FindTransactions(ctx context.Context, from string, method string) ([]models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, "SELECT "+transactionColumns+` FROM transactions
+		WHERE from_address = $1 AND method = $2 ORDER BY created_at`, from, method)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.TransactionResult, error) {
+		return scanTransaction(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
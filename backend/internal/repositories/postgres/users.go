@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+
+	"github.com/jackc/pgx/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const userColumns = `id, username, email, password_hash, created_at, updated_at, status, is_admin,
+	totp_enabled, totp_secret, totp_pending_secret, totp_last_step, recovery_code_hashes,
+	webauthn_handle, require_webauthn_for_transactions, oidc_issuer, oidc_subject`
+
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt,
+		&user.Status, &user.IsAdmin, &user.TOTPEnabled, &user.TOTPSecret, &user.TOTPPendingSecret, &user.TOTPLastStep,
+		&user.RecoveryCodeHashes, &user.WebAuthnHandle, &user.RequireWebAuthnForTransactions,
+		&user.OIDCIssuer, &user.OIDCSubject)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	recoveryCodeHashes := user.RecoveryCodeHashes
+	if recoveryCodeHashes == nil {
+		recoveryCodeHashes = []string{}
+	}
+	var webAuthnHandle []byte
+	if len(user.WebAuthnHandle) > 0 {
+		webAuthnHandle = user.WebAuthnHandle
+	}
+
+	_, err := s.pool.Exec(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+		newID(), user.Username, user.Email, user.PasswordHash, user.CreatedAt, user.UpdatedAt,
+		user.Status, user.IsAdmin, user.TOTPEnabled, user.TOTPSecret, user.TOTPPendingSecret, user.TOTPLastStep,
+		recoveryCodeHashes, webAuthnHandle, user.RequireWebAuthnForTransactions,
+		user.OIDCIssuer, user.OIDCSubject)
+	if constraint, ok := uniqueViolation(err); ok {
+		switch constraint {
+		case "users_email_key":
+			return repositories.ErrEmailTaken
+		case "users_username_key":
+			return repositories.ErrUsernameTaken
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) getUser(ctx context.Context, where string, args ...any) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := scanUser(s.pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE "+where, args...))
+	if err != nil {
+		return nil, findError("user", err)
+	}
+	return user, nil
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.getUser(ctx, "username = $1", username)
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
+	return s.getUser(ctx, "id = $1", id)
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.getUser(ctx, "email = $1", email)
+}
+
+func (s *Store) GetUserByWebAuthnHandle(ctx context.Context, handle []byte) (*models.User, error) {
+	if len(handle) == 0 {
+		return nil, findError("user", pgx.ErrNoRows)
+	}
+	return s.getUser(ctx, "webauthn_handle = $1", handle)
+}
+
+func (s *Store) GetUserByOIDCSubject(ctx context.Context, issuer string, subject string) (*models.User, error) {
+	return s.getUser(ctx, "oidc_issuer = $1 AND oidc_subject = $2", issuer, subject)
+}
+
+// updateUser runs an UPDATE of the user with the given SET clause and extra
+// WHERE condition, whose placeholders start at $2. It fails with
+// ErrUserNotUpdated when no row matched, like the MongoDB implementation's
+// conditional updates.
+func (s *Store) updateUser(ctx context.Context, userId string, set string, condition string, args ...any) error {
+	if _, err := primitive.ObjectIDFromHex(userId); err != nil {
+		return fmt.Errorf("invalid user id: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := "UPDATE users SET " + set + " WHERE id = $1"
+	if condition != "" {
+		query += " AND " + condition
+	}
+	tag, err := s.pool.Exec(ctx, query, append([]any{userId}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repositories.ErrUserNotUpdated
+	}
+	return nil
+}
+
+func (s *Store) SetUserStatus(ctx context.Context, userId string, status string) error {
+	return s.updateUser(ctx, userId, "status = $2, updated_at = $3", "", status, time.Now().Format(time.RFC3339))
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, userId string, passwordHash string) error {
+	return s.updateUser(ctx, userId, "password_hash = $2, updated_at = $3", "", passwordHash, time.Now().Format(time.RFC3339))
+}
+
+func (s *Store) SetUserAdmin(ctx context.Context, userId string, isAdmin bool) error {
+	return s.updateUser(ctx, userId, "is_admin = $2", "", isAdmin)
+}
+
+func (s *Store) SetPendingTOTPSecret(ctx context.Context, userId string, secret string) error {
+	return s.updateUser(ctx, userId, "totp_pending_secret = $2", "", secret)
+}
+
+func (s *Store) EnableTOTP(ctx context.Context, userId string, secret string, step int64, recoveryCodeHashes []string) error {
+	if recoveryCodeHashes == nil {
+		recoveryCodeHashes = []string{}
+	}
+	return s.updateUser(ctx, userId,
+		"totp_enabled = true, totp_secret = $2, totp_pending_secret = '', totp_last_step = $3, recovery_code_hashes = $4",
+		"totp_pending_secret = $2", secret, step, recoveryCodeHashes)
+}
+
+func (s *Store) DisableTOTP(ctx context.Context, userId string) error {
+	return s.updateUser(ctx, userId,
+		"totp_enabled = false, totp_secret = '', totp_pending_secret = '', totp_last_step = 0, recovery_code_hashes = '{}'", "")
+}
+
+func (s *Store) SetRecoveryCodes(ctx context.Context, userId string, recoveryCodeHashes []string) error {
+	if recoveryCodeHashes == nil {
+		recoveryCodeHashes = []string{}
+	}
+	return s.updateUser(ctx, userId, "recovery_code_hashes = $2", "totp_enabled", recoveryCodeHashes)
+}
+
+func (s *Store) AdvanceTOTPStep(ctx context.Context, userId string, step int64) error {
+	return s.updateUser(ctx, userId, "totp_last_step = $2", "totp_last_step < $2", step)
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	return s.updateUser(ctx, userId, "recovery_code_hashes = array_remove(recovery_code_hashes, $2)",
+		"$2 = ANY(recovery_code_hashes)", codeHash)
+}
+
+func (s *Store) SetWebAuthnHandle(ctx context.Context, userId string, handle []byte) error {
+	return s.updateUser(ctx, userId, "webauthn_handle = $2", "webauthn_handle IS NULL", handle)
+}
+
+func (s *Store) SetRequireWebAuthnForTransactions(ctx context.Context, userId string, required bool) error {
+	return s.updateUser(ctx, userId, "require_webauthn_for_transactions = $2", "", required)
+}
+
+func (s *Store) LinkOIDCIdentity(ctx context.Context, userId string, issuer string, subject string) error {
+	return s.updateUser(ctx, userId, "oidc_issuer = $2, oidc_subject = $3, status = $4",
+		"oidc_subject = ''", issuer, subject, models.UserStatusActive)
+}
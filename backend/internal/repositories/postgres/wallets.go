@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const walletColumns = "id, name, address, balance, kms_key_id, user_id, organisation_id, next_nonce"
+
+func scanWallet(row pgx.Row) (models.Wallet, error) {
+	var wallet models.Wallet
+	var nextNonce int64
+	err := row.Scan(&wallet.ID, &wallet.Name, &wallet.PublicKey, &wallet.Balance, &wallet.KMSKeyID,
+		&wallet.UserID, &wallet.OrganisationID, &nextNonce)
+	wallet.NextNonce = uint64(nextNonce)
+	return wallet, err
+}
+
+func (s *Store) SaveWallet(ctx context.Context, wallet *models.Wallet) (models.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	wallet.ID = newID()
+	_, err := s.pool.Exec(ctx, `INSERT INTO wallets (`+walletColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		wallet.ID, wallet.Name, wallet.PublicKey, wallet.Balance, wallet.KMSKeyID, wallet.UserID, wallet.OrganisationID,
+		int64(wallet.NextNonce))
+	if err != nil {
+		return models.Wallet{}, fmt.Errorf("failed to save wallet: %v", err)
+	}
+	return *wallet, nil
+}
+
+func (s *Store) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	wallet, err := scanWallet(s.pool.QueryRow(ctx,
+		"SELECT "+walletColumns+" FROM wallets WHERE address = $1 ORDER BY id LIMIT 1", address))
+	if err != nil {
+		return models.Wallet{}, findError("wallet", err)
+	}
+	return wallet, nil
+}
+
+func (s *Store) ListWallets(ctx context.Context, userId string, organisationIds []string) ([]models.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if organisationIds == nil {
+		organisationIds = []string{}
+	}
+	rows, err := s.pool.Query(ctx, "SELECT "+walletColumns+` FROM wallets
+		WHERE (user_id = $1 AND organisation_id = '') OR organisation_id = ANY($2)
+		ORDER BY id`, userId, organisationIds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %v", err)
+	}
+	wallets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Wallet, error) {
+		return scanWallet(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %v", err)
+	}
+	return wallets, nil
+}
+
+func (s *Store) CountWallets(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var count int64
+	if err := s.pool.QueryRow(ctx, "SELECT count(*) FROM wallets").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count wallets: %v", err)
+	}
+	return count, nil
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -11,31 +12,125 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// Repository stores everything in MongoDB. It implements Store and the
+// UserStore, WalletStore and TransactionStore interfaces.
 type Repository struct {
 	dbClient *mongo.Client
+	database string
 }
 
-func NewRepository(dbClient *mongo.Client) *Repository {
-	return &Repository{dbClient: dbClient}
+func NewRepository(dbClient *mongo.Client, database string) *Repository {
+	return &Repository{dbClient: dbClient, database: database}
+}
+
+func (r *Repository) collection(name string) *mongo.Collection {
+	return r.dbClient.Database(r.database).Collection(name)
+}
+
+// Ping checks that the MongoDB primary can be reached.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.dbClient.Ping(ctx, readpref.Primary())
+}
+
+// findError wraps a failed lookup, reporting missing documents as ErrNotFound.
+func findError(what string, err error) error {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("failed to find %s: %w", what, ErrNotFound)
+	}
+	return fmt.Errorf("failed to find %s: %v", what, err)
 }
 
 func (r *Repository) SaveTransaction(ctx context.Context, newTransaction *models.TransactionResult) (models.TransactionResult, error) {
-	collection := r.dbClient.Database("walletdb").Collection("transactions")
+	collection := r.collection("transactions")
 	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	if newTransaction.CreatedAt.IsZero() {
+		newTransaction.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	}
 	result, err := collection.InsertOne(insertCtx, newTransaction)
 	if err != nil {
 		return *newTransaction, fmt.Errorf("failed to insert transaction into database: %v", err)
 	}
 	newTransaction.ID = result.InsertedID.(primitive.ObjectID).Hex()
+
+	// User operations are sent by the bundler with the smart account's nonce.
+	// MongoDB has no transactions without a replica set, so a failure here
+	// leaves the record saved and the error tells the caller.
+	if newTransaction.UserOpHash == "" && newTransaction.TransactionHash != "" {
+		_, err = r.collection("wallets").UpdateMany(insertCtx, bson.M{"publickey": newTransaction.From},
+			bson.M{"$max": bson.M{"nextnonce": newTransaction.Nonce + 1}})
+		if err != nil {
+			return *newTransaction, fmt.Errorf("failed to advance the wallet nonce: %v", err)
+		}
+	}
 	return *newTransaction, nil
 }
 
+// GetTransactionByUserOpHash returns the transaction record of a user operation
+// sent by the smart account sender.
+func (r *Repository) GetTransactionByUserOpHash(ctx context.Context, userOpHash string, sender string) (models.TransactionResult, error) {
+	collection := r.collection("transactions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var transaction models.TransactionResult
+	err := collection.FindOne(ctx, bson.M{"userophash": userOpHash, "from": sender}).Decode(&transaction)
+	if err != nil {
+		return transaction, findError("transaction", err)
+	}
+	return transaction, nil
+}
+
+// UpdateTransactionReceipt stores the on-chain outcome of a pending transaction.
+func (r *Repository) UpdateTransactionReceipt(ctx context.Context, transaction *models.TransactionResult) error {
+	collection := r.collection("transactions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(transaction.ID)
+	if err != nil {
+		return fmt.Errorf("invalid transaction id: %v", err)
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"transactionhash": transaction.TransactionHash,
+		"blocknumber":     transaction.BlockNumber,
+		"gasused":         transaction.GasUsed,
+		"status":          transaction.Status,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to update transaction: %v", err)
+	}
+	return nil
+}
+
+// FindTransactions returns the transactions sent from an address that called
+// the given method signature.
+func (r *Repository) FindTransactions(ctx context.Context, from string, method string) ([]models.TransactionResult, error) {
+	collection := r.collection("transactions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"from": from, "method": method})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var transactions []models.TransactionResult
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
 func (r *Repository) SaveWallet(ctx context.Context, newWallet *models.Wallet) (models.Wallet, error) {
-	collection := r.dbClient.Database("walletdb").Collection("wallets")
+	collection := r.collection("wallets")
 	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -47,25 +142,30 @@ func (r *Repository) SaveWallet(ctx context.Context, newWallet *models.Wallet) (
 	return *newWallet, nil
 }
 
-func (r *Repository) GetWallet(ctx context.Context, address string, userId string) (models.Wallet, error) {
-	collection := r.dbClient.Database("walletdb").Collection("wallets")
+func (r *Repository) GetWallet(ctx context.Context, address string) (models.Wallet, error) {
+	collection := r.collection("wallets")
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var wallet models.Wallet
-	err := collection.FindOne(ctx, bson.M{"publickey": address, "userid": userId}).Decode(&wallet)
+	err := collection.FindOne(ctx, bson.M{"publickey": address}).Decode(&wallet)
 	if err != nil {
-		return wallet, fmt.Errorf("failed to find wallet: %v", err)
+		return wallet, findError("wallet", err)
 	}
 	return wallet, nil
 }
 
-func (r *Repository) ListWallets(ctx context.Context, userId string) ([]models.Wallet, error) {
-	collection := r.dbClient.Database("walletdb").Collection("wallets")
+// ListWallets returns the user's personal wallets and the wallets of the
+// given organisations.
+func (r *Repository) ListWallets(ctx context.Context, userId string, organisationIds []string) ([]models.Wallet, error) {
+	collection := r.collection("wallets")
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	cursor, err := collection.Find(ctx, bson.M{"userid": userId})
+	cursor, err := collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"userid": userId, "organisationid": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"organisationid": bson.M{"$in": organisationIds}},
+	}})
 	if err != nil {
 		return nil, err
 	}
@@ -79,18 +179,26 @@ func (r *Repository) ListWallets(ctx context.Context, userId string) ([]models.W
 	return wallets, nil
 }
 
-func (r *Repository) CreateUser(user *models.User) error {
-	collection := r.dbClient.Database("walletdb").Collection("users")
-	insertCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// CountWallets returns the number of wallets of all users and organisations.
+func (r *Repository) CountWallets(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.collection("wallets").EstimatedDocumentCount(ctx)
+}
+
+func (r *Repository) CreateUser(ctx context.Context, user *models.User) error {
+	collection := r.collection("users")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	_, err := collection.InsertOne(insertCtx, user)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			if strings.Contains(err.Error(), "email") {
-				return fmt.Errorf("email already exists")
+				return ErrEmailTaken
 			} else if strings.Contains(err.Error(), "username") {
-				return fmt.Errorf("username already exists")
+				return ErrUsernameTaken
 			}
 			return fmt.Errorf("duplicate key error")
 		}
@@ -99,15 +207,46 @@ func (r *Repository) CreateUser(user *models.User) error {
 	return nil
 }
 
-func (r *Repository) GetUserByUsername(username string) (*models.User, error) {
-	collection := r.dbClient.Database("walletdb").Collection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *Repository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	collection := r.collection("users")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var user models.User
 	err := collection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find user: %v", err)
+		return nil, findError("user", err)
+	}
+	return &user, nil
+}
+
+func (r *Repository) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	collection := r.collection("users")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %v", err)
+	}
+
+	var user models.User
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	if err != nil {
+		return nil, findError("user", err)
+	}
+	return &user, nil
+}
+
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	collection := r.collection("users")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		return nil, findError("user", err)
 	}
 	return &user, nil
 }
@@ -0,0 +1,50 @@
+package repositories_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/db"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/repotest"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestRepository runs the store conformance suite against the MongoDB at
+// MONGO_TEST_URI, using a new database for every test.
+func TestRepository(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	count := 0
+	newRepository := func(t *testing.T) *repositories.Repository {
+		count++
+		database := fmt.Sprintf("walletdb_test_%d_%d", time.Now().UnixNano(), count)
+		if err := db.InitDatabase(client, database); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Database(database).Drop(context.Background()) })
+
+		return repositories.NewRepository(client, database)
+	}
+	repotest.Run(t, func(t *testing.T) repositories.Stores { return newRepository(t).Stores() })
+	repotest.RunStore(t, func(t *testing.T) repositories.Store { return newRepository(t) })
+}
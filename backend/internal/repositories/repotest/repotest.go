@@ -0,0 +1,433 @@
+// Package repotest is a conformance suite for the stores. Every
+// implementation runs it from its own tests, so they all behave like the
+// MongoDB repository the services were written against.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Run runs the whole suite. newStores must return empty stores on every call.
+func Run(t *testing.T, newStores func(t *testing.T) repositories.Stores) {
+	t.Run("Users", func(t *testing.T) {
+		RunUserStore(t, func(t *testing.T) repositories.UserStore { return newStores(t).Users })
+	})
+	t.Run("Wallets", func(t *testing.T) {
+		RunWalletStore(t, func(t *testing.T) repositories.WalletStore { return newStores(t).Wallets })
+	})
+	t.Run("Transactions", func(t *testing.T) {
+		RunTransactionStore(t, func(t *testing.T) repositories.TransactionStore { return newStores(t).Transactions })
+	})
+	t.Run("TransactionNonces", func(t *testing.T) {
+		runTransactionNonces(t, newStores(t))
+	})
+}
+
+// runTransactionNonces checks that recording a transaction sent from a wallet
+// advances the wallet's next nonce.
+func runTransactionNonces(t *testing.T, stores repositories.Stores) {
+	ctx := context.Background()
+
+	if _, err := stores.Wallets.SaveWallet(ctx, &models.Wallet{Name: "main", PublicKey: "0x01", Balance: "0", KMSKeyID: "key-1", UserID: "user-1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, transaction := range []models.TransactionResult{
+		{TransactionHash: "0xa1", From: "0x01", To: "0x02", Nonce: 4, Status: models.TransactionStatusSuccess, UserID: "user-1"},
+		// An older transaction recorded late does not move the nonce back
+		{TransactionHash: "0xa2", From: "0x01", To: "0x02", Nonce: 2, Status: models.TransactionStatusSuccess, UserID: "user-1"},
+		// User operations carry the smart account's nonce, not the wallet's
+		{UserOpHash: "0xop", From: "0x01", To: "0x02", Nonce: 9, Status: models.TransactionStatusPending, UserID: "user-1"},
+	} {
+		if _, err := stores.Transactions.SaveTransaction(ctx, &transaction); err != nil {
+			t.Fatalf("SaveTransaction: %v", err)
+		}
+	}
+
+	wallet, err := stores.Wallets.GetWallet(ctx, "0x01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.NextNonce != 5 {
+		t.Fatalf("wallet next nonce is %d, expected 5", wallet.NextNonce)
+	}
+}
+
+// RunFailedNonceUpdate checks that a transaction is not recorded when
+// advancing its wallet's nonce fails, for stores that write both atomically.
+// breakNonceUpdate must make every later update of the wallets fail.
+func RunFailedNonceUpdate(t *testing.T, stores repositories.Stores, breakNonceUpdate func(t *testing.T)) {
+	ctx := context.Background()
+
+	if _, err := stores.Wallets.SaveWallet(ctx, &models.Wallet{Name: "main", PublicKey: "0x01", Balance: "0", KMSKeyID: "key-1", UserID: "user-1"}); err != nil {
+		t.Fatal(err)
+	}
+	breakNonceUpdate(t)
+
+	_, err := stores.Transactions.SaveTransaction(ctx, &models.TransactionResult{
+		TransactionHash: "0xa1", From: "0x01", To: "0x02", Method: "transfer", Nonce: 4,
+		Status: models.TransactionStatusSuccess, UserID: "user-1",
+	})
+	expectError(t, err, nil, "SaveTransaction with a failing nonce update")
+
+	transactions, err := stores.Transactions.FindTransactions(ctx, "0x01", "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 0 {
+		t.Fatalf("the transaction was recorded without its nonce update: %+v", transactions)
+	}
+	wallet, err := stores.Wallets.GetWallet(ctx, "0x01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wallet.NextNonce != 0 {
+		t.Fatalf("wallet next nonce is %d, expected 0", wallet.NextNonce)
+	}
+}
+
+// createUser creates a user and returns it as stored, with its id.
+func createUser(t *testing.T, store repositories.UserStore, username string) *models.User {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Now().Format(time.RFC3339)
+	err := store.CreateUser(ctx, &models.User{
+		Username:     username,
+		Email:        username + "@example.com",
+		PasswordHash: "hash-" + username,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Status:       models.UserStatusPendingVerification,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", username, err)
+	}
+	user, err := store.GetUserByUsername(ctx, username)
+	if err != nil {
+		t.Fatalf("GetUserByUsername(%s): %v", username, err)
+	}
+	if user.ID == "" {
+		t.Fatalf("stored user %s has no id", username)
+	}
+	return user
+}
+
+func getUser(t *testing.T, store repositories.UserStore, id string) *models.User {
+	t.Helper()
+
+	user, err := store.GetUserByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetUserByID(%s): %v", id, err)
+	}
+	return user
+}
+
+func expectError(t *testing.T, err error, target error, action string) {
+	t.Helper()
+
+	if err == nil {
+		t.Fatalf("%s: expected an error", action)
+	}
+	if target != nil && !errors.Is(err, target) {
+		t.Fatalf("%s: expected %v, got %v", action, target, err)
+	}
+}
+
+func RunUserStore(t *testing.T, newStore func(t *testing.T) repositories.UserStore) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "alice")
+
+		if user.Email != "alice@example.com" || user.PasswordHash != "hash-alice" || user.Status != models.UserStatusPendingVerification {
+			t.Fatalf("stored user does not match: %+v", user)
+		}
+		byID := getUser(t, store, user.ID)
+		if byID.Username != "alice" {
+			t.Fatalf("GetUserByID returned %s", byID.Username)
+		}
+		byEmail, err := store.GetUserByEmail(ctx, "alice@example.com")
+		if err != nil || byEmail.ID != user.ID {
+			t.Fatalf("GetUserByEmail: %v", err)
+		}
+	})
+
+	t.Run("UniqueUsernameAndEmail", func(t *testing.T) {
+		store := newStore(t)
+		createUser(t, store, "alice")
+
+		err := store.CreateUser(ctx, &models.User{Username: "alice", Email: "other@example.com"})
+		expectError(t, err, repositories.ErrUsernameTaken, "CreateUser with a taken username")
+		err = store.CreateUser(ctx, &models.User{Username: "other", Email: "alice@example.com"})
+		expectError(t, err, repositories.ErrEmailTaken, "CreateUser with a taken email")
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetUserByUsername(ctx, "nobody")
+		expectError(t, err, repositories.ErrNotFound, "GetUserByUsername")
+		_, err = store.GetUserByEmail(ctx, "nobody@example.com")
+		expectError(t, err, repositories.ErrNotFound, "GetUserByEmail")
+		_, err = store.GetUserByID(ctx, primitive.NewObjectID().Hex())
+		expectError(t, err, repositories.ErrNotFound, "GetUserByID")
+		_, err = store.GetUserByID(ctx, "not-an-id")
+		expectError(t, err, nil, "GetUserByID with an invalid id")
+
+		err = store.SetUserStatus(ctx, primitive.NewObjectID().Hex(), models.UserStatusActive)
+		expectError(t, err, repositories.ErrUserNotUpdated, "SetUserStatus of a missing user")
+	})
+
+	t.Run("AccountUpdates", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "alice")
+
+		if err := store.SetUserStatus(ctx, user.ID, models.UserStatusActive); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.UpdatePassword(ctx, user.ID, "new-hash"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetUserAdmin(ctx, user.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.SetRequireWebAuthnForTransactions(ctx, user.ID, true); err != nil {
+			t.Fatal(err)
+		}
+
+		updated := getUser(t, store, user.ID)
+		if updated.Status != models.UserStatusActive || updated.PasswordHash != "new-hash" || !updated.IsAdmin || !updated.RequireWebAuthnForTransactions {
+			t.Fatalf("updates not stored: %+v", updated)
+		}
+	})
+
+	t.Run("TOTP", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "alice")
+
+		if err := store.SetPendingTOTPSecret(ctx, user.ID, "secret"); err != nil {
+			t.Fatal(err)
+		}
+		err := store.EnableTOTP(ctx, user.ID, "other-secret", 10, []string{"a", "b"})
+		expectError(t, err, repositories.ErrUserNotUpdated, "EnableTOTP with a secret that is not pending")
+		if err := store.EnableTOTP(ctx, user.ID, "secret", 10, []string{"a", "b"}); err != nil {
+			t.Fatal(err)
+		}
+
+		enabled := getUser(t, store, user.ID)
+		if !enabled.TOTPEnabled || enabled.TOTPSecret != "secret" || enabled.TOTPPendingSecret != "" || enabled.TOTPLastStep != 10 || len(enabled.RecoveryCodeHashes) != 2 {
+			t.Fatalf("TOTP not enabled: %+v", enabled)
+		}
+
+		expectError(t, store.AdvanceTOTPStep(ctx, user.ID, 10), repositories.ErrUserNotUpdated, "AdvanceTOTPStep to a used step")
+		if err := store.AdvanceTOTPStep(ctx, user.ID, 11); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.UseRecoveryCode(ctx, user.ID, "a"); err != nil {
+			t.Fatal(err)
+		}
+		expectError(t, store.UseRecoveryCode(ctx, user.ID, "a"), repositories.ErrUserNotUpdated, "UseRecoveryCode twice")
+		if codes := getUser(t, store, user.ID).RecoveryCodeHashes; len(codes) != 1 || codes[0] != "b" {
+			t.Fatalf("recovery codes after use: %v", codes)
+		}
+		if err := store.SetRecoveryCodes(ctx, user.ID, []string{"c"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.DisableTOTP(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		disabled := getUser(t, store, user.ID)
+		if disabled.TOTPEnabled || disabled.TOTPSecret != "" || disabled.TOTPLastStep != 0 || len(disabled.RecoveryCodeHashes) != 0 {
+			t.Fatalf("TOTP not disabled: %+v", disabled)
+		}
+		expectError(t, store.SetRecoveryCodes(ctx, user.ID, []string{"d"}), repositories.ErrUserNotUpdated, "SetRecoveryCodes without TOTP")
+	})
+
+	t.Run("WebAuthnHandle", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "alice")
+
+		if err := store.SetWebAuthnHandle(ctx, user.ID, []byte("handle")); err != nil {
+			t.Fatal(err)
+		}
+		err := store.SetWebAuthnHandle(ctx, user.ID, []byte("other"))
+		expectError(t, err, repositories.ErrUserNotUpdated, "SetWebAuthnHandle twice")
+
+		found, err := store.GetUserByWebAuthnHandle(ctx, []byte("handle"))
+		if err != nil || found.ID != user.ID {
+			t.Fatalf("GetUserByWebAuthnHandle: %v", err)
+		}
+		_, err = store.GetUserByWebAuthnHandle(ctx, []byte("other"))
+		expectError(t, err, repositories.ErrNotFound, "GetUserByWebAuthnHandle of an unknown handle")
+	})
+
+	t.Run("OIDCIdentity", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "alice")
+
+		if err := store.LinkOIDCIdentity(ctx, user.ID, "https://idp.example.com", "subject"); err != nil {
+			t.Fatal(err)
+		}
+		err := store.LinkOIDCIdentity(ctx, user.ID, "https://idp.example.com", "other")
+		expectError(t, err, repositories.ErrUserNotUpdated, "LinkOIDCIdentity twice")
+
+		found, err := store.GetUserByOIDCSubject(ctx, "https://idp.example.com", "subject")
+		if err != nil || found.ID != user.ID {
+			t.Fatalf("GetUserByOIDCSubject: %v", err)
+		}
+		if found.Status != models.UserStatusActive {
+			t.Fatalf("linked user status is %q", found.Status)
+		}
+		_, err = store.GetUserByOIDCSubject(ctx, "https://other.example.com", "subject")
+		expectError(t, err, repositories.ErrNotFound, "GetUserByOIDCSubject with another issuer")
+	})
+}
+
+func RunWalletStore(t *testing.T, newStore func(t *testing.T) repositories.WalletStore) {
+	ctx := context.Background()
+
+	save := func(t *testing.T, store repositories.WalletStore, wallet models.Wallet) models.Wallet {
+		t.Helper()
+		saved, err := store.SaveWallet(ctx, &wallet)
+		if err != nil {
+			t.Fatalf("SaveWallet: %v", err)
+		}
+		if saved.ID == "" {
+			t.Fatal("saved wallet has no id")
+		}
+		return saved
+	}
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		store := newStore(t)
+		saved := save(t, store, models.Wallet{Name: "main", PublicKey: "0x01", Balance: "0", KMSKeyID: "key-1", UserID: "user-1"})
+
+		wallet, err := store.GetWallet(ctx, "0x01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wallet != saved {
+			t.Fatalf("GetWallet returned %+v, saved %+v", wallet, saved)
+		}
+		_, err = store.GetWallet(ctx, "0x02")
+		expectError(t, err, repositories.ErrNotFound, "GetWallet of an unknown address")
+	})
+
+	t.Run("ListWallets", func(t *testing.T) {
+		store := newStore(t)
+		save(t, store, models.Wallet{Name: "personal", PublicKey: "0x01", UserID: "user-1"})
+		save(t, store, models.Wallet{Name: "other user", PublicKey: "0x02", UserID: "user-2"})
+		save(t, store, models.Wallet{Name: "organisation", PublicKey: "0x03", UserID: "user-2", OrganisationID: "org-1"})
+		save(t, store, models.Wallet{Name: "other organisation", PublicKey: "0x04", UserID: "user-1", OrganisationID: "org-2"})
+
+		wallets, err := store.ListWallets(ctx, "user-1", []string{"org-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := map[string]bool{}
+		for _, wallet := range wallets {
+			names[wallet.Name] = true
+		}
+		if len(wallets) != 2 || !names["personal"] || !names["organisation"] {
+			t.Fatalf("ListWallets returned %v", names)
+		}
+
+		wallets, err = store.ListWallets(ctx, "user-3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(wallets) != 0 {
+			t.Fatalf("ListWallets of a user without wallets returned %d", len(wallets))
+		}
+	})
+
+	t.Run("CountWallets", func(t *testing.T) {
+		store := newStore(t)
+		save(t, store, models.Wallet{Name: "personal", PublicKey: "0x01", UserID: "user-1"})
+		save(t, store, models.Wallet{Name: "organisation", PublicKey: "0x02", UserID: "user-2", OrganisationID: "org-1"})
+
+		count, err := store.CountWallets(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Fatalf("CountWallets returned %d, want 2", count)
+		}
+	})
+}
+
+func RunTransactionStore(t *testing.T, newStore func(t *testing.T) repositories.TransactionStore) {
+	ctx := context.Background()
+
+	save := func(t *testing.T, store repositories.TransactionStore, transaction models.TransactionResult) models.TransactionResult {
+		t.Helper()
+		saved, err := store.SaveTransaction(ctx, &transaction)
+		if err != nil {
+			t.Fatalf("SaveTransaction: %v", err)
+		}
+		if saved.ID == "" {
+			t.Fatal("saved transaction has no id")
+		}
+		if saved.CreatedAt.IsZero() {
+			t.Fatal("saved transaction has no creation time")
+		}
+		return saved
+	}
+
+	t.Run("FindTransactions", func(t *testing.T) {
+		store := newStore(t)
+		save(t, store, models.TransactionResult{TransactionHash: "0xa1", From: "0x01", To: "0x02", Value: "1", Status: models.TransactionStatusSuccess, UserID: "user-1"})
+		save(t, store, models.TransactionResult{TransactionHash: "0xa2", From: "0x01", To: "0x03", Method: "approve", Status: models.TransactionStatusSuccess, UserID: "user-1"})
+		save(t, store, models.TransactionResult{TransactionHash: "0xa3", From: "0x09", To: "0x03", Method: "approve", Status: models.TransactionStatusSuccess, UserID: "user-2"})
+
+		transactions, err := store.FindTransactions(ctx, "0x01", "approve")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transactions) != 1 || transactions[0].TransactionHash != "0xa2" {
+			t.Fatalf("FindTransactions returned %+v", transactions)
+		}
+		transactions, err = store.FindTransactions(ctx, "0x01", "transfer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transactions) != 0 {
+			t.Fatalf("FindTransactions of an unused method returned %d", len(transactions))
+		}
+	})
+
+	t.Run("UserOperationReceipt", func(t *testing.T) {
+		store := newStore(t)
+		saved := save(t, store, models.TransactionResult{From: "0x01", To: "0x02", UserOpHash: "0xop", Status: models.TransactionStatusPending, UserID: "user-1"})
+
+		_, err := store.GetTransactionByUserOpHash(ctx, "0xop", "0x02")
+		expectError(t, err, repositories.ErrNotFound, "GetTransactionByUserOpHash of another sender")
+
+		saved.TransactionHash = "0xa1"
+		saved.BlockNumber = 12
+		saved.GasUsed = 21000
+		saved.Status = models.TransactionStatusSuccess
+		if err := store.UpdateTransactionReceipt(ctx, &saved); err != nil {
+			t.Fatal(err)
+		}
+
+		transaction, err := store.GetTransactionByUserOpHash(ctx, "0xop", "0x01")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transaction != saved {
+			t.Fatalf("GetTransactionByUserOpHash returned %+v, expected %+v", transaction, saved)
+		}
+	})
+}
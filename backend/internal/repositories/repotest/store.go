@@ -0,0 +1,287 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+)
+
+// RunStore checks the conditional updates the services rely on for
+// everything besides users, wallets and transactions. newStore must return an
+// empty store on every call.
+func RunStore(t *testing.T, newStore func(t *testing.T) repositories.Store) {
+	ctx := context.Background()
+	// Stored times have millisecond precision
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	t.Run("Sessions", func(t *testing.T) {
+		store := newStore(t)
+		session, err := store.CreateSession(ctx, &models.Session{UserID: "user-1", RefreshTokenHash: "hash-1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.RotateSessionToken(ctx, session.ID, "hash-1", "hash-2", now.Add(2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		err = store.RotateSessionToken(ctx, session.ID, "hash-1", "hash-3", now.Add(2*time.Hour))
+		expectError(t, err, nil, "RotateSessionToken with a rotated token")
+
+		// The previous token still finds the session, to detect its reuse
+		found, err := store.GetSessionByRefreshToken(ctx, "hash-1")
+		if err != nil || found.ID != session.ID || found.RefreshTokenHash != "hash-2" {
+			t.Fatalf("GetSessionByRefreshToken returned %+v, %v", found, err)
+		}
+
+		if err := store.RevokeUserSessions(ctx, "user-1"); err != nil {
+			t.Fatal(err)
+		}
+		err = store.RotateSessionToken(ctx, session.ID, "hash-2", "hash-3", now.Add(2*time.Hour))
+		expectError(t, err, nil, "RotateSessionToken of a revoked session")
+	})
+
+	t.Run("UserTokens", func(t *testing.T) {
+		store := newStore(t)
+		save := func(hash string, expiresAt time.Time) {
+			t.Helper()
+			_, err := store.SaveUserToken(ctx, &models.UserToken{UserID: "user-1", Purpose: models.TokenPurposePasswordReset, TokenHash: hash, CreatedAt: now, ExpiresAt: expiresAt})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		save("old", now.Add(time.Hour))
+		save("new", now.Add(time.Hour))
+		_, err := store.ConsumeUserToken(ctx, "old", models.TokenPurposePasswordReset)
+		expectError(t, err, nil, "ConsumeUserToken of a replaced token")
+		_, err = store.ConsumeUserToken(ctx, "new", models.TokenPurposeEmailVerification)
+		expectError(t, err, nil, "ConsumeUserToken for another purpose")
+
+		token, err := store.ConsumeUserToken(ctx, "new", models.TokenPurposePasswordReset)
+		if err != nil || token.UserID != "user-1" {
+			t.Fatalf("ConsumeUserToken returned %+v, %v", token, err)
+		}
+		_, err = store.ConsumeUserToken(ctx, "new", models.TokenPurposePasswordReset)
+		expectError(t, err, nil, "ConsumeUserToken twice")
+
+		save("expired", now.Add(-time.Minute))
+		_, err = store.ConsumeUserToken(ctx, "expired", models.TokenPurposePasswordReset)
+		expectError(t, err, nil, "ConsumeUserToken of an expired token")
+	})
+
+	t.Run("LoginAttempts", func(t *testing.T) {
+		store := newStore(t)
+		for i := 1; i <= 3; i++ {
+			attempt, err := store.RecordLoginFailure(ctx, "user:alice", time.Hour)
+			if err != nil || attempt.Failures != i {
+				t.Fatalf("RecordLoginFailure %d returned %+v, %v", i, attempt, err)
+			}
+		}
+		// Failures outside the window start the count again
+		attempt, err := store.RecordLoginFailure(ctx, "user:alice", 0)
+		if err != nil || attempt.Failures != 1 {
+			t.Fatalf("RecordLoginFailure after the window returned %+v, %v", attempt, err)
+		}
+
+		if err := store.LockLogin(ctx, "user:alice", now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		locked, err := store.ListLockedLogins(ctx)
+		if err != nil || len(locked) != 1 || locked[0].Key != "user:alice" {
+			t.Fatalf("ListLockedLogins returned %+v, %v", locked, err)
+		}
+
+		if err := store.ClearLoginAttempts(ctx, "user:alice"); err != nil {
+			t.Fatal(err)
+		}
+		attempts, err := store.GetLoginAttempts(ctx, []string{"user:alice", "ip:127.0.0.1"})
+		if err != nil || len(attempts) != 0 {
+			t.Fatalf("GetLoginAttempts after clearing returned %+v, %v", attempts, err)
+		}
+	})
+
+	t.Run("WebAuthnChallenges", func(t *testing.T) {
+		store := newStore(t)
+		challenge, err := store.SaveWebAuthnChallenge(ctx, &models.WebAuthnChallenge{UserID: "user-1", Purpose: models.WebAuthnPurposeSignature, Digest: "0x01", ExpiresAt: now.Add(time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = store.TakeWebAuthnChallenge(ctx, challenge.ID, models.WebAuthnPurposeLogin)
+		expectError(t, err, nil, "TakeWebAuthnChallenge for another purpose")
+		peeked, err := store.GetWebAuthnChallenge(ctx, challenge.ID, models.WebAuthnPurposeSignature)
+		if err != nil || peeked.Digest != "0x01" {
+			t.Fatalf("GetWebAuthnChallenge returned %+v, %v", peeked, err)
+		}
+		if _, err := store.TakeWebAuthnChallenge(ctx, challenge.ID, models.WebAuthnPurposeSignature); err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.TakeWebAuthnChallenge(ctx, challenge.ID, models.WebAuthnPurposeSignature)
+		expectError(t, err, nil, "TakeWebAuthnChallenge twice")
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		store := newStore(t)
+		head, err := store.GetLastAuditEntry(ctx)
+		if err != nil || head.Sequence != 0 {
+			t.Fatalf("GetLastAuditEntry of an empty log returned %+v, %v", head, err)
+		}
+
+		for i, action := range []string{models.AuditLogin, models.AuditLogout, models.AuditLogin} {
+			entry := models.AuditEntry{Sequence: int64(i + 1), Action: action, UserID: "user-1", CreatedAt: now, Hash: action}
+			if err := store.InsertAuditEntry(ctx, &entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = store.InsertAuditEntry(ctx, &models.AuditEntry{Sequence: 2, Action: models.AuditLogin, CreatedAt: now})
+		expectError(t, err, repositories.ErrAuditSequenceTaken, "InsertAuditEntry with a taken sequence")
+
+		head, err = store.GetLastAuditEntry(ctx)
+		if err != nil || head.Sequence != 3 {
+			t.Fatalf("GetLastAuditEntry returned %+v, %v", head, err)
+		}
+		entries, err := store.ListAuditEntries(ctx, models.AuditQuery{Action: models.AuditLogin, Limit: 1})
+		if err != nil || len(entries) != 1 || entries[0].Sequence != 3 {
+			t.Fatalf("ListAuditEntries returned %+v, %v", entries, err)
+		}
+
+		var sequences []int64
+		err = store.WalkAuditEntries(ctx, func(entry models.AuditEntry) error {
+			sequences = append(sequences, entry.Sequence)
+			return nil
+		})
+		if err != nil || len(sequences) != 3 || sequences[0] != 1 || sequences[2] != 3 {
+			t.Fatalf("WalkAuditEntries visited %v, %v", sequences, err)
+		}
+	})
+
+	t.Run("OrganisationMembers", func(t *testing.T) {
+		store := newStore(t)
+		organisation, err := store.SaveOrganisation(ctx, &models.Organisation{Name: "Acme", CreatedBy: "user-1", CreatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+		member := models.OrganisationMember{OrganisationID: organisation.ID, UserID: "user-1", Role: models.RoleOwner, CreatedAt: now}
+		if _, err := store.SaveOrganisationMember(ctx, &member); err != nil {
+			t.Fatal(err)
+		}
+		duplicate := member
+		duplicate.ID = ""
+		_, err = store.SaveOrganisationMember(ctx, &duplicate)
+		expectError(t, err, nil, "SaveOrganisationMember twice")
+
+		if err := store.UpdateOrganisationMemberRole(ctx, organisation.ID, "user-1", models.RoleViewer); err != nil {
+			t.Fatal(err)
+		}
+		memberships, err := store.ListMemberships(ctx, "user-1")
+		if err != nil || len(memberships) != 1 || memberships[0].Role != models.RoleViewer {
+			t.Fatalf("ListMemberships returned %+v, %v", memberships, err)
+		}
+
+		if err := store.DeleteOrganisationMember(ctx, organisation.ID, "user-1"); err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.GetOrganisationMember(ctx, organisation.ID, "user-1")
+		expectError(t, err, nil, "GetOrganisationMember after deleting it")
+	})
+
+	t.Run("SafeTransactions", func(t *testing.T) {
+		store := newStore(t)
+		if _, err := store.SaveSafe(ctx, &models.SafeWallet{Address: "0xsafe", Owners: []string{"0x01", "0x02"}, Threshold: 2, CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+		safes, err := store.ListSafes(ctx, []string{"0x02", "0x03"})
+		if err != nil || len(safes) != 1 {
+			t.Fatalf("ListSafes returned %+v, %v", safes, err)
+		}
+
+		transaction, err := store.SaveSafeTransaction(ctx, &models.SafeTransaction{SafeAddress: "0xsafe", Status: models.SafeTransactionProposed, Signatures: []models.SafeSignature{}, CreatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, owner := range []string{"0x01", "0x01", "0x02"} {
+			if err := store.AddSafeSignature(ctx, transaction.ID, models.SafeSignature{Owner: owner}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_, err = store.GetSafeTransaction(ctx, transaction.ID, "0xother")
+		expectError(t, err, nil, "GetSafeTransaction of another Safe")
+
+		if err := store.ClaimSafeTransaction(ctx, transaction.ID); err != nil {
+			t.Fatal(err)
+		}
+		expectError(t, store.ClaimSafeTransaction(ctx, transaction.ID), nil, "ClaimSafeTransaction twice")
+		if err := store.SetSafeExecutionTxHash(ctx, transaction.ID, "0xtx"); err != nil {
+			t.Fatal(err)
+		}
+		expectError(t, store.ReleaseSafeTransaction(ctx, transaction.ID), nil, "ReleaseSafeTransaction of a signed execution")
+		if err := store.MarkSafeTransactionExecuted(ctx, transaction.ID, models.SafeTransactionExecuted, "0xtx"); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := store.GetSafeTransaction(ctx, transaction.ID, "0xsafe")
+		if err != nil || len(stored.Signatures) != 2 || stored.Status != models.SafeTransactionExecuted || stored.ExecutionTxHash != "0xtx" {
+			t.Fatalf("GetSafeTransaction returned %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("SmartAccounts", func(t *testing.T) {
+		store := newStore(t)
+		if _, err := store.SaveSmartAccount(ctx, &models.SmartAccount{Address: "0xaccount", OwnerAddress: "0x01", CreatedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetSmartAccount(ctx, "0xaccount"); err != nil {
+			t.Fatal(err)
+		}
+		accounts, err := store.ListSmartAccounts(ctx, []string{"0x02"})
+		if err != nil || len(accounts) != 0 {
+			t.Fatalf("ListSmartAccounts of another owner returned %+v, %v", accounts, err)
+		}
+	})
+
+	t.Run("Schedules", func(t *testing.T) {
+		store := newStore(t)
+		schedule, err := store.SaveSchedule(ctx, &models.Schedule{FromAddress: "0x01", Status: models.ScheduleStatusActive, NextRunAt: now.Add(-time.Minute), UserID: "user-1", CreatedAt: now, UpdatedAt: now})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claimed, err := store.ClaimDueSchedule(ctx, now, time.Minute)
+		if err != nil || claimed == nil || claimed.ID != schedule.ID {
+			t.Fatalf("ClaimDueSchedule returned %+v, %v", claimed, err)
+		}
+		claimed, err = store.ClaimDueSchedule(ctx, now, time.Minute)
+		if err != nil || claimed != nil {
+			t.Fatalf("ClaimDueSchedule of a leased schedule returned %+v, %v", claimed, err)
+		}
+
+		if err := store.SetPendingRun(ctx, schedule.ID, models.PendingRun{TransactionHash: "0xtx", StartedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+		paused, err := store.PauseUserSchedules(ctx, "user-1")
+		if err != nil || paused != 1 {
+			t.Fatalf("PauseUserSchedules returned %d, %v", paused, err)
+		}
+
+		// A pause issued while the run was in flight is kept
+		if err := store.FinishScheduleRun(ctx, schedule.ID, now, models.ScheduleStatusCompleted, now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		stored, err := store.GetSchedule(ctx, schedule.ID)
+		if err != nil || stored.Status != models.ScheduleStatusPaused || stored.PendingRun != nil {
+			t.Fatalf("GetSchedule returned %+v, %v", stored, err)
+		}
+
+		schedules, err := store.ListSchedules(ctx, []string{"0x01"})
+		if err != nil || len(schedules) != 1 {
+			t.Fatalf("ListSchedules returned %+v, %v", schedules, err)
+		}
+		if err := store.DeleteSchedule(ctx, schedule.ID); err != nil {
+			t.Fatal(err)
+		}
+		expectError(t, store.DeleteSchedule(ctx, schedule.ID), nil, "DeleteSchedule twice")
+	})
+}
@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *Repository) SaveSafe(ctx context.Context, safe *models.SafeWallet) (models.SafeWallet, error) {
+	collection := r.collection("safes")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, safe)
+	if err != nil {
+		return *safe, fmt.Errorf("failed to insert safe into database: %v", err)
+	}
+	safe.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *safe, nil
+}
+
+func (r *Repository) GetSafe(ctx context.Context, address string) (models.SafeWallet, error) {
+	collection := r.collection("safes")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var safe models.SafeWallet
+	err := collection.FindOne(ctx, bson.M{"address": address}).Decode(&safe)
+	if err != nil {
+		return safe, fmt.Errorf("failed to find safe: %v", err)
+	}
+	return safe, nil
+}
+
+// ListSafes returns the Safes owned by any of the given wallet addresses.
+func (r *Repository) ListSafes(ctx context.Context, owners []string) ([]models.SafeWallet, error) {
+	collection := r.collection("safes")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"owners": bson.M{"$in": owners}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var safes []models.SafeWallet
+	if err := cursor.All(ctx, &safes); err != nil {
+		return nil, err
+	}
+
+	return safes, nil
+}
+
+func (r *Repository) SaveSafeTransaction(ctx context.Context, transaction *models.SafeTransaction) (models.SafeTransaction, error) {
+	collection := r.collection("safe_transactions")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, transaction)
+	if err != nil {
+		return *transaction, fmt.Errorf("failed to insert safe transaction into database: %v", err)
+	}
+	transaction.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *transaction, nil
+}
+
+func (r *Repository) GetSafeTransaction(ctx context.Context, id string, safeAddress string) (models.SafeTransaction, error) {
+	collection := r.collection("safe_transactions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var transaction models.SafeTransaction
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return transaction, fmt.Errorf("invalid safe transaction id: %v", err)
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": objectID, "safeaddress": safeAddress}).Decode(&transaction)
+	if err != nil {
+		return transaction, fmt.Errorf("failed to find safe transaction: %v", err)
+	}
+	return transaction, nil
+}
+
+func (r *Repository) ListSafeTransactions(ctx context.Context, safeAddress string) ([]models.SafeTransaction, error) {
+	collection := r.collection("safe_transactions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"safeaddress": safeAddress}, options.Find().SetSort(bson.M{"createdat": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var transactions []models.SafeTransaction
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// AddSafeSignature appends an owner signature to a proposed Safe transaction,
+// ignoring owners that already signed.
+func (r *Repository) AddSafeSignature(ctx context.Context, id string, signature models.SafeSignature) error {
+	collection := r.collection("safe_transactions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid safe transaction id: %v", err)
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{
+		"_id":              objectID,
+		"status":           models.SafeTransactionProposed,
+		"signatures.owner": bson.M{"$ne": signature.Owner},
+	}, bson.M{"$push": bson.M{"signatures": signature}})
+	if err != nil {
+		return fmt.Errorf("failed to add signature: %v", err)
+	}
+	return nil
+}
+
+// ClaimSafeTransaction moves a proposed Safe transaction to the executing
+// state, so that only one caller submits it. It fails if the transaction is
+// no longer proposed.
+func (r *Repository) ClaimSafeTransaction(ctx context.Context, id string) error {
+	return r.updateSafeTransaction(ctx, id, bson.M{"status": models.SafeTransactionProposed}, bson.M{
+		"status": models.SafeTransactionExecuting,
+	})
+}
+
+// SetSafeExecutionTxHash records the transaction submitting a claimed Safe
+// transaction before it is broadcast.
+func (r *Repository) SetSafeExecutionTxHash(ctx context.Context, id string, executionTxHash string) error {
+	return r.updateSafeTransaction(ctx, id, bson.M{"status": models.SafeTransactionExecuting}, bson.M{
+		"executiontxhash": executionTxHash,
+	})
+}
+
+// ReleaseSafeTransaction returns a claimed Safe transaction whose execution
+// was never signed to the proposed state.
+func (r *Repository) ReleaseSafeTransaction(ctx context.Context, id string) error {
+	return r.updateSafeTransaction(ctx, id, bson.M{
+		"status":          models.SafeTransactionExecuting,
+		"executiontxhash": bson.M{"$in": bson.A{nil, ""}},
+	}, bson.M{"status": models.SafeTransactionProposed})
+}
+
+// MarkSafeTransactionExecuted records the outcome of executing a claimed Safe
+// transaction.
+func (r *Repository) MarkSafeTransactionExecuted(ctx context.Context, id string, status string, executionTxHash string) error {
+	return r.updateSafeTransaction(ctx, id, bson.M{"status": models.SafeTransactionExecuting}, bson.M{
+		"status":          status,
+		"executiontxhash": executionTxHash,
+	})
+}
+
+// updateSafeTransaction sets fields on the Safe transaction with the id if it
+// also matches filter. It fails if it does not.
+func (r *Repository) updateSafeTransaction(ctx context.Context, id string, filter bson.M, set bson.M) error {
+	collection := r.collection("safe_transactions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid safe transaction id: %v", err)
+	}
+	filter["_id"] = objectID
+
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("failed to update safe transaction: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to find safe transaction: %v", mongo.ErrNoDocuments)
+	}
+	return nil
+}
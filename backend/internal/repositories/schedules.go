@@ -0,0 +1,245 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *Repository) SaveSchedule(ctx context.Context, schedule *models.Schedule) (models.Schedule, error) {
+	collection := r.collection("schedules")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, schedule)
+	if err != nil {
+		return *schedule, fmt.Errorf("failed to insert schedule into database: %v", err)
+	}
+	schedule.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *schedule, nil
+}
+
+func (r *Repository) GetSchedule(ctx context.Context, id string) (models.Schedule, error) {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var schedule models.Schedule
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return schedule, fmt.Errorf("invalid schedule id: %v", err)
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&schedule)
+	if err != nil {
+		return schedule, fmt.Errorf("failed to find schedule: %v", err)
+	}
+	return schedule, nil
+}
+
+// ListSchedules returns the schedules sending from any of the wallets.
+func (r *Repository) ListSchedules(ctx context.Context, wallets []string) ([]models.Schedule, error) {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"fromaddress": bson.M{"$in": wallets}}, options.Find().SetSort(bson.M{"nextrunat": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var schedules []models.Schedule
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// UpdateScheduleState sets the status and next run time of a schedule.
+func (r *Repository) UpdateScheduleState(ctx context.Context, id string, status string, nextRunAt time.Time) error {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid schedule id: %v", err)
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"status":    status,
+		"nextrunat": nextRunAt,
+		"updatedat": time.Now().UTC(),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to find schedule: %v", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+// PauseUserSchedules pauses every active schedule of a user and returns how
+// many were paused.
+func (r *Repository) PauseUserSchedules(ctx context.Context, userId string) (int64, error) {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.UpdateMany(ctx, bson.M{"userid": userId, "status": models.ScheduleStatusActive}, bson.M{"$set": bson.M{
+		"status":    models.ScheduleStatusPaused,
+		"updatedat": time.Now().UTC(),
+	}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to pause schedules: %v", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *Repository) DeleteSchedule(ctx context.Context, id string) error {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid schedule id: %v", err)
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("failed to find schedule: %v", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+// ClaimDueSchedule atomically leases one active schedule whose next run is due,
+// so that concurrent schedulers never execute the same run twice. It returns
+// nil when no schedule is due.
+func (r *Repository) ClaimDueSchedule(ctx context.Context, now time.Time, lease time.Duration) (*models.Schedule, error) {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"status":      models.ScheduleStatusActive,
+		"nextrunat":   bson.M{"$lte": now},
+		"lockeduntil": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"lockeduntil": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"nextrunat": 1}).
+		SetReturnDocument(options.After)
+
+	var schedule models.Schedule
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&schedule)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim schedule: %v", err)
+	}
+	return &schedule, nil
+}
+
+// FinishScheduleRun releases the lease taken by ClaimDueSchedule and records
+// the outcome of the run. It clears the pending run, so it is only called
+// once the outcome of the run's transaction is known.
+func (r *Repository) FinishScheduleRun(ctx context.Context, id string, ranAt time.Time, status string, nextRunAt time.Time) error {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid schedule id: %v", err)
+	}
+
+	// Only move a schedule that is still active, so a pause issued while the
+	// run was in flight is preserved.
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.A{bson.M{"$set": bson.M{
+		"lastrunat":   ranAt,
+		"nextrunat":   nextRunAt,
+		"lockeduntil": time.Time{},
+		"pendingrun":  nil,
+		"updatedat":   time.Now().UTC(),
+		"status": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$status", models.ScheduleStatusActive}}, status, "$status",
+		}},
+	}}})
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	return nil
+}
+
+// SetPendingRun records the signed transaction of the run in progress on a
+// schedule claimed by ClaimDueSchedule, before the transaction is broadcast.
+func (r *Repository) SetPendingRun(ctx context.Context, id string, pending models.PendingRun) error {
+	collection := r.collection("schedules")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid schedule id: %v", err)
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"pendingrun": pending,
+		"updatedat":  time.Now().UTC(),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to record schedule run: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to find schedule: %v", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+func (r *Repository) SaveScheduleRun(ctx context.Context, run *models.ScheduleRun) (models.ScheduleRun, error) {
+	collection := r.collection("schedule_runs")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, run)
+	if err != nil {
+		return *run, fmt.Errorf("failed to insert schedule run into database: %v", err)
+	}
+	run.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *run, nil
+}
+
+func (r *Repository) ListScheduleRuns(ctx context.Context, scheduleId string) ([]models.ScheduleRun, error) {
+	collection := r.collection("schedule_runs")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"scheduleid": scheduleId}, options.Find().SetSort(bson.M{"startedat": -1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var runs []models.ScheduleRun
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
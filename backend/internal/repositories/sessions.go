@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (r *Repository) CreateSession(ctx context.Context, session *models.Session) (models.Session, error) {
+	collection := r.collection("sessions")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, session)
+	if err != nil {
+		return *session, fmt.Errorf("failed to insert session into database: %v", err)
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *session, nil
+}
+
+func (r *Repository) GetSession(ctx context.Context, id string) (models.Session, error) {
+	collection := r.collection("sessions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var session models.Session
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return session, fmt.Errorf("invalid session id: %v", err)
+	}
+
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&session)
+	if err != nil {
+		return session, fmt.Errorf("failed to find session: %v", err)
+	}
+	return session, nil
+}
+
+// GetSessionByRefreshToken finds the session whose current or previous
+// refresh token has the given hash.
+func (r *Repository) GetSessionByRefreshToken(ctx context.Context, tokenHash string) (models.Session, error) {
+	collection := r.collection("sessions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var session models.Session
+	err := collection.FindOne(ctx, bson.M{"$or": bson.A{
+		bson.M{"refreshtokenhash": tokenHash},
+		bson.M{"previousrefreshtokenhash": tokenHash},
+	}}).Decode(&session)
+	if err != nil {
+		return session, fmt.Errorf("failed to find session: %v", err)
+	}
+	return session, nil
+}
+
+// RotateSessionToken replaces the refresh token of an active session. It only
+// succeeds if oldHash is still the current token, so concurrent refreshes with
+// the same token cannot both win.
+func (r *Repository) RotateSessionToken(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) error {
+	collection := r.collection("sessions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid session id: %v", err)
+	}
+
+	result, err := collection.UpdateOne(ctx, bson.M{
+		"_id":              objectID,
+		"refreshtokenhash": oldHash,
+		"revokedat":        nil,
+	}, bson.M{"$set": bson.M{
+		"refreshtokenhash":         newHash,
+		"previousrefreshtokenhash": oldHash,
+		"lastusedat":               time.Now().UTC(),
+		"expiresat":                expiresAt,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to rotate session: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to find session: %v", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+func (r *Repository) RevokeSession(ctx context.Context, id string) error {
+	collection := r.collection("sessions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid session id: %v", err)
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID, "revokedat": nil}, bson.M{"$set": bson.M{"revokedat": time.Now().UTC()}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %v", err)
+	}
+	return nil
+}
+
+func (r *Repository) RevokeUserSessions(ctx context.Context, userId string) error {
+	collection := r.collection("sessions")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := collection.UpdateMany(ctx, bson.M{"userid": userId, "revokedat": nil}, bson.M{"$set": bson.M{"revokedat": time.Now().UTC()}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %v", err)
+	}
+	return nil
+}
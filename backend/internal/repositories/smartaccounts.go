@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (r *Repository) SaveSmartAccount(ctx context.Context, account *models.SmartAccount) (models.SmartAccount, error) {
+	collection := r.collection("smart_accounts")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, account)
+	if err != nil {
+		return *account, fmt.Errorf("failed to insert smart account into database: %v", err)
+	}
+	account.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *account, nil
+}
+
+func (r *Repository) GetSmartAccount(ctx context.Context, address string) (models.SmartAccount, error) {
+	collection := r.collection("smart_accounts")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var account models.SmartAccount
+	err := collection.FindOne(ctx, bson.M{"address": address}).Decode(&account)
+	if err != nil {
+		return account, fmt.Errorf("failed to find smart account: %v", err)
+	}
+	return account, nil
+}
+
+// ListSmartAccounts returns the smart accounts owned by any of the wallets.
+func (r *Repository) ListSmartAccounts(ctx context.Context, owners []string) ([]models.SmartAccount, error) {
+	collection := r.collection("smart_accounts")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"owneraddress": bson.M{"$in": owners}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var accounts []models.SmartAccount
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+// Errors shared by every store implementation.
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrUsernameTaken  = errors.New("username already exists")
+	ErrEmailTaken     = errors.New("email already exists")
+	ErrUserNotUpdated = errors.New("user not found or state changed")
+)
+
+// UserStore persists user accounts. Conditional updates fail with
+// ErrUserNotUpdated when the user is missing or not in the expected state.
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserByWebAuthnHandle(ctx context.Context, handle []byte) (*models.User, error)
+	GetUserByOIDCSubject(ctx context.Context, issuer string, subject string) (*models.User, error)
+
+	SetUserStatus(ctx context.Context, userId string, status string) error
+	UpdatePassword(ctx context.Context, userId string, passwordHash string) error
+	SetUserAdmin(ctx context.Context, userId string, isAdmin bool) error
+
+	SetPendingTOTPSecret(ctx context.Context, userId string, secret string) error
+	EnableTOTP(ctx context.Context, userId string, secret string, step int64, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, userId string) error
+	SetRecoveryCodes(ctx context.Context, userId string, recoveryCodeHashes []string) error
+	AdvanceTOTPStep(ctx context.Context, userId string, step int64) error
+	UseRecoveryCode(ctx context.Context, userId string, codeHash string) error
+
+	SetWebAuthnHandle(ctx context.Context, userId string, handle []byte) error
+	SetRequireWebAuthnForTransactions(ctx context.Context, userId string, required bool) error
+	LinkOIDCIdentity(ctx context.Context, userId string, issuer string, subject string) error
+}
+
+// WalletStore persists wallets, looked up by address.
+type WalletStore interface {
+	SaveWallet(ctx context.Context, wallet *models.Wallet) (models.Wallet, error)
+	GetWallet(ctx context.Context, address string) (models.Wallet, error)
+	ListWallets(ctx context.Context, userId string, organisationIds []string) ([]models.Wallet, error)
+	CountWallets(ctx context.Context) (int64, error)
+}
+
+// TransactionStore persists the history of sent transactions.
+type TransactionStore interface {
+	SaveTransaction(ctx context.Context, transaction *models.TransactionResult) (models.TransactionResult, error)
+	GetTransactionByUserOpHash(ctx context.Context, userOpHash string, sender string) (models.TransactionResult, error)
+	UpdateTransactionReceipt(ctx context.Context, transaction *models.TransactionResult) error
+	FindTransactions(ctx context.Context, from string, method string) ([]models.TransactionResult, error)
+}
+
+// SessionStore persists login sessions and their refresh tokens.
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *models.Session) (models.Session, error)
+	GetSession(ctx context.Context, id string) (models.Session, error)
+	GetSessionByRefreshToken(ctx context.Context, tokenHash string) (models.Session, error)
+	RotateSessionToken(ctx context.Context, id string, oldHash string, newHash string, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userId string) error
+}
+
+// APIKeyStore persists API keys, looked up by the hash of the key.
+type APIKeyStore interface {
+	SaveAPIKey(ctx context.Context, apiKey *models.APIKey) (models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userId string) ([]models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+	RevokeAPIKey(ctx context.Context, id string, userId string) error
+}
+
+// UserTokenStore persists single use email verification and password reset
+// tokens.
+type UserTokenStore interface {
+	SaveUserToken(ctx context.Context, token *models.UserToken) (models.UserToken, error)
+	ConsumeUserToken(ctx context.Context, tokenHash string, purpose string) (models.UserToken, error)
+}
+
+// LoginAttemptStore counts failed logins per username and client IP.
+type LoginAttemptStore interface {
+	GetLoginAttempts(ctx context.Context, keys []string) ([]models.LoginAttempt, error)
+	RecordLoginFailure(ctx context.Context, key string, window time.Duration) (models.LoginAttempt, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ClearLoginAttempts(ctx context.Context, key string) error
+	ListLockedLogins(ctx context.Context) ([]models.LoginAttempt, error)
+}
+
+// OIDCStateStore persists single sign-on logins between the redirect and the
+// callback.
+type OIDCStateStore interface {
+	SaveOIDCState(ctx context.Context, state *models.OIDCState) error
+	TakeOIDCState(ctx context.Context, stateHash string) (models.OIDCState, error)
+}
+
+// WebAuthnStore persists passkeys and the state of unfinished ceremonies.
+type WebAuthnStore interface {
+	SaveWebAuthnCredential(ctx context.Context, credential *models.WebAuthnCredential) (models.WebAuthnCredential, error)
+	ListWebAuthnCredentials(ctx context.Context, userId string) ([]models.WebAuthnCredential, error)
+	UpdateWebAuthnCredentialUse(ctx context.Context, credential *models.WebAuthnCredential) error
+	DeleteWebAuthnCredential(ctx context.Context, id string, userId string) error
+	SaveWebAuthnChallenge(ctx context.Context, challenge *models.WebAuthnChallenge) (models.WebAuthnChallenge, error)
+	TakeWebAuthnChallenge(ctx context.Context, id string, purpose string) (models.WebAuthnChallenge, error)
+	GetWebAuthnChallenge(ctx context.Context, id string, purpose string) (models.WebAuthnChallenge, error)
+}
+
+// AuditStore persists the append-only audit log. InsertAuditEntry fails with
+// ErrAuditSequenceTaken when the sequence number is already used.
+type AuditStore interface {
+	GetLastAuditEntry(ctx context.Context) (models.AuditEntry, error)
+	InsertAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	ListAuditEntries(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error)
+	WalkAuditEntries(ctx context.Context, fn func(models.AuditEntry) error) error
+}
+
+// OrganisationStore persists organisations and their members.
+type OrganisationStore interface {
+	SaveOrganisation(ctx context.Context, organisation *models.Organisation) (models.Organisation, error)
+	GetOrganisation(ctx context.Context, id string) (models.Organisation, error)
+	ListOrganisations(ctx context.Context, ids []string) ([]models.Organisation, error)
+	SaveOrganisationMember(ctx context.Context, member *models.OrganisationMember) (models.OrganisationMember, error)
+	GetOrganisationMember(ctx context.Context, organisationId string, userId string) (models.OrganisationMember, error)
+	ListOrganisationMembers(ctx context.Context, organisationId string) ([]models.OrganisationMember, error)
+	ListMemberships(ctx context.Context, userId string) ([]models.OrganisationMember, error)
+	UpdateOrganisationMemberRole(ctx context.Context, organisationId string, userId string, role string) error
+	DeleteOrganisationMember(ctx context.Context, organisationId string, userId string) error
+}
+
+// SafeStore persists Safe multisigs and their proposed transactions.
+type SafeStore interface {
+	SaveSafe(ctx context.Context, safe *models.SafeWallet) (models.SafeWallet, error)
+	GetSafe(ctx context.Context, address string) (models.SafeWallet, error)
+	ListSafes(ctx context.Context, owners []string) ([]models.SafeWallet, error)
+	SaveSafeTransaction(ctx context.Context, transaction *models.SafeTransaction) (models.SafeTransaction, error)
+	GetSafeTransaction(ctx context.Context, id string, safeAddress string) (models.SafeTransaction, error)
+	ListSafeTransactions(ctx context.Context, safeAddress string) ([]models.SafeTransaction, error)
+	AddSafeSignature(ctx context.Context, id string, signature models.SafeSignature) error
+	ClaimSafeTransaction(ctx context.Context, id string) error
+	SetSafeExecutionTxHash(ctx context.Context, id string, executionTxHash string) error
+	ReleaseSafeTransaction(ctx context.Context, id string) error
+	MarkSafeTransactionExecuted(ctx context.Context, id string, status string, executionTxHash string) error
+}
+
+// SmartAccountStore persists ERC-4337 smart accounts.
+type SmartAccountStore interface {
+	SaveSmartAccount(ctx context.Context, account *models.SmartAccount) (models.SmartAccount, error)
+	GetSmartAccount(ctx context.Context, address string) (models.SmartAccount, error)
+	ListSmartAccounts(ctx context.Context, owners []string) ([]models.SmartAccount, error)
+}
+
+// ScheduleStore persists scheduled transfers and the history of their runs.
+type ScheduleStore interface {
+	SaveSchedule(ctx context.Context, schedule *models.Schedule) (models.Schedule, error)
+	GetSchedule(ctx context.Context, id string) (models.Schedule, error)
+	ListSchedules(ctx context.Context, wallets []string) ([]models.Schedule, error)
+	UpdateScheduleState(ctx context.Context, id string, status string, nextRunAt time.Time) error
+	PauseUserSchedules(ctx context.Context, userId string) (int64, error)
+	DeleteSchedule(ctx context.Context, id string) error
+	ClaimDueSchedule(ctx context.Context, now time.Time, lease time.Duration) (*models.Schedule, error)
+	FinishScheduleRun(ctx context.Context, id string, ranAt time.Time, status string, nextRunAt time.Time) error
+	SetPendingRun(ctx context.Context, id string, pending models.PendingRun) error
+	SaveScheduleRun(ctx context.Context, run *models.ScheduleRun) (models.ScheduleRun, error)
+	ListScheduleRuns(ctx context.Context, scheduleId string) ([]models.ScheduleRun, error)
+}
+
+// Store persists everything the services keep besides users, wallets and
+// transactions. It is implemented by the MongoDB repository and the memory
+// store.
+type Store interface {
+	SessionStore
+	APIKeyStore
+	UserTokenStore
+	LoginAttemptStore
+	OIDCStateStore
+	WebAuthnStore
+	AuditStore
+	OrganisationStore
+	SafeStore
+	SmartAccountStore
+	ScheduleStore
+
+	// Ping checks that the store can be reached.
+	Ping(ctx context.Context) error
+}
+
+// Stores groups the stores the services persist users, wallets and
+// transactions in.
+type Stores struct {
+	Users        UserStore
+	Wallets      WalletStore
+	Transactions TransactionStore
+}
+
+// Stores returns the MongoDB implementation of every store.
+func (r *Repository) Stores() Stores {
+	return Stores{Users: r, Wallets: r, Transactions: r}
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (r *Repository) SetPendingTOTPSecret(ctx context.Context, userId string, secret string) error {
+	return r.updateUser(ctx, userId, bson.M{}, bson.M{"$set": bson.M{"totppendingsecret": secret}})
+}
+
+// EnableTOTP promotes the pending secret and stores the recovery code hashes.
+// The step the confirming code was generated in is recorded so it cannot be
+// replayed.
+func (r *Repository) EnableTOTP(ctx context.Context, userId string, secret string, step int64, recoveryCodeHashes []string) error {
+	return r.updateUser(ctx, userId, bson.M{"totppendingsecret": secret}, bson.M{
+		"$set": bson.M{
+			"totpenabled":        true,
+			"totpsecret":         secret,
+			"totppendingsecret":  "",
+			"totplaststep":       step,
+			"recoverycodehashes": recoveryCodeHashes,
+		},
+	})
+}
+
+func (r *Repository) DisableTOTP(ctx context.Context, userId string) error {
+	return r.updateUser(ctx, userId, bson.M{}, bson.M{
+		"$set": bson.M{
+			"totpenabled":        false,
+			"totpsecret":         "",
+			"totppendingsecret":  "",
+			"totplaststep":       0,
+			"recoverycodehashes": []string{},
+		},
+	})
+}
+
+func (r *Repository) SetRecoveryCodes(ctx context.Context, userId string, recoveryCodeHashes []string) error {
+	return r.updateUser(ctx, userId, bson.M{"totpenabled": true}, bson.M{"$set": bson.M{"recoverycodehashes": recoveryCodeHashes}})
+}
+
+// AdvanceTOTPStep records a used TOTP step. It fails if the step, or a later
+// one, was already used, which prevents a code from being accepted twice.
+func (r *Repository) AdvanceTOTPStep(ctx context.Context, userId string, step int64) error {
+	return r.updateUser(ctx, userId, bson.M{"totplaststep": bson.M{"$lt": step}}, bson.M{"$set": bson.M{"totplaststep": step}})
+}
+
+// UseRecoveryCode removes a recovery code, failing if it was not present.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userId string, codeHash string) error {
+	return r.updateUser(ctx, userId, bson.M{"recoverycodehashes": codeHash}, bson.M{"$pull": bson.M{"recoverycodehashes": codeHash}})
+}
+
+// updateUser applies update to the user if it also matches filter.
+func (r *Repository) updateUser(ctx context.Context, userId string, filter bson.M, update bson.M) error {
+	collection := r.collection("users")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %v", err)
+	}
+	filter["_id"] = objectID
+
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotUpdated
+	}
+	return nil
+}
+
+func (r *Repository) SetUserStatus(ctx context.Context, userId string, status string) error {
+	return r.updateUser(ctx, userId, bson.M{}, bson.M{"$set": bson.M{
+		"status":    status,
+		"updatedat": time.Now().Format(time.RFC3339),
+	}})
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, userId string, passwordHash string) error {
+	return r.updateUser(ctx, userId, bson.M{}, bson.M{"$set": bson.M{
+		"passwordhash": passwordHash,
+		"updatedat":    time.Now().Format(time.RFC3339),
+	}})
+}
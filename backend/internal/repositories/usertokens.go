@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SaveUserToken stores a new token, invalidating earlier unused tokens of the
+// same purpose for the user.
+func (r *Repository) SaveUserToken(ctx context.Context, token *models.UserToken) (models.UserToken, error) {
+	collection := r.collection("user_tokens")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := collection.UpdateMany(ctx,
+		bson.M{"userid": token.UserID, "purpose": token.Purpose, "usedat": nil},
+		bson.M{"$set": bson.M{"usedat": time.Now().UTC()}},
+	)
+	if err != nil {
+		return *token, fmt.Errorf("failed to invalidate tokens: %v", err)
+	}
+
+	result, err := collection.InsertOne(ctx, token)
+	if err != nil {
+		return *token, fmt.Errorf("failed to insert token into database: %v", err)
+	}
+	token.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *token, nil
+}
+
+// ConsumeUserToken marks an unused, unexpired token as used and returns it.
+func (r *Repository) ConsumeUserToken(ctx context.Context, tokenHash string, purpose string) (models.UserToken, error) {
+	collection := r.collection("user_tokens")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	var token models.UserToken
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"tokenhash": tokenHash, "purpose": purpose, "usedat": nil, "expiresat": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"usedat": now}},
+	).Decode(&token)
+	if err != nil {
+		return token, fmt.Errorf("invalid or expired token")
+	}
+	return token, nil
+}
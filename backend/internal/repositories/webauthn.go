@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (r *Repository) SaveWebAuthnCredential(ctx context.Context, credential *models.WebAuthnCredential) (models.WebAuthnCredential, error) {
+	collection := r.collection("webauthn_credentials")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, credential)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return *credential, fmt.Errorf("credential already registered")
+		}
+		return *credential, fmt.Errorf("failed to insert credential into database: %v", err)
+	}
+	credential.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *credential, nil
+}
+
+func (r *Repository) ListWebAuthnCredentials(ctx context.Context, userId string) ([]models.WebAuthnCredential, error) {
+	collection := r.collection("webauthn_credentials")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := collection.Find(ctx, bson.M{"userid": userId}, options.Find().SetSort(bson.M{"createdat": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find credentials: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	credentials := []models.WebAuthnCredential{}
+	if err := cursor.All(ctx, &credentials); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials: %v", err)
+	}
+	return credentials, nil
+}
+
+// UpdateWebAuthnCredentialUse stores the authenticator state after an
+// assertion, so sign counter regressions can be detected.
+func (r *Repository) UpdateWebAuthnCredentialUse(ctx context.Context, credential *models.WebAuthnCredential) error {
+	collection := r.collection("webauthn_credentials")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(credential.ID)
+	if err != nil {
+		return fmt.Errorf("invalid credential id: %v", err)
+	}
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"credential": credential.Credential,
+			"lastusedat": credential.LastUsedAt,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update credential: %v", err)
+	}
+	return nil
+}
+
+func (r *Repository) DeleteWebAuthnCredential(ctx context.Context, id string, userId string) error {
+	collection := r.collection("webauthn_credentials")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid credential id: %v", err)
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID, "userid": userId})
+	if err != nil {
+		return fmt.Errorf("failed to delete credential: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("credential not found")
+	}
+	return nil
+}
+
+func (r *Repository) SaveWebAuthnChallenge(ctx context.Context, challenge *models.WebAuthnChallenge) (models.WebAuthnChallenge, error) {
+	collection := r.collection("webauthn_challenges")
+	insertCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := collection.InsertOne(insertCtx, challenge)
+	if err != nil {
+		return *challenge, fmt.Errorf("failed to insert challenge into database: %v", err)
+	}
+	challenge.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return *challenge, nil
+}
+
+// TakeWebAuthnChallenge removes and returns an unexpired challenge, so each
+// ceremony can only be finished once.
+func (r *Repository) TakeWebAuthnChallenge(ctx context.Context, id string, purpose string) (models.WebAuthnChallenge, error) {
+	collection := r.collection("webauthn_challenges")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var challenge models.WebAuthnChallenge
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return challenge, fmt.Errorf("invalid challenge id: %v", err)
+	}
+
+	err = collection.FindOneAndDelete(ctx, bson.M{
+		"_id":       objectID,
+		"purpose":   purpose,
+		"expiresat": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&challenge)
+	if err != nil {
+		return challenge, fmt.Errorf("challenge not found or expired")
+	}
+	return challenge, nil
+}
+
+// GetWebAuthnChallenge returns an unexpired challenge without consuming it.
+func (r *Repository) GetWebAuthnChallenge(ctx context.Context, id string, purpose string) (models.WebAuthnChallenge, error) {
+	collection := r.collection("webauthn_challenges")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var challenge models.WebAuthnChallenge
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return challenge, fmt.Errorf("invalid challenge id: %v", err)
+	}
+
+	err = collection.FindOne(ctx, bson.M{
+		"_id":       objectID,
+		"purpose":   purpose,
+		"expiresat": bson.M{"$gt": time.Now().UTC()},
+	}).Decode(&challenge)
+	if err != nil {
+		return challenge, fmt.Errorf("challenge not found or expired")
+	}
+	return challenge, nil
+}
+
+func (r *Repository) GetUserByWebAuthnHandle(ctx context.Context, handle []byte) (*models.User, error) {
+	collection := r.collection("users")
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := collection.FindOne(ctx, bson.M{"webauthnhandle": handle}).Decode(&user)
+	if err != nil {
+		return nil, findError("user", err)
+	}
+	return &user, nil
+}
+
+// SetWebAuthnHandle assigns a user handle to a user that has none yet.
+func (r *Repository) SetWebAuthnHandle(ctx context.Context, userId string, handle []byte) error {
+	return r.updateUser(ctx, userId, bson.M{"webauthnhandle": nil}, bson.M{"$set": bson.M{"webauthnhandle": handle}})
+}
+
+func (r *Repository) SetRequireWebAuthnForTransactions(ctx context.Context, userId string, required bool) error {
+	return r.updateUser(ctx, userId, bson.M{}, bson.M{"$set": bson.M{"requirewebauthnfortransactions": required}})
+}
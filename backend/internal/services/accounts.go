@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/mailer"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+// ErrEmailNotVerified is returned when an account that has not verified its
+// email address tries to log in.
+var ErrEmailNotVerified = errors.New("email address has not been verified")
+
+// VerifyEmail activates the account the verification token was sent to.
+func (s *Service) VerifyEmail(ctx context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userToken, err := s.repo.ConsumeUserToken(ctx, hashToken(token), models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	return s.users.SetUserStatus(ctx, userToken.UserID, models.UserStatusActive)
+}
+
+// ResendVerificationEmail sends a new verification link. It does nothing for
+// unknown or already verified addresses, so it cannot be used to probe for
+// accounts.
+func (s *Service) ResendVerificationEmail(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil || user.Status != models.UserStatusPendingVerification {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// RequestPasswordReset emails a password reset link. Like
+// ResendVerificationEmail it succeeds for unknown addresses.
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in one hour.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+			user.Username, s.appURL+"/reset-password?token="+url.QueryEscape(token)),
+	})
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out
+// everywhere. Receiving the email also proves the address, so pending
+// accounts are activated.
+func (s *Service) ResetPassword(ctx context.Context, token string, password string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	userToken, err := s.repo.ConsumeUserToken(ctx, hashToken(token), models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePassword(ctx, userToken.UserID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.users.SetUserStatus(ctx, userToken.UserID, models.UserStatusActive); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditPasswordReset, UserID: userToken.UserID})
+
+	return s.repo.RevokeUserSessions(ctx, userToken.UserID)
+}
+
+func (s *Service) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueUserToken(ctx, user.ID, models.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address to activate your wallet account:\n\n%s\n\nThe link expires in 24 hours.\n",
+			user.Username, s.appURL+"/verify-email?token="+url.QueryEscape(token)),
+	})
+}
+
+// sendVerificationEmailAfterSignUp sends the first verification email. The
+// account already exists at this point, so failures are only logged and the
+// user can ask for the email again.
+func (s *Service) sendVerificationEmailAfterSignUp(ctx context.Context, user *models.User) {
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", user.ID, err)
+	}
+}
+
+// issueUserToken creates a single-use token and returns it; only its hash is stored.
+func (s *Service) issueUserToken(ctx context.Context, userId string, purpose string, ttl time.Duration) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	_, err = s.repo.SaveUserToken(ctx, &models.UserToken{
+		UserID:    userId,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ListAllowances returns the current ERC-20 allowances granted by a wallet.
+// It checks every token/spender pair the wallet approved through the service,
+// plus the cross product of the given tokens and spenders.
+func (s *Service) ListAllowances(ctx context.Context, address string, userId string, tokens []string, spenders []string) ([]models.Allowance, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	wallet, err := s.authorizeWallet(ctx, address, userId, PermissionViewWallet)
+	if err != nil {
+		return nil, err
+	}
+	owner := common.HexToAddress(wallet.PublicKey)
+
+	type pair struct{ token, spender common.Address }
+	var pairs []pair
+	seen := map[pair]bool{}
+	add := func(p pair) {
+		if !seen[p] {
+			seen[p] = true
+			pairs = append(pairs, p)
+		}
+	}
+
+	// Pairs approved earlier, recovered from the recorded calldata
+	approvals, err := s.transactions.FindTransactions(ctx, owner.Hex(), web3.ERC20Approve.Sig)
+	if err != nil {
+		return nil, err
+	}
+	for _, approval := range approvals {
+		data, err := hexutil.Decode(approval.Data)
+		if err != nil || len(data) < 4 {
+			continue
+		}
+		args, err := web3.ERC20Approve.Inputs.Unpack(data[4:])
+		if err != nil {
+			continue
+		}
+		add(pair{common.HexToAddress(approval.To), args[0].(common.Address)})
+	}
+
+	for _, token := range tokens {
+		for _, spender := range spenders {
+			if !common.IsHexAddress(token) || !common.IsHexAddress(spender) {
+				return nil, fmt.Errorf("invalid address format")
+			}
+			add(pair{common.HexToAddress(token), common.HexToAddress(spender)})
+		}
+	}
+
+	allowances := []models.Allowance{}
+	for _, p := range pairs {
+		outputs, err := s.callMethod(ctx, p.token, web3.ERC20Allowance, owner, p.spender)
+		if err != nil {
+			return nil, err
+		}
+
+		allowance := models.Allowance{
+			TokenAddress:   p.token.Hex(),
+			SpenderAddress: p.spender.Hex(),
+			Allowance:      outputs[0].(*big.Int).String(),
+		}
+
+		// symbol and decimals are optional in ERC-20
+		if outputs, err := s.callMethod(ctx, p.token, web3.ERC20Symbol); err == nil {
+			allowance.Symbol = outputs[0].(string)
+		}
+		if outputs, err := s.callMethod(ctx, p.token, web3.ERC20Decimals); err == nil {
+			allowance.Decimals = outputs[0].(uint8)
+		}
+
+		allowances = append(allowances, allowance)
+	}
+
+	return allowances, nil
+}
+
+// ApproveAllowance grants a spender an exact ERC-20 allowance.
+func (s *Service) ApproveAllowance(ctx context.Context, request models.AllowanceRequest, userId string) (models.TransactionResult, error) {
+	amount, ok := new(big.Int).SetString(request.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return models.TransactionResult{}, fmt.Errorf("invalid amount: %s", request.Amount)
+	}
+	return s.approve(ctx, request, amount, userId)
+}
+
+// RevokeAllowance sets a spender's ERC-20 allowance back to zero.
+func (s *Service) RevokeAllowance(ctx context.Context, request models.AllowanceRequest, userId string) (models.TransactionResult, error) {
+	return s.approve(ctx, request, new(big.Int), userId)
+}
+
+func (s *Service) approve(ctx context.Context, request models.AllowanceRequest, amount *big.Int, userId string) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	if !common.IsHexAddress(request.FromAddress) || !common.IsHexAddress(request.TokenAddress) || !common.IsHexAddress(request.SpenderAddress) {
+		return models.TransactionResult{}, fmt.Errorf("invalid address format")
+	}
+
+	data, err := web3.Pack(web3.ERC20Approve, common.HexToAddress(request.SpenderAddress), amount)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	result, _, err := s.signAndSend(ctx, outgoingTransaction{
+		From:   common.HexToAddress(request.FromAddress),
+		To:     common.HexToAddress(request.TokenAddress),
+		Data:   data,
+		Method: web3.ERC20Approve.Sig,
+		UserID: userId,
+	})
+	return result, err
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	apiKeyPrefix         = "cwk_"
+	defaultAPIKeyTTLDays = 90
+	maxAPIKeyTTLDays     = 365
+)
+
+// CreateAPIKey issues a new API key for the user. The key is only returned here.
+func (s *Service) CreateAPIKey(ctx context.Context, request models.APIKeyRequest, userId string) (models.CreatedAPIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if len(request.Scopes) == 0 {
+		return models.CreatedAPIKey{}, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range request.Scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return models.CreatedAPIKey{}, fmt.Errorf("unknown scope: %s", scope)
+		}
+	}
+
+	wallets := make([]string, len(request.Wallets))
+	for i, address := range request.Wallets {
+		if !common.IsHexAddress(address) {
+			return models.CreatedAPIKey{}, fmt.Errorf("invalid wallet address: %s", address)
+		}
+		wallet, err := s.authorizeAPIKeyWallet(ctx, common.HexToAddress(address).Hex(), userId)
+		if err != nil {
+			return models.CreatedAPIKey{}, fmt.Errorf("wallet not found: %s", address)
+		}
+		wallets[i] = wallet
+	}
+
+	cidrs := make([]string, len(request.AllowedCIDRs))
+	for i, cidr := range request.AllowedCIDRs {
+		network, err := parseCIDR(cidr)
+		if err != nil {
+			return models.CreatedAPIKey{}, err
+		}
+		cidrs[i] = network.String()
+	}
+
+	days := request.ExpiresInDays
+	if days == 0 {
+		days = defaultAPIKeyTTLDays
+	}
+	if days < 0 || days > maxAPIKeyTTLDays {
+		return models.CreatedAPIKey{}, fmt.Errorf("expires_in_days must be between 1 and %d", maxAPIKeyTTLDays)
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	key := apiKeyPrefix + secret
+
+	now := time.Now().UTC()
+	apiKey, err := s.repo.SaveAPIKey(ctx, &models.APIKey{
+		UserID:       userId,
+		Name:         request.Name,
+		Prefix:       key[:len(apiKeyPrefix)+8],
+		KeyHash:      hashToken(key),
+		Scopes:       request.Scopes,
+		Wallets:      wallets,
+		AllowedCIDRs: cidrs,
+		CreatedAt:    now,
+		ExpiresAt:    now.AddDate(0, 0, days),
+	})
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	s.audit(ctx, models.AuditEntry{
+		Action: models.AuditAPIKeyCreated,
+		UserID: userId,
+		Target: apiKey.ID,
+		Details: map[string]string{
+			"scopes":  strings.Join(apiKey.Scopes, ","),
+			"wallets": strings.Join(apiKey.Wallets, ","),
+		},
+	})
+
+	return models.CreatedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// authorizeAPIKeyWallet checks that the user may view the wallet, Safe or
+// smart account at address, so an API key can be restricted to it.
+func (s *Service) authorizeAPIKeyWallet(ctx context.Context, address string, userId string) (string, error) {
+	if wallet, err := s.authorizeWallet(ctx, address, userId, PermissionViewWallet); err == nil {
+		return wallet.PublicKey, nil
+	}
+	if safe, err := s.authorizeSafe(ctx, address, userId, PermissionViewWallet); err == nil {
+		return safe.Address, nil
+	}
+	account, _, err := s.authorizeSmartAccount(ctx, address, userId, PermissionViewWallet)
+	if err != nil {
+		return "", err
+	}
+	return account.Address, nil
+}
+
+func (s *Service) ListAPIKeys(ctx context.Context, userId string) ([]models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return s.repo.ListAPIKeys(ctx, userId)
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, id string, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.RevokeAPIKey(ctx, id, userId); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditAPIKeyRevoked, UserID: userId, Target: id})
+	return nil
+}
+
+// ValidateAPIKey checks an API key presented from clientIP and returns it.
+func (s *Service) ValidateAPIKey(ctx context.Context, key string, clientIP string) (models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return models.APIKey{}, fmt.Errorf("invalid api key")
+	}
+
+	apiKey, err := s.repo.GetAPIKeyByHash(ctx, hashToken(key))
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("invalid api key")
+	}
+	now := time.Now()
+	if apiKey.RevokedAt != nil || now.After(apiKey.ExpiresAt) {
+		return models.APIKey{}, fmt.Errorf("api key revoked or expired")
+	}
+
+	if len(apiKey.AllowedCIDRs) > 0 {
+		ip := net.ParseIP(clientIP)
+		allowed := false
+		for _, cidr := range apiKey.AllowedCIDRs {
+			if _, network, err := net.ParseCIDR(cidr); err == nil && ip != nil && network.Contains(ip) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return models.APIKey{}, fmt.Errorf("api key not allowed from %s", clientIP)
+		}
+	}
+
+	// Last use is recorded at most once a minute
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > time.Minute {
+		if err := s.repo.TouchAPIKey(ctx, apiKey.ID, now.UTC()); err != nil {
+			return models.APIKey{}, err
+		}
+	}
+
+	return apiKey, nil
+}
+
+// parseCIDR accepts a CIDR range or a single IP address.
+func parseCIDR(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP range: %s", value)
+		}
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP range: %s", value)
+	}
+	return network, nil
+}
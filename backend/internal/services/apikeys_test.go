@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+func TestCreateAPIKeyForSafesAndSmartAccounts(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, store, "alice")
+	other := createTestUser(t, store, "bob")
+
+	const owner = "0x1111111111111111111111111111111111111111"
+	const safe = "0x2222222222222222222222222222222222222222"
+	const account = "0x3333333333333333333333333333333333333333"
+	const otherWallet = "0x4444444444444444444444444444444444444444"
+	saveTestWallet(t, store, owner, user.ID, "")
+	saveTestWallet(t, store, otherWallet, other.ID, "")
+	if _, err := store.SaveSafe(ctx, &models.SafeWallet{Address: safe, Owners: []string{owner}, UserID: user.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveSmartAccount(ctx, &models.SmartAccount{Address: account, OwnerAddress: owner}); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := service.CreateAPIKey(ctx, models.APIKeyRequest{
+		Name:    "bot",
+		Scopes:  []string{models.ScopeTxSend},
+		Wallets: []string{owner, safe, account},
+	}, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(created.APIKey.Wallets, []string{owner, safe, account}) {
+		t.Errorf("wallets = %v", created.APIKey.Wallets)
+	}
+
+	_, err = service.CreateAPIKey(ctx, models.APIKeyRequest{
+		Name:    "bot",
+		Scopes:  []string{models.ScopeTxSend},
+		Wallets: []string{safe},
+	}, other.ID)
+	if err == nil {
+		t.Error("a key was created for another user's Safe")
+	}
+	_, err = service.CreateAPIKey(ctx, models.APIKeyRequest{
+		Name:    "bot",
+		Scopes:  []string{models.ScopeTxSend},
+		Wallets: []string{otherWallet},
+	}, user.ID)
+	if err == nil {
+		t.Error("a key was created for another user's wallet")
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+)
+
+// auditRetryDelay bounds the random wait before chaining onto an entry that
+// another writer appended first.
+const auditRetryDelay = 20 * time.Millisecond
+
+// audit appends an entry to the audit log. Failures are logged rather than
+// failing the audited action, which has already happened.
+func (s *Service) audit(ctx context.Context, entry models.AuditEntry) {
+	// Record the entry even if the action used up its own deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err := appendAuditEntry(ctx, s.repo, entry); err != nil {
+		log.Printf("Failed to write audit entry %s for user %s: %v", entry.Action, entry.UserID, err)
+	}
+}
+
+// auditLogin records a successful login and where it came from.
+func (s *Service) auditLogin(ctx context.Context, userId string, clientIP string, method string) {
+	s.audit(ctx, models.AuditEntry{
+		Action:  models.AuditLogin,
+		UserID:  userId,
+		IP:      clientIP,
+		Details: map[string]string{"method": method},
+	})
+}
+
+// appendAuditEntry chains the entry onto the current head of the log,
+// retrying when another writer appended first until ctx is done.
+func appendAuditEntry(ctx context.Context, repo repositories.AuditStore, entry models.AuditEntry) error {
+	// Stored times have millisecond precision, so hash what will be read back
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+	for {
+		head, err := repo.GetLastAuditEntry(ctx)
+		if err != nil {
+			return err
+		}
+		entry.Sequence = head.Sequence + 1
+		entry.PrevHash = head.Hash
+		entry.Hash = auditHash(entry)
+
+		err = repo.InsertAuditEntry(ctx, &entry)
+		if !errors.Is(err, repositories.ErrAuditSequenceTaken) {
+			return err
+		}
+
+		// Spread out writers that keep colliding
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("audit log is busy: %v", ctx.Err())
+		case <-time.After(rand.N(auditRetryDelay)):
+		}
+	}
+}
+
+// auditHash returns the hex SHA-256 of the entry's content and the previous
+// entry's hash.
+func auditHash(entry models.AuditEntry) string {
+	content, _ := json.Marshal(struct {
+		Sequence  int64
+		Action    string
+		UserID    string
+		IP        string
+		Target    string
+		Details   map[string]string `json:",omitempty"`
+		CreatedAt string
+		PrevHash  string
+	}{
+		Sequence:  entry.Sequence,
+		Action:    entry.Action,
+		UserID:    entry.UserID,
+		IP:        entry.IP,
+		Target:    entry.Target,
+		Details:   entry.Details,
+		CreatedAt: entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		PrevHash:  entry.PrevHash,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain walks the audit log and checks that sequence numbers have
+// no gaps and every hash matches its entry and links to the previous one.
+// It is used by the admin endpoint and the audit command.
+func VerifyAuditChain(ctx context.Context, repo repositories.AuditStore) (models.AuditVerification, error) {
+	verification := models.AuditVerification{Valid: true}
+	var previous models.AuditEntry
+
+	errBroken := errors.New("audit chain broken")
+	err := repo.WalkAuditEntries(ctx, func(entry models.AuditEntry) error {
+		var problem string
+		switch {
+		case entry.Sequence != previous.Sequence+1:
+			problem = fmt.Sprintf("expected sequence %d, found %d", previous.Sequence+1, entry.Sequence)
+		case entry.PrevHash != previous.Hash:
+			problem = fmt.Sprintf("entry %d does not link to the previous entry", entry.Sequence)
+		case entry.Hash != auditHash(entry):
+			problem = fmt.Sprintf("entry %d does not match its hash", entry.Sequence)
+		}
+		if problem != "" {
+			verification.Valid = false
+			verification.BrokenAt = previous.Sequence + 1
+			verification.Error = problem
+			return errBroken
+		}
+
+		verification.Entries++
+		verification.HeadHash = entry.Hash
+		previous = entry
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBroken) {
+		return models.AuditVerification{}, err
+	}
+	return verification, nil
+}
+
+// ListAuditEntries returns the audit entries matching the query, newest
+// first.
+func (s *Service) ListAuditEntries(ctx context.Context, query models.AuditQuery) ([]models.AuditEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if query.Limit <= 0 || query.Limit > 1000 {
+		query.Limit = 100
+	}
+	return s.repo.ListAuditEntries(ctx, query)
+}
+
+// VerifyAuditLog checks the whole audit hash chain.
+func (s *Service) VerifyAuditLog(ctx context.Context) (models.AuditVerification, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	return VerifyAuditChain(ctx, s.repo)
+}
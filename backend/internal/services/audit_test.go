@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+)
+
+func TestAuditChainValidates(t *testing.T) {
+	service, store := newTestService(t)
+
+	// Concurrent writers collide on the next sequence number and retry
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			service.audit(context.Background(), models.AuditEntry{Action: models.AuditLogin, UserID: fmt.Sprintf("user-%d", i)})
+		}()
+	}
+	wg.Wait()
+
+	verification, err := VerifyAuditChain(context.Background(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verification.Valid || verification.Entries != writers {
+		t.Fatalf("VerifyAuditChain returned %+v, expected %d valid entries", verification, writers)
+	}
+}
+
+// tamperedAuditStore shows the audit log through tamper when it is walked.
+type tamperedAuditStore struct {
+	repositories.AuditStore
+	tamper func(entries []models.AuditEntry) []models.AuditEntry
+}
+
+func (s tamperedAuditStore) WalkAuditEntries(ctx context.Context, fn func(models.AuditEntry) error) error {
+	var entries []models.AuditEntry
+	err := s.AuditStore.WalkAuditEntries(ctx, func(entry models.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, entry := range s.tamper(entries) {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestAuditTamperingIsDetected(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(entries []models.AuditEntry) []models.AuditEntry
+		brokenAt int64
+	}{
+		{
+			name: "edited entry",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				entries[2].UserID = "someone-else"
+				return entries
+			},
+			brokenAt: 3,
+		},
+		{
+			name: "edited and rehashed entry",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				entries[2].UserID = "someone-else"
+				entries[2].Hash = auditHash(entries[2])
+				return entries
+			},
+			brokenAt: 4,
+		},
+		{
+			name: "deleted entry",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				return append(entries[:2], entries[3:]...)
+			},
+			brokenAt: 3,
+		},
+		{
+			name: "swapped entries",
+			tamper: func(entries []models.AuditEntry) []models.AuditEntry {
+				entries[1], entries[2] = entries[2], entries[1]
+				return entries
+			},
+			brokenAt: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, store := newTestService(t)
+			for i := range 5 {
+				service.audit(context.Background(), models.AuditEntry{Action: models.AuditLogin, UserID: fmt.Sprintf("user-%d", i)})
+			}
+
+			verification, err := VerifyAuditChain(context.Background(), tamperedAuditStore{AuditStore: store, tamper: test.tamper})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verification.Valid || verification.BrokenAt != test.brokenAt {
+				t.Fatalf("VerifyAuditChain returned %+v, expected the chain broken at %d", verification, test.brokenAt)
+			}
+		})
+	}
+}
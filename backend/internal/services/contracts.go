@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CallContract performs a read-only eth_call and returns the decoded outputs.
+func (s *Service) CallContract(ctx context.Context, request models.ContractCallRequest) (models.ContractCallResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	method, data, err := encodeContractCall(request)
+	if err != nil {
+		return models.ContractCallResult{}, err
+	}
+
+	contractAddress := common.HexToAddress(request.ContractAddress)
+	msg := ethereum.CallMsg{
+		To:   &contractAddress,
+		Data: data,
+	}
+	if common.IsHexAddress(request.FromAddress) {
+		msg.From = common.HexToAddress(request.FromAddress)
+	}
+	if request.Value != "" {
+		value, ok := new(big.Int).SetString(request.Value, 10)
+		if !ok {
+			return models.ContractCallResult{}, fmt.Errorf("invalid value: %s", request.Value)
+		}
+		msg.Value = value
+	}
+
+	output, err := s.web3Client.CallContract(ctx, msg, nil)
+	if err != nil {
+		return models.ContractCallResult{}, fmt.Errorf("contract call failed: %v", err)
+	}
+
+	outputs, err := web3.DecodeOutputs(method, output)
+	if err != nil {
+		return models.ContractCallResult{}, fmt.Errorf("failed to decode outputs: %v", err)
+	}
+
+	return models.ContractCallResult{Outputs: outputs}, nil
+}
+
+// SendContractTransaction builds a state-changing contract call, signs it with
+// the sender wallet's KMS key and sends it.
+func (s *Service) SendContractTransaction(ctx context.Context, request models.ContractCallRequest, userId string) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	if !common.IsHexAddress(request.FromAddress) {
+		return models.TransactionResult{}, fmt.Errorf("invalid address format")
+	}
+
+	method, data, err := encodeContractCall(request)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	value := new(big.Int)
+	if request.Value != "" {
+		var ok bool
+		value, ok = value.SetString(request.Value, 10)
+		if !ok {
+			return models.TransactionResult{}, fmt.Errorf("invalid value: %s", request.Value)
+		}
+	}
+
+	result, _, err := s.signAndSend(ctx, outgoingTransaction{
+		From:   common.HexToAddress(request.FromAddress),
+		To:     common.HexToAddress(request.ContractAddress),
+		Value:  value,
+		Data:   data,
+		Method: method.Sig,
+		UserID: userId,
+	})
+	return result, err
+}
+
+// encodeContractCall resolves the requested method and ABI-encodes its arguments.
+func encodeContractCall(request models.ContractCallRequest) (abi.Method, []byte, error) {
+	if !common.IsHexAddress(request.ContractAddress) {
+		return abi.Method{}, nil, fmt.Errorf("invalid contract address")
+	}
+
+	method, err := web3.ResolveMethod(request.ABI, request.Method)
+	if err != nil {
+		return abi.Method{}, nil, err
+	}
+
+	data, err := web3.EncodeCall(method, request.Args)
+	if err != nil {
+		return abi.Method{}, nil, fmt.Errorf("failed to encode arguments: %v", err)
+	}
+
+	return method, data, nil
+}
+
+// callMethod performs a read-only call with Go-typed arguments and returns the
+// raw unpacked outputs.
+func (s *Service) callMethod(ctx context.Context, contractAddress common.Address, method abi.Method, args ...interface{}) ([]interface{}, error) {
+	data, err := web3.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := s.web3Client.CallContract(ctx, ethereum.CallMsg{To: &contractAddress, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s call failed: %v", method.Name, err)
+	}
+
+	return method.Outputs.Unpack(output)
+}
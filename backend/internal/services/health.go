@@ -0,0 +1,171 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+// Version is the release of the running build, set at build time with
+// -ldflags "-X github.com/natneam/crypto-wallet-app/backend/internal/services.Version=...".
+var Version = "dev"
+
+const (
+	// healthCheckTimeout bounds each dependency check.
+	healthCheckTimeout = 3 * time.Second
+
+	// readinessCacheTTL is how long dependency checks are reused, so
+	// frequent probes do not hammer MongoDB, the node and KMS.
+	readinessCacheTTL = 5 * time.Second
+)
+
+type healthState struct {
+	startedAt      time.Time
+	storageBackend string
+	kmsProvider    string
+	maxBlockAge    time.Duration
+
+	mu        sync.Mutex
+	readiness models.Readiness
+}
+
+// pinger is implemented by stores that can check their connection.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// SetReady marks whether the instance should receive traffic. It is cleared
+// first when shutting down, so load balancers stop routing requests here
+// before the server stops accepting them.
+func (s *Service) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Ready reports whether the instance should receive traffic.
+func (s *Service) Ready() bool {
+	return s.ready.Load()
+}
+
+// CheckReadiness checks that MongoDB, the storage backend, the node and KMS
+// can be reached and that the node is following the chain. Results are
+// reused for a few seconds.
+func (s *Service) CheckReadiness() models.Readiness {
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+
+	if time.Since(s.health.readiness.Checked) < readinessCacheTTL {
+		return s.health.readiness
+	}
+
+	checks := map[string]func(ctx context.Context) error{
+		"mongodb": s.repo.Ping,
+		"ethereum": func(ctx context.Context) error {
+			chain, err := s.chainStatus(ctx)
+			if err != nil {
+				return err
+			}
+			if lag := time.Duration(chain.LagSeconds * float64(time.Second)); lag > s.health.maxBlockAge {
+				return fmt.Errorf("head block %d is %s old", chain.HeadBlock, lag.Round(time.Second))
+			}
+			return nil
+		},
+		"kms": s.keys.Ping,
+	}
+	// The MongoDB stores are already covered by the mongodb check
+	if store, ok := s.users.(pinger); ok && any(s.users) != any(s.repo) {
+		checks["storage"] = store.Ping
+	}
+
+	readiness := models.Readiness{Ready: true, Checked: time.Now().UTC()}
+	results := make(chan models.HealthCheck, len(checks))
+	for name, check := range checks {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			defer cancel()
+
+			started := time.Now()
+			err := check(ctx)
+			result := models.HealthCheck{Name: name, Healthy: err == nil, LatencyMs: time.Since(started).Milliseconds()}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			results <- result
+		}()
+	}
+	for range checks {
+		result := <-results
+		readiness.Ready = readiness.Ready && result.Healthy
+		readiness.Checks = append(readiness.Checks, result)
+	}
+	slices.SortFunc(readiness.Checks, func(a, b models.HealthCheck) int { return cmp.Compare(a.Name, b.Name) })
+
+	s.health.readiness = readiness
+	return readiness
+}
+
+// Status returns the build, chain head and dependency state for operators.
+func (s *Service) Status() models.ServiceStatus {
+	status := models.ServiceStatus{
+		Version:        versionInfo(),
+		StartedAt:      s.health.startedAt,
+		UptimeSeconds:  int64(time.Since(s.health.startedAt).Seconds()),
+		StorageBackend: s.health.storageBackend,
+		KMSProvider:    s.health.kmsProvider,
+		Readiness:      s.CheckReadiness(),
+	}
+	if !s.Ready() {
+		status.Readiness.Ready = false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	chain, err := s.chainStatus(ctx)
+	if err != nil {
+		status.ChainError = err.Error()
+	} else {
+		status.Chain = &chain
+	}
+	return status
+}
+
+func (s *Service) chainStatus(ctx context.Context) (models.ChainStatus, error) {
+	chainID, err := s.web3Client.ChainID(ctx)
+	if err != nil {
+		return models.ChainStatus{}, fmt.Errorf("failed to get chain id: %v", err)
+	}
+	head, err := s.web3Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return models.ChainStatus{}, fmt.Errorf("failed to get head block: %v", err)
+	}
+
+	headTime := time.Unix(int64(head.Time), 0).UTC()
+	return models.ChainStatus{
+		ChainID:    chainID.String(),
+		HeadBlock:  head.Number.Uint64(),
+		HeadHash:   head.Hash().Hex(),
+		HeadTime:   headTime,
+		LagSeconds: time.Since(headTime).Seconds(),
+	}, nil
+}
+
+func versionInfo() models.VersionInfo {
+	info := models.VersionInfo{Version: Version, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Revision = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/mailer"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+// Login throttling. Failures per username are delayed progressively after
+// a few attempts and lock the username for a while after more; failures per
+// client IP lock the IP out. Counters reset after loginFailureWindow without
+// failures. Wrong second factor codes given to step up a session count too.
+const (
+	loginFailureWindow   = 15 * time.Minute
+	userDelayAfter       = 3
+	maxLoginDelay        = time.Minute
+	userLockoutThreshold = 10
+	userLockoutDuration  = 15 * time.Minute
+	ipLockoutThreshold   = 50
+	ipLockoutDuration    = 15 * time.Minute
+)
+
+// LoginThrottledError is returned while a username or IP must wait before
+// trying to log in again.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+func userLoginKey(username string) string { return "user:" + username }
+func ipLoginKey(ip string) string         { return "ip:" + ip }
+
+// checkLoginAllowed fails with a LoginThrottledError while the username or
+// the client IP is delayed or locked.
+func (s *Service) checkLoginAllowed(ctx context.Context, username string, clientIP string) error {
+	attempts, err := s.repo.GetLoginAttempts(ctx, []string{userLoginKey(username), ipLoginKey(clientIP)})
+	if err != nil {
+		return err
+	}
+
+	var retryAfter time.Duration
+	for _, attempt := range attempts {
+		if attempt.LockedUntil != nil {
+			retryAfter = max(retryAfter, time.Until(*attempt.LockedUntil))
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against the username and the
+// client IP and applies delays and lockouts.
+func (s *Service) recordLoginFailure(ctx context.Context, username string, clientIP string) {
+	now := time.Now().UTC()
+	s.audit(ctx, models.AuditEntry{Action: models.AuditLoginFailed, IP: clientIP, Target: username})
+
+	attempt, err := s.repo.RecordLoginFailure(ctx, userLoginKey(username), loginFailureWindow)
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+	} else {
+		var lockFor time.Duration
+		switch {
+		case attempt.Failures >= userLockoutThreshold:
+			lockFor = userLockoutDuration
+		case attempt.Failures >= userDelayAfter:
+			lockFor = min(time.Second<<(attempt.Failures-userDelayAfter), maxLoginDelay)
+		}
+		if lockFor > 0 {
+			if err := s.repo.LockLogin(ctx, attempt.Key, now.Add(lockFor)); err != nil {
+				log.Printf("Failed to lock login: %v", err)
+			}
+		}
+		if attempt.Failures == userLockoutThreshold {
+			s.sendLockoutEmail(ctx, username)
+		}
+	}
+
+	attempt, err = s.repo.RecordLoginFailure(ctx, ipLoginKey(clientIP), loginFailureWindow)
+	if err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+	} else if attempt.Failures >= ipLockoutThreshold {
+		if err := s.repo.LockLogin(ctx, attempt.Key, now.Add(ipLockoutDuration)); err != nil {
+			log.Printf("Failed to lock login: %v", err)
+		}
+	}
+}
+
+// recordLoginSuccess clears the username's failures. The IP's failures are
+// kept, so one valid account cannot be used to reset them.
+func (s *Service) recordLoginSuccess(ctx context.Context, username string) {
+	if err := s.repo.ClearLoginAttempts(ctx, userLoginKey(username)); err != nil {
+		log.Printf("Failed to clear login attempts: %v", err)
+	}
+}
+
+// sendLockoutEmail tells the account owner, if the username exists, that it
+// was locked.
+func (s *Service) sendLockoutEmail(ctx context.Context, username string) {
+	user, err := s.users.GetUserByUsername(ctx, username)
+	if err != nil {
+		return
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been temporarily locked",
+		Body: fmt.Sprintf("Hi %s,\n\nAfter %d failed sign-in attempts your account has been locked for %d minutes.\n\nIf this was not you, consider resetting your password:\n\n%s\n",
+			user.Username, userLockoutThreshold, int(userLockoutDuration.Minutes()), s.appURL+"/forgot-password"),
+	})
+	if err != nil {
+		log.Printf("Failed to send lockout email to user %s: %v", user.ID, err)
+	}
+}
+
+// ListLockedLogins lists the usernames and IPs currently locked.
+func (s *Service) ListLockedLogins(ctx context.Context) ([]models.LoginAttempt, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return s.repo.ListLockedLogins(ctx)
+}
+
+// UnlockLogin clears the failures of a username, an IP, or both.
+func (s *Service) UnlockLogin(ctx context.Context, request models.UnlockLoginRequest, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if request.Username == "" && request.IP == "" {
+		return fmt.Errorf("username or ip is required")
+	}
+	if request.Username != "" {
+		if err := s.repo.ClearLoginAttempts(ctx, userLoginKey(request.Username)); err != nil {
+			return err
+		}
+	}
+	if request.IP != "" {
+		if err := s.repo.ClearLoginAttempts(ctx, ipLoginKey(request.IP)); err != nil {
+			return err
+		}
+	}
+
+	s.audit(ctx, models.AuditEntry{
+		Action:  models.AuditLoginUnlocked,
+		UserID:  userId,
+		Target:  request.Username,
+		Details: map[string]string{"ip": request.IP},
+	})
+	return nil
+}
+
+// IsAdmin reports whether the user is an administrator.
+func (s *Service) IsAdmin(ctx context.Context, userId string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
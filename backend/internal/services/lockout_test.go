@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/mailer"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/memory"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "correct horse battery staple"
+
+// recordingMailer keeps the messages it is asked to send.
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// createTestUserWithPassword stores an active user who logs in with
+// testPassword.
+func createTestUserWithPassword(t *testing.T, store *memory.Store, username string) *models.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{Username: username, Email: username + "@example.com", PasswordHash: string(hash), Status: models.UserStatusActive}
+	if err := store.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.GetUserByUsername(context.Background(), username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// expectThrottled checks that err is a login throttling error asking to wait
+// between min and max.
+func expectThrottled(t *testing.T, err error, min time.Duration, max time.Duration) {
+	t.Helper()
+
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("expected the login to be throttled, got %v", err)
+	}
+	if throttled.RetryAfter <= min || throttled.RetryAfter > max {
+		t.Fatalf("retry after %s, expected between %s and %s", throttled.RetryAfter, min, max)
+	}
+}
+
+func TestUsernameLockout(t *testing.T) {
+	service, store := newTestService(t)
+	mail := &recordingMailer{}
+	service.mailer = mail
+	ctx := context.Background()
+	user := createTestUserWithPassword(t, store, "alice")
+	createTestUserWithPassword(t, store, "bob")
+
+	// Failures from different addresses still count against the username
+	for i := range userDelayAfter {
+		_, err := service.Login(ctx, "alice", "wrong password", fmt.Sprintf("198.51.100.%d", i))
+		if err == nil || errors.As(err, new(*LoginThrottledError)) {
+			t.Fatalf("failed login %d returned %v, expected invalid credentials", i+1, err)
+		}
+	}
+
+	// Then even the right password has to wait
+	_, err := service.Login(ctx, "alice", testPassword, "198.51.100.1")
+	expectThrottled(t, err, 0, time.Second)
+
+	// Other usernames from the same address are not affected
+	if _, err := service.Login(ctx, "bob", testPassword, "198.51.100.1"); err != nil {
+		t.Fatalf("another user was throttled: %v", err)
+	}
+
+	for range userLockoutThreshold - userDelayAfter {
+		service.recordLoginFailure(ctx, "alice", "198.51.100.1")
+	}
+	_, err = service.Login(ctx, "alice", testPassword, "198.51.100.1")
+	expectThrottled(t, err, userLockoutDuration-time.Minute, userLockoutDuration)
+
+	if len(mail.messages) != 1 || mail.messages[0].To != user.Email {
+		t.Fatalf("lockout emails %+v, expected one to %s", mail.messages, user.Email)
+	}
+
+	// An administrator can lift the lock
+	if err := service.UnlockLogin(ctx, models.UnlockLoginRequest{Username: "alice"}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Login(ctx, "alice", testPassword, "198.51.100.1"); err != nil {
+		t.Fatalf("login after unlocking failed: %v", err)
+	}
+}
+
+func TestSuccessfulLoginResetsUsernameFailures(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	createTestUserWithPassword(t, store, "alice")
+
+	for range 2 {
+		for range userDelayAfter - 1 {
+			if _, err := service.Login(ctx, "alice", "wrong password", "198.51.100.1"); errors.As(err, new(*LoginThrottledError)) {
+				t.Fatalf("failed login was throttled: %v", err)
+			}
+		}
+		if _, err := service.Login(ctx, "alice", testPassword, "198.51.100.1"); err != nil {
+			t.Fatalf("login failed: %v", err)
+		}
+	}
+}
+
+func TestIPLockout(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	createTestUserWithPassword(t, store, "alice")
+	const attacker = "203.0.113.7"
+
+	// Spraying one guess at many usernames locks none of them, but the address
+	for i := range ipLockoutThreshold - 1 {
+		service.recordLoginFailure(ctx, fmt.Sprintf("user-%d", i), attacker)
+	}
+	if _, err := service.Login(ctx, "alice", testPassword, attacker); err != nil {
+		t.Fatalf("login below the address threshold failed: %v", err)
+	}
+
+	// A successful login does not reset the address's failures
+	service.recordLoginFailure(ctx, "user-last", attacker)
+	_, err := service.Login(ctx, "alice", testPassword, attacker)
+	expectThrottled(t, err, ipLockoutDuration-time.Minute, ipLockoutDuration)
+
+	if _, err := service.Login(ctx, "alice", testPassword, "198.51.100.1"); err != nil {
+		t.Fatalf("login from another address failed: %v", err)
+	}
+
+	locked, err := service.ListLockedLogins(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locked) != 1 || locked[0].Key != ipLoginKey(attacker) {
+		t.Fatalf("locked logins %+v, expected only the attacker's address", locked)
+	}
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// maxEnumeratedTokens caps how many ERC-721 tokens are listed per collection.
+const maxEnumeratedTokens = 100
+
+// ListNFTs returns the NFTs a wallet holds in the given collections. ERC-721
+// holdings are enumerated on-chain (the collection must implement
+// ERC721Enumerable); ERC-1155 has no enumeration, so balances are read for
+// the given token IDs.
+func (s *Service) ListNFTs(ctx context.Context, address string, userId string, contracts []string, tokenIds []string) ([]models.NFT, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	wallet, err := s.authorizeWallet(ctx, address, userId, PermissionViewWallet)
+	if err != nil {
+		return nil, err
+	}
+	owner := common.HexToAddress(wallet.PublicKey)
+
+	ids := make([]*big.Int, 0, len(tokenIds))
+	for _, tokenId := range tokenIds {
+		id, ok := new(big.Int).SetString(tokenId, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid token id: %s", tokenId)
+		}
+		ids = append(ids, id)
+	}
+
+	nfts := []models.NFT{}
+	for _, contract := range contracts {
+		if !common.IsHexAddress(contract) {
+			return nil, fmt.Errorf("invalid contract address: %s", contract)
+		}
+		contractAddress := common.HexToAddress(contract)
+
+		standard, err := s.detectNFTStandard(ctx, contractAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		var held []models.NFT
+		if standard == web3.StandardERC721 {
+			held, err = s.listERC721(ctx, contractAddress, owner)
+		} else {
+			held, err = s.listERC1155(ctx, contractAddress, owner, ids)
+		}
+		if err != nil {
+			return nil, err
+		}
+		nfts = append(nfts, held...)
+	}
+
+	return nfts, nil
+}
+
+func (s *Service) listERC721(ctx context.Context, contract common.Address, owner common.Address) ([]models.NFT, error) {
+	enumerable, err := s.supportsInterface(ctx, contract, web3.InterfaceIDERC721Enumerable)
+	if err != nil {
+		return nil, err
+	}
+	if !enumerable {
+		return nil, fmt.Errorf("collection %s does not implement ERC721Enumerable", contract.Hex())
+	}
+
+	outputs, err := s.callMethod(ctx, contract, web3.ERC721BalanceOf, owner)
+	if err != nil {
+		return nil, err
+	}
+	balance := outputs[0].(*big.Int)
+
+	// The balance comes from the contract, so it is clamped before use
+	count := maxEnumeratedTokens
+	if balance.Sign() < 0 {
+		return nil, fmt.Errorf("collection %s returned a negative balance", contract.Hex())
+	}
+	if balance.Cmp(big.NewInt(maxEnumeratedTokens)) < 0 {
+		count = int(balance.Int64())
+	}
+	nfts := make([]models.NFT, 0, count)
+	for i := 0; i < count; i++ {
+		outputs, err := s.callMethod(ctx, contract, web3.ERC721TokenOfOwnerByIndex, owner, big.NewInt(int64(i)))
+		if err != nil {
+			return nil, err
+		}
+		tokenId := outputs[0].(*big.Int)
+
+		uri, err := s.tokenURI(ctx, contract, web3.StandardERC721, tokenId)
+		if err != nil {
+			return nil, err
+		}
+
+		nfts = append(nfts, models.NFT{
+			ContractAddress: contract.Hex(),
+			Standard:        web3.StandardERC721,
+			TokenID:         tokenId.String(),
+			Balance:         "1",
+			TokenURI:        uri,
+		})
+	}
+
+	return nfts, nil
+}
+
+func (s *Service) listERC1155(ctx context.Context, contract common.Address, owner common.Address, ids []*big.Int) ([]models.NFT, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("token ids are required for ERC-1155 collection %s", contract.Hex())
+	}
+
+	owners := make([]common.Address, len(ids))
+	for i := range owners {
+		owners[i] = owner
+	}
+
+	outputs, err := s.callMethod(ctx, contract, web3.ERC1155BalanceOfBatch, owners, ids)
+	if err != nil {
+		return nil, err
+	}
+	balances := outputs[0].([]*big.Int)
+
+	nfts := []models.NFT{}
+	for i, balance := range balances {
+		if balance.Sign() == 0 {
+			continue
+		}
+
+		uri, err := s.tokenURI(ctx, contract, web3.StandardERC1155, ids[i])
+		if err != nil {
+			return nil, err
+		}
+
+		nfts = append(nfts, models.NFT{
+			ContractAddress: contract.Hex(),
+			Standard:        web3.StandardERC1155,
+			TokenID:         ids[i].String(),
+			Balance:         balance.String(),
+			TokenURI:        uri,
+		})
+	}
+
+	return nfts, nil
+}
+
+// GetNFTMetadataURI returns the metadata URI of a token, detecting the
+// collection's standard through ERC-165.
+func (s *Service) GetNFTMetadataURI(ctx context.Context, contract string, tokenId string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if !common.IsHexAddress(contract) {
+		return "", fmt.Errorf("invalid contract address")
+	}
+	id, ok := new(big.Int).SetString(tokenId, 0)
+	if !ok {
+		return "", fmt.Errorf("invalid token id: %s", tokenId)
+	}
+
+	contractAddress := common.HexToAddress(contract)
+	standard, err := s.detectNFTStandard(ctx, contractAddress)
+	if err != nil {
+		return "", err
+	}
+
+	return s.tokenURI(ctx, contractAddress, standard, id)
+}
+
+// tokenURI reads the metadata URI of a token. For ERC-1155 the {id}
+// placeholder is substituted as described in the standard.
+func (s *Service) tokenURI(ctx context.Context, contract common.Address, standard string, tokenId *big.Int) (string, error) {
+	method := web3.ERC721TokenURI
+	if standard == web3.StandardERC1155 {
+		method = web3.ERC1155URI
+	}
+
+	outputs, err := s.callMethod(ctx, contract, method, tokenId)
+	if err != nil {
+		// The metadata extension is optional in both standards
+		return "", nil
+	}
+
+	uri := outputs[0].(string)
+	if standard == web3.StandardERC1155 {
+		uri = strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%064x", tokenId))
+	}
+	return uri, nil
+}
+
+// TransferNFT sends an ERC-721 or ERC-1155 token with safeTransferFrom, signed
+// with the sender wallet's KMS key.
+func (s *Service) TransferNFT(ctx context.Context, request models.NFTTransferRequest, userId string) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	if !common.IsHexAddress(request.FromAddress) || !common.IsHexAddress(request.ToAddress) || !common.IsHexAddress(request.ContractAddress) {
+		return models.TransactionResult{}, fmt.Errorf("invalid address format")
+	}
+	tokenId, ok := new(big.Int).SetString(request.TokenID, 0)
+	if !ok {
+		return models.TransactionResult{}, fmt.Errorf("invalid token id: %s", request.TokenID)
+	}
+
+	var data []byte
+	if request.Data != "" {
+		var err error
+		data, err = hexutil.Decode(request.Data)
+		if err != nil {
+			return models.TransactionResult{}, fmt.Errorf("invalid data: %v", err)
+		}
+	}
+
+	fromAddress := common.HexToAddress(request.FromAddress)
+	toAddress := common.HexToAddress(request.ToAddress)
+	contractAddress := common.HexToAddress(request.ContractAddress)
+
+	standard := strings.ToLower(request.Standard)
+	if standard == "" {
+		var err error
+		standard, err = s.detectNFTStandard(ctx, contractAddress)
+		if err != nil {
+			return models.TransactionResult{}, err
+		}
+	}
+
+	var (
+		method abi.Method
+		args   []interface{}
+	)
+	switch standard {
+	case web3.StandardERC721:
+		method = web3.ERC721SafeTransferFrom
+		args = []interface{}{fromAddress, toAddress, tokenId, data}
+	case web3.StandardERC1155:
+		amount := big.NewInt(1)
+		if request.Amount != "" {
+			amount, ok = new(big.Int).SetString(request.Amount, 10)
+			if !ok || amount.Sign() <= 0 {
+				return models.TransactionResult{}, fmt.Errorf("invalid amount: %s", request.Amount)
+			}
+		}
+		method = web3.ERC1155SafeTransferFrom
+		args = []interface{}{fromAddress, toAddress, tokenId, amount, data}
+	default:
+		return models.TransactionResult{}, fmt.Errorf("unsupported token standard: %s", request.Standard)
+	}
+
+	calldata, err := web3.Pack(method, args...)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	result, _, err := s.signAndSend(ctx, outgoingTransaction{
+		From:   fromAddress,
+		To:     contractAddress,
+		Data:   calldata,
+		Method: method.Sig,
+		UserID: userId,
+	})
+	return result, err
+}
+
+// detectNFTStandard identifies an ERC-721 or ERC-1155 collection through ERC-165.
+func (s *Service) detectNFTStandard(ctx context.Context, contract common.Address) (string, error) {
+	isERC721, err := s.supportsInterface(ctx, contract, web3.InterfaceIDERC721)
+	if err != nil {
+		return "", err
+	}
+	if isERC721 {
+		return web3.StandardERC721, nil
+	}
+
+	isERC1155, err := s.supportsInterface(ctx, contract, web3.InterfaceIDERC1155)
+	if err != nil {
+		return "", err
+	}
+	if isERC1155 {
+		return web3.StandardERC1155, nil
+	}
+
+	return "", fmt.Errorf("contract %s is not an ERC-721 or ERC-1155 collection", contract.Hex())
+}
+
+func (s *Service) supportsInterface(ctx context.Context, contract common.Address, interfaceId [4]byte) (bool, error) {
+	outputs, err := s.callMethod(ctx, contract, web3.SupportsInterface, interfaceId)
+	if err != nil {
+		// Contracts without ERC-165 revert or return no data
+		return false, nil
+	}
+	return outputs[0].(bool), nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// ErrOIDCDisabled is returned by single sign-on when no identity provider is
+// configured.
+var ErrOIDCDisabled = errors.New("single sign-on is not configured")
+
+// oidcClaims are the ID token claims used to find or create the user.
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Nonce             string `json:"nonce"`
+}
+
+// BeginOIDCLogin starts an authorization code login with PKCE. The frontend
+// should keep the returned state and check that the provider sends it back
+// before posting the code to FinishOIDCLogin.
+func (s *Service) BeginOIDCLogin(ctx context.Context) (models.OIDCAuthorization, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if s.oidc == nil {
+		return models.OIDCAuthorization{}, ErrOIDCDisabled
+	}
+
+	state, err := generateToken()
+	if err != nil {
+		return models.OIDCAuthorization{}, err
+	}
+	nonce, err := generateToken()
+	if err != nil {
+		return models.OIDCAuthorization{}, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	err = s.repo.SaveOIDCState(ctx, &models.OIDCState{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().UTC().Add(oidcStateTTL),
+	})
+	if err != nil {
+		return models.OIDCAuthorization{}, err
+	}
+
+	return models.OIDCAuthorization{
+		AuthorizationURL: s.oidc.OAuth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:            state,
+	}, nil
+}
+
+// FinishOIDCLogin exchanges the authorization code, verifies the ID token and
+// signs in the linked user. Unknown identities are linked to the user with
+// the same verified email, or get a new account. Group memberships are mapped
+// to roles on every login. Users with TOTP enabled still need their second
+// factor.
+func (s *Service) FinishOIDCLogin(ctx context.Context, request models.OIDCCallbackRequest, clientIP string) (models.LoginResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	if s.oidc == nil {
+		return models.LoginResult{}, ErrOIDCDisabled
+	}
+
+	state, err := s.repo.TakeOIDCState(ctx, hashToken(request.State))
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+
+	token, err := s.oidc.OAuth2.Exchange(ctx, request.Code, oauth2.VerifierOption(state.CodeVerifier))
+	if err != nil {
+		return models.LoginResult{}, fmt.Errorf("failed to exchange authorization code: %v", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return models.LoginResult{}, fmt.Errorf("identity provider returned no ID token")
+	}
+	idToken, err := s.oidc.Verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return models.LoginResult{}, fmt.Errorf("invalid ID token: %v", err)
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return models.LoginResult{}, fmt.Errorf("invalid ID token claims: %v", err)
+	}
+	if claims.Nonce != state.Nonce {
+		return models.LoginResult{}, fmt.Errorf("invalid ID token nonce")
+	}
+	var allClaims map[string]interface{}
+	if err := idToken.Claims(&allClaims); err != nil {
+		return models.LoginResult{}, fmt.Errorf("invalid ID token claims: %v", err)
+	}
+
+	user, err := s.findOrCreateOIDCUser(ctx, idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	if err := s.applyOIDCGroups(ctx, user, claimStrings(allClaims[s.oidc.GroupsClaim])); err != nil {
+		return models.LoginResult{}, err
+	}
+
+	if user.TOTPEnabled {
+		mfaToken, err := s.issueMFAToken(user.ID)
+		if err != nil {
+			return models.LoginResult{}, err
+		}
+		return models.LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	s.auditLogin(ctx, user.ID, clientIP, "oidc")
+
+	tokens, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	return models.LoginResult{AuthTokens: &tokens}, nil
+}
+
+// findOrCreateOIDCUser returns the user linked to the identity, linking or
+// creating one by verified email the first time it signs in.
+func (s *Service) findOrCreateOIDCUser(ctx context.Context, issuer string, subject string, claims oidcClaims) (*models.User, error) {
+	if user, err := s.users.GetUserByOIDCSubject(ctx, issuer, subject); err == nil {
+		return user, nil
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, fmt.Errorf("identity provider did not return a verified email")
+	}
+
+	if user, err := s.users.GetUserByEmail(ctx, claims.Email); err == nil {
+		if user.OIDCSubject != "" {
+			return nil, fmt.Errorf("account is linked to another identity")
+		}
+		if err := s.users.LinkOIDCIdentity(ctx, user.ID, issuer, subject); err != nil {
+			return nil, err
+		}
+		// The provider verified the address, as the emailed link would have.
+		// The password was chosen by whoever signed up with the address before
+		// it was verified, so it is cleared; a reset sets a new one.
+		if user.Status == models.UserStatusPendingVerification {
+			if err := s.users.UpdatePassword(ctx, user.ID, ""); err != nil {
+				return nil, err
+			}
+			if err := s.users.SetUserStatus(ctx, user.ID, models.UserStatusActive); err != nil {
+				return nil, err
+			}
+		}
+		return s.users.GetUserByID(ctx, user.ID)
+	}
+
+	username := claims.PreferredUsername
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	now := time.Now()
+	user := &models.User{
+		Username:    username,
+		Email:       claims.Email,
+		CreatedAt:   now.Format(time.RFC3339),
+		UpdatedAt:   now.Format(time.RFC3339),
+		Status:      models.UserStatusActive,
+		OIDCIssuer:  issuer,
+		OIDCSubject: subject,
+	}
+	err := s.users.CreateUser(ctx, user)
+	if errors.Is(err, repositories.ErrUsernameTaken) {
+		// Keep the provider's username recognisable when it is taken
+		suffix, err := generateToken()
+		if err != nil {
+			return nil, err
+		}
+		user.Username = username + "-" + strings.ToLower(suffix[:6])
+		err = s.users.CreateUser(ctx, user)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.users.GetUserByOIDCSubject(ctx, issuer, subject)
+}
+
+// applyOIDCGroups gives the user the highest role any of their groups maps to
+// in each mapped organisation, removing them from mapped organisations none
+// of their groups grant. The last owner of an organisation is left in place.
+// Administrator status follows the admin groups when any are configured.
+func (s *Service) applyOIDCGroups(ctx context.Context, user *models.User, groups []string) error {
+	if len(s.oidc.AdminGroups) > 0 {
+		isAdmin := slices.ContainsFunc(groups, func(group string) bool {
+			return slices.Contains(s.oidc.AdminGroups, group)
+		})
+		if isAdmin != user.IsAdmin {
+			if err := s.users.SetUserAdmin(ctx, user.ID, isAdmin); err != nil {
+				return err
+			}
+			s.audit(ctx, models.AuditEntry{
+				Action:  models.AuditAdminChanged,
+				Target:  user.ID,
+				Details: map[string]string{"is_admin": strconv.FormatBool(isAdmin), "source": "oidc"},
+			})
+			user.IsAdmin = isAdmin
+		}
+	}
+	if len(s.oidc.GroupRoles) == 0 {
+		return nil
+	}
+
+	// The most privileged role granted in each mapped organisation
+	granted := map[string]string{}
+	for _, mapping := range s.oidc.GroupRoles {
+		if _, ok := granted[mapping.OrganisationID]; !ok {
+			granted[mapping.OrganisationID] = ""
+		}
+		if !slices.Contains(groups, mapping.Group) {
+			continue
+		}
+		current := granted[mapping.OrganisationID]
+		if current == "" || slices.Index(models.OrganisationRoles, mapping.Role) < slices.Index(models.OrganisationRoles, current) {
+			granted[mapping.OrganisationID] = mapping.Role
+		}
+	}
+
+	memberships, err := s.repo.ListMemberships(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	roles := map[string]string{}
+	for _, membership := range memberships {
+		roles[membership.OrganisationID] = membership.Role
+	}
+
+	for organisationId, role := range granted {
+		current, isMember := roles[organisationId]
+		if current == role {
+			continue
+		}
+		if current == models.RoleOwner {
+			if err := s.checkNotLastOwner(ctx, organisationId); err != nil {
+				log.Printf("Keeping last owner %s of organisation %s despite group mapping", user.ID, organisationId)
+				continue
+			}
+		}
+
+		action := models.AuditMemberAdded
+		switch {
+		case role == "":
+			action = models.AuditMemberRemoved
+			err = s.repo.DeleteOrganisationMember(ctx, organisationId, user.ID)
+		case isMember:
+			action = models.AuditMemberRoleChanged
+			err = s.repo.UpdateOrganisationMemberRole(ctx, organisationId, user.ID, role)
+		default:
+			_, err = s.repo.SaveOrganisationMember(ctx, &models.OrganisationMember{
+				OrganisationID: organisationId,
+				UserID:         user.ID,
+				Username:       user.Username,
+				Role:           role,
+				CreatedAt:      time.Now().UTC(),
+			})
+		}
+		if err != nil {
+			return err
+		}
+		// Changed by the identity provider, so there is no acting user
+		s.auditMemberChange(ctx, action, "", organisationId, user.ID, role)
+	}
+	return nil
+}
+
+// claimStrings reads a claim holding a string or a list of strings.
+func claimStrings(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
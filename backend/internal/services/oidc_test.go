@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/memory"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testOIDCClientID = "wallet"
+
+// mockIdP is an OpenID provider that authorizes every request it is shown
+// and checks PKCE when the code is exchanged.
+type mockIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+// mockAuthorization is what the token endpoint returns for an issued code.
+type mockAuthorization struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/keys",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	authorization, ok := idp.codes[r.FormValue("code")]
+	delete(idp.codes, r.FormValue("code"))
+	idp.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || r.FormValue("grant_type") != "authorization_code" ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, authorization.claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+// authorize plays the user signing in at the authorization URL and returns
+// the code the provider redirects back with. The ID token gets the claims,
+// over the standard ones and the nonce from the URL.
+func (idp *mockIdP) authorize(t *testing.T, authorizationURL string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL %s does not use PKCE", authorizationURL)
+	}
+
+	now := time.Now()
+	idClaims := jwt.MapClaims{
+		"iss":   idp.URL,
+		"aud":   testOIDCClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for name, value := range claims {
+		idClaims[name] = value
+	}
+
+	code, err := generateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), claims: idClaims}
+	idp.mu.Unlock()
+	return code
+}
+
+// newOIDCTestService returns a service signing in through the identity
+// provider.
+func newOIDCTestService(t *testing.T, idp *mockIdP, settings config.OIDC) (*Service, *memory.Store) {
+	t.Helper()
+
+	settings.IssuerURL = idp.URL
+	settings.ClientID = testOIDCClientID
+	settings.ClientSecret = "secret"
+	settings.RedirectURL = "http://localhost:3000/sso/callback"
+	settings.Scopes = []string{"openid", "email", "profile"}
+	settings.GroupsClaim = "groups"
+	provider, err := settings.Provider()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service, store := newTestService(t)
+	service.oidc = provider
+	return service, store
+}
+
+// oidcLogin signs in through the identity provider with the claims.
+func oidcLogin(t *testing.T, service *Service, idp *mockIdP, claims jwt.MapClaims) (models.LoginResult, error) {
+	t.Helper()
+
+	authorization, err := service.BeginOIDCLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := idp.authorize(t, authorization.AuthorizationURL, claims)
+	return service.FinishOIDCLogin(context.Background(), models.OIDCCallbackRequest{Code: code, State: authorization.State}, "127.0.0.1")
+}
+
+func aliceClaims(groups ...string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":                "alice-subject",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+		"groups":             groups,
+	}
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	idp := newMockIdP(t)
+	service, _ := newOIDCTestService(t, idp, config.OIDC{})
+
+	if endpoint := service.oidc.OAuth2.Endpoint; endpoint.AuthURL != idp.URL+"/authorize" || endpoint.TokenURL != idp.URL+"/token" {
+		t.Fatalf("discovered endpoint %+v", endpoint)
+	}
+
+	authorization, err := service.BeginOIDCLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(authorization.AuthorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("client_id") != testOIDCClientID || query.Get("state") != authorization.State || query.Get("nonce") == "" {
+		t.Fatalf("authorization URL %s", authorization.AuthorizationURL)
+	}
+}
+
+func TestOIDCLoginCreatesUser(t *testing.T) {
+	idp := newMockIdP(t)
+	service, store := newOIDCTestService(t, idp, config.OIDC{})
+
+	result, err := oidcLogin(t, service, idp, aliceClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AuthTokens == nil || result.Token == "" {
+		t.Fatalf("FinishOIDCLogin returned %+v, expected a session", result)
+	}
+
+	user, err := store.GetUserByOIDCSubject(context.Background(), idp.URL, "alice-subject")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "alice" || user.Email != "alice@example.com" || user.Status != models.UserStatusActive {
+		t.Fatalf("created user %+v", user)
+	}
+
+	// Signing in again finds the same user
+	if _, err := oidcLogin(t, service, idp, aliceClaims()); err != nil {
+		t.Fatal(err)
+	}
+	again, err := store.GetUserByOIDCSubject(context.Background(), idp.URL, "alice-subject")
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("second login found %+v, %v", again, err)
+	}
+}
+
+func TestOIDCLoginRejectsForgedResponses(t *testing.T) {
+	idp := newMockIdP(t)
+	service, store := newOIDCTestService(t, idp, config.OIDC{})
+	ctx := context.Background()
+
+	t.Run("unknown state", func(t *testing.T) {
+		authorization, err := service.BeginOIDCLogin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := idp.authorize(t, authorization.AuthorizationURL, aliceClaims())
+		_, err = service.FinishOIDCLogin(ctx, models.OIDCCallbackRequest{Code: code, State: "forged"}, "127.0.0.1")
+		if err == nil {
+			t.Fatal("expected a login with an unknown state to fail")
+		}
+	})
+
+	t.Run("reused state", func(t *testing.T) {
+		authorization, err := service.BeginOIDCLogin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := idp.authorize(t, authorization.AuthorizationURL, jwt.MapClaims{"sub": "bob-subject", "email": "bob@example.com", "email_verified": true})
+		request := models.OIDCCallbackRequest{Code: code, State: authorization.State}
+		if _, err := service.FinishOIDCLogin(ctx, request, "127.0.0.1"); err != nil {
+			t.Fatal(err)
+		}
+		request.Code = idp.authorize(t, authorization.AuthorizationURL, jwt.MapClaims{"sub": "bob-subject", "email": "bob@example.com", "email_verified": true})
+		if _, err := service.FinishOIDCLogin(ctx, request, "127.0.0.1"); err == nil {
+			t.Fatal("expected a second login with the same state to fail")
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		claims := aliceClaims()
+		claims["nonce"] = "replayed"
+		if _, err := oidcLogin(t, service, idp, claims); err == nil {
+			t.Fatal("expected an ID token with another nonce to be rejected")
+		}
+	})
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		authorization, err := service.BeginOIDCLogin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := idp.authorize(t, authorization.AuthorizationURL, aliceClaims())
+		idp.mu.Lock()
+		intercepted := idp.codes[code]
+		intercepted.challenge = base64.RawURLEncoding.EncodeToString(make([]byte, sha256.Size))
+		idp.codes[code] = intercepted
+		idp.mu.Unlock()
+
+		_, err = service.FinishOIDCLogin(ctx, models.OIDCCallbackRequest{Code: code, State: authorization.State}, "127.0.0.1")
+		if err == nil {
+			t.Fatal("expected the exchange to fail when the verifier does not match the challenge")
+		}
+	})
+
+	if _, err := store.GetUserByOIDCSubject(ctx, idp.URL, "alice-subject"); err == nil {
+		t.Fatal("a rejected login created a user")
+	}
+}
+
+func TestOIDCLinksPendingUserByEmail(t *testing.T) {
+	idp := newMockIdP(t)
+	service, store := newOIDCTestService(t, idp, config.OIDC{})
+	ctx := context.Background()
+
+	err := store.CreateUser(ctx, &models.User{
+		Username:     "alice",
+		Email:        "alice@example.com",
+		PasswordHash: "set-before-the-address-was-verified",
+		Status:       models.UserStatusPendingVerification,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := store.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := oidcLogin(t, service, idp, aliceClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AuthTokens == nil {
+		t.Fatalf("FinishOIDCLogin returned %+v, expected a session", result)
+	}
+
+	user, err := store.GetUserByID(ctx, pending.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.OIDCSubject != "alice-subject" || user.Status != models.UserStatusActive || user.PasswordHash != "" {
+		t.Fatalf("linked user %+v, expected it active, linked and without the unverified password", user)
+	}
+}
+
+func TestOIDCGroupRoles(t *testing.T) {
+	idp := newMockIdP(t)
+	service, store := newOIDCTestService(t, idp, config.OIDC{
+		GroupRoles: []config.OIDCGroupRole{
+			{Group: "engineering", OrganisationID: "org1", Role: models.RoleOperator},
+			{Group: "leads", OrganisationID: "org1", Role: models.RoleAdmin},
+			{Group: "finance", OrganisationID: "org2", Role: models.RoleViewer},
+		},
+		AdminGroups: []string{"wallet-admins"},
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		groups  []string
+		roles   map[string]string
+		isAdmin bool
+	}{
+		{[]string{"engineering", "leads", "wallet-admins"}, map[string]string{"org1": models.RoleAdmin}, true},
+		{[]string{"engineering", "finance"}, map[string]string{"org1": models.RoleOperator, "org2": models.RoleViewer}, false},
+		{[]string{"marketing"}, map[string]string{}, false},
+	}
+	for _, test := range tests {
+		if _, err := oidcLogin(t, service, idp, aliceClaims(test.groups...)); err != nil {
+			t.Fatal(err)
+		}
+		user, err := store.GetUserByOIDCSubject(ctx, idp.URL, "alice-subject")
+		if err != nil {
+			t.Fatal(err)
+		}
+		memberships, err := store.ListMemberships(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roles := map[string]string{}
+		for _, membership := range memberships {
+			roles[membership.OrganisationID] = membership.Role
+		}
+
+		if len(roles) != len(test.roles) {
+			t.Errorf("groups %v gave roles %v, expected %v", test.groups, roles, test.roles)
+		}
+		for organisationId, role := range test.roles {
+			if roles[organisationId] != role {
+				t.Errorf("groups %v gave roles %v, expected %v", test.groups, roles, test.roles)
+			}
+		}
+		if user.IsAdmin != test.isAdmin {
+			t.Errorf("groups %v made the user admin %v, expected %v", test.groups, user.IsAdmin, test.isAdmin)
+		}
+	}
+}
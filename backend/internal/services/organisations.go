@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+// CreateOrganisation creates an organisation with the user as its owner.
+func (s *Service) CreateOrganisation(ctx context.Context, name string, userId string) (models.Organisation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return models.Organisation{}, err
+	}
+
+	organisation, err := s.repo.SaveOrganisation(ctx, &models.Organisation{
+		Name:      name,
+		CreatedBy: userId,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return models.Organisation{}, err
+	}
+
+	_, err = s.repo.SaveOrganisationMember(ctx, &models.OrganisationMember{
+		OrganisationID: organisation.ID,
+		UserID:         userId,
+		Username:       user.Username,
+		Role:           models.RoleOwner,
+		CreatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return models.Organisation{}, err
+	}
+
+	s.audit(ctx, models.AuditEntry{Action: models.AuditOrganisationCreated, UserID: userId, Target: organisation.ID})
+
+	organisation.Role = models.RoleOwner
+	return organisation, nil
+}
+
+// ListOrganisations lists the organisations the user is a member of, with
+// the user's role in each.
+func (s *Service) ListOrganisations(ctx context.Context, userId string) ([]models.Organisation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	memberships, err := s.repo.ListMemberships(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := map[string]string{}
+	ids := make([]string, len(memberships))
+	for i, membership := range memberships {
+		ids[i] = membership.OrganisationID
+		roles[membership.OrganisationID] = membership.Role
+	}
+
+	organisations, err := s.repo.ListOrganisations(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range organisations {
+		organisations[i].Role = roles[organisations[i].ID]
+	}
+	return organisations, nil
+}
+
+func (s *Service) GetOrganisation(ctx context.Context, id string, userId string) (models.Organisation, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	member, err := s.authorizeOrganisation(ctx, id, userId, PermissionViewOrganisation)
+	if err != nil {
+		return models.Organisation{}, err
+	}
+
+	organisation, err := s.repo.GetOrganisation(ctx, id)
+	if err != nil {
+		return models.Organisation{}, err
+	}
+	organisation.Role = member.Role
+	return organisation, nil
+}
+
+func (s *Service) ListOrganisationMembers(ctx context.Context, id string, userId string) ([]models.OrganisationMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.authorizeOrganisation(ctx, id, userId, PermissionViewOrganisation); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListOrganisationMembers(ctx, id)
+}
+
+// AddOrganisationMember adds a user by username with the given role.
+func (s *Service) AddOrganisationMember(ctx context.Context, id string, request models.OrganisationMemberRequest, userId string) (models.OrganisationMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	actor, err := s.authorizeOrganisation(ctx, id, userId, PermissionManageMembers)
+	if err != nil {
+		return models.OrganisationMember{}, err
+	}
+	if err := checkRoleChange(actor, "", request.Role); err != nil {
+		return models.OrganisationMember{}, err
+	}
+
+	user, err := s.users.GetUserByUsername(ctx, request.Username)
+	if err != nil {
+		return models.OrganisationMember{}, fmt.Errorf("user not found: %s", request.Username)
+	}
+
+	member, err := s.repo.SaveOrganisationMember(ctx, &models.OrganisationMember{
+		OrganisationID: id,
+		UserID:         user.ID,
+		Username:       user.Username,
+		Role:           request.Role,
+		CreatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return models.OrganisationMember{}, err
+	}
+	s.auditMemberChange(ctx, models.AuditMemberAdded, userId, id, user.ID, request.Role)
+	return member, nil
+}
+
+// UpdateOrganisationMember changes a member's role.
+func (s *Service) UpdateOrganisationMember(ctx context.Context, id string, memberId string, role string, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	actor, err := s.authorizeOrganisation(ctx, id, userId, PermissionManageMembers)
+	if err != nil {
+		return err
+	}
+	member, err := s.repo.GetOrganisationMember(ctx, id, memberId)
+	if err != nil {
+		return fmt.Errorf("member not found")
+	}
+	if err := checkRoleChange(actor, member.Role, role); err != nil {
+		return err
+	}
+	if member.Role == models.RoleOwner && role != models.RoleOwner {
+		if err := s.checkNotLastOwner(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.UpdateOrganisationMemberRole(ctx, id, memberId, role); err != nil {
+		return err
+	}
+	s.auditMemberChange(ctx, models.AuditMemberRoleChanged, userId, id, memberId, role)
+	return nil
+}
+
+// RemoveOrganisationMember removes a member. Members may always leave.
+func (s *Service) RemoveOrganisationMember(ctx context.Context, id string, memberId string, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	member, err := s.repo.GetOrganisationMember(ctx, id, memberId)
+	if err != nil {
+		return fmt.Errorf("member not found")
+	}
+
+	if memberId != userId {
+		actor, err := s.authorizeOrganisation(ctx, id, userId, PermissionManageMembers)
+		if err != nil {
+			return err
+		}
+		if err := checkRoleChange(actor, member.Role, ""); err != nil {
+			return err
+		}
+	}
+	if member.Role == models.RoleOwner {
+		if err := s.checkNotLastOwner(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.DeleteOrganisationMember(ctx, id, memberId); err != nil {
+		return err
+	}
+	s.auditMemberChange(ctx, models.AuditMemberRemoved, userId, id, memberId, "")
+	return nil
+}
+
+// auditMemberChange records a change to an organisation's members, which
+// changes who may use its wallets.
+func (s *Service) auditMemberChange(ctx context.Context, action string, userId string, organisationId string, memberId string, role string) {
+	details := map[string]string{"member": memberId}
+	if role != "" {
+		details["role"] = role
+	}
+	s.audit(ctx, models.AuditEntry{Action: action, UserID: userId, Target: organisationId, Details: details})
+}
+
+// checkRoleChange validates a role and checks that only owners grant or take
+// away the owner role.
+func checkRoleChange(actor models.OrganisationMember, from string, to string) error {
+	if _, ok := rolePermissions[to]; to != "" && !ok {
+		return fmt.Errorf("unknown role: %s", to)
+	}
+	if (from == models.RoleOwner || to == models.RoleOwner) && actor.Role != models.RoleOwner {
+		return fmt.Errorf("%w: only owners can manage owners", ErrPermissionDenied)
+	}
+	return nil
+}
+
+func (s *Service) checkNotLastOwner(ctx context.Context, id string) error {
+	members, err := s.repo.ListOrganisationMembers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	owners := 0
+	for _, member := range members {
+		if member.Role == models.RoleOwner {
+			owners++
+		}
+	}
+	if owners <= 1 {
+		return fmt.Errorf("an organisation must keep at least one owner")
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+// Permission is an action a user may be allowed to take on a wallet or an
+// organisation.
+type Permission string
+
+const (
+	PermissionViewWallet       Permission = "wallet:view"
+	PermissionTransact         Permission = "wallet:transact"
+	PermissionSignMessages     Permission = "wallet:sign"
+	PermissionApprove          Permission = "wallet:approve"
+	PermissionManageWallets    Permission = "wallet:manage"
+	PermissionManageMembers    Permission = "organisation:manage"
+	PermissionViewOrganisation Permission = "organisation:view"
+)
+
+// rolePermissions maps organisation roles to what they allow. Owners and
+// admins differ only in that admins cannot manage owners.
+var rolePermissions = map[string][]Permission{
+	models.RoleOwner: {
+		PermissionViewOrganisation, PermissionViewWallet, PermissionTransact, PermissionSignMessages,
+		PermissionApprove, PermissionManageWallets, PermissionManageMembers,
+	},
+	models.RoleAdmin: {
+		PermissionViewOrganisation, PermissionViewWallet, PermissionTransact, PermissionSignMessages,
+		PermissionApprove, PermissionManageWallets, PermissionManageMembers,
+	},
+	models.RoleOperator: {PermissionViewOrganisation, PermissionViewWallet, PermissionTransact, PermissionSignMessages},
+	models.RoleApprover: {PermissionViewOrganisation, PermissionViewWallet, PermissionApprove},
+	models.RoleViewer:   {PermissionViewOrganisation, PermissionViewWallet},
+}
+
+// ErrPermissionDenied is returned when a user lacks the permission for an action.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// authorizeWallet loads a wallet and checks that the user may act on it.
+// Personal wallets allow everything to their owner; organisation wallets
+// allow what the user's role grants.
+func (s *Service) authorizeWallet(ctx context.Context, address string, userId string, permission Permission) (models.Wallet, error) {
+	wallet, err := s.wallets.GetWallet(ctx, address)
+	if err != nil {
+		return models.Wallet{}, err
+	}
+
+	if wallet.OrganisationID == "" {
+		if wallet.UserID != userId {
+			// Do not reveal other users' wallets
+			return models.Wallet{}, fmt.Errorf("failed to find wallet: %s", address)
+		}
+		return wallet, nil
+	}
+
+	if _, err := s.authorizeOrganisation(ctx, wallet.OrganisationID, userId, permission); err != nil {
+		return models.Wallet{}, err
+	}
+	return wallet, nil
+}
+
+// authorizeOrganisation checks that the user is a member of the organisation
+// whose role grants the permission, and returns the membership.
+func (s *Service) authorizeOrganisation(ctx context.Context, organisationId string, userId string, permission Permission) (models.OrganisationMember, error) {
+	member, err := s.repo.GetOrganisationMember(ctx, organisationId, userId)
+	if err != nil {
+		return models.OrganisationMember{}, fmt.Errorf("%w: not a member of the organisation", ErrPermissionDenied)
+	}
+	if !slices.Contains(rolePermissions[member.Role], permission) {
+		return models.OrganisationMember{}, fmt.Errorf("%w: %s role cannot %s", ErrPermissionDenied, member.Role, permission)
+	}
+	return member, nil
+}
+
+// accessibleWallets returns the user's personal wallets and the wallets of
+// every organisation the user belongs to.
+func (s *Service) accessibleWallets(ctx context.Context, userId string) ([]models.Wallet, error) {
+	memberships, err := s.repo.ListMemberships(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	organisationIds := make([]string, len(memberships))
+	for i, membership := range memberships {
+		organisationIds[i] = membership.OrganisationID
+	}
+
+	return s.wallets.ListWallets(ctx, userId, organisationIds)
+}
+
+// authorizeSafe loads a Safe and checks that the user holds the permission on
+// at least one of its owner wallets, personally or through an organisation.
+func (s *Service) authorizeSafe(ctx context.Context, address string, userId string, permission Permission) (models.SafeWallet, error) {
+	safe, err := s.repo.GetSafe(ctx, address)
+	if err != nil {
+		return models.SafeWallet{}, err
+	}
+
+	denied := fmt.Errorf("failed to find safe: %s", address)
+	for _, owner := range safe.Owners {
+		_, err := s.authorizeWallet(ctx, owner, userId, permission)
+		if err == nil {
+			return safe, nil
+		}
+		if errors.Is(err, ErrPermissionDenied) {
+			denied = err
+		}
+	}
+	return models.SafeWallet{}, denied
+}
+
+// authorizeSmartAccount loads a smart account and checks that the user holds
+// the permission on its owner wallet, which it also returns.
+func (s *Service) authorizeSmartAccount(ctx context.Context, address string, userId string, permission Permission) (models.SmartAccount, models.Wallet, error) {
+	account, err := s.repo.GetSmartAccount(ctx, address)
+	if err != nil {
+		return models.SmartAccount{}, models.Wallet{}, err
+	}
+
+	owner, err := s.authorizeWallet(ctx, account.OwnerAddress, userId, permission)
+	if err != nil {
+		if errors.Is(err, ErrPermissionDenied) {
+			return models.SmartAccount{}, models.Wallet{}, err
+		}
+		return models.SmartAccount{}, models.Wallet{}, fmt.Errorf("failed to find smart account: %s", address)
+	}
+	return account, owner, nil
+}
+
+// authorizeSchedule loads a schedule and checks that the user holds the
+// permission on the wallet it sends from.
+func (s *Service) authorizeSchedule(ctx context.Context, id string, userId string, permission Permission) (models.Schedule, error) {
+	schedule, err := s.repo.GetSchedule(ctx, id)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	if _, err := s.authorizeWallet(ctx, schedule.FromAddress, userId, permission); err != nil {
+		if errors.Is(err, ErrPermissionDenied) {
+			return models.Schedule{}, err
+		}
+		return models.Schedule{}, fmt.Errorf("failed to find schedule: %s", id)
+	}
+	return schedule, nil
+}
+
+// walletAddresses returns the addresses of the wallets.
+func walletAddresses(wallets []models.Wallet) []string {
+	addresses := make([]string, len(wallets))
+	for i, wallet := range wallets {
+		addresses[i] = wallet.PublicKey
+	}
+	return addresses
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+var allPermissions = []Permission{
+	PermissionViewOrganisation, PermissionViewWallet, PermissionTransact, PermissionSignMessages,
+	PermissionApprove, PermissionManageWallets, PermissionManageMembers,
+}
+
+// roleGrants is what each role is expected to allow, written out rather
+// than read from rolePermissions.
+var roleGrants = map[string]map[Permission]bool{
+	models.RoleOwner: {
+		PermissionViewOrganisation: true, PermissionViewWallet: true, PermissionTransact: true, PermissionSignMessages: true,
+		PermissionApprove: true, PermissionManageWallets: true, PermissionManageMembers: true,
+	},
+	models.RoleAdmin: {
+		PermissionViewOrganisation: true, PermissionViewWallet: true, PermissionTransact: true, PermissionSignMessages: true,
+		PermissionApprove: true, PermissionManageWallets: true, PermissionManageMembers: true,
+	},
+	models.RoleOperator: {
+		PermissionViewOrganisation: true, PermissionViewWallet: true, PermissionTransact: true, PermissionSignMessages: true,
+	},
+	models.RoleApprover: {
+		PermissionViewOrganisation: true, PermissionViewWallet: true, PermissionApprove: true,
+	},
+	models.RoleViewer: {
+		PermissionViewOrganisation: true, PermissionViewWallet: true,
+	},
+}
+
+func TestRolePermissions(t *testing.T) {
+	for _, role := range models.OrganisationRoles {
+		for _, permission := range allPermissions {
+			if got := slices.Contains(rolePermissions[role], permission); got != roleGrants[role][permission] {
+				t.Errorf("%s %s: granted %v, expected %v", role, permission, got, roleGrants[role][permission])
+			}
+		}
+	}
+}
+
+func TestAuthorizeByRole(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	outsider := createTestUser(t, store, "outsider")
+
+	const organisationId = "org1"
+	for i, role := range models.OrganisationRoles {
+		user := createTestUser(t, store, role)
+		_, err := store.SaveOrganisationMember(ctx, &models.OrganisationMember{OrganisationID: organisationId, UserID: user.ID, Username: user.Username, Role: role})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wallet := saveTestWallet(t, store, fmt.Sprintf("0x%040x", i+1), "", organisationId)
+
+		for _, permission := range allPermissions {
+			want := roleGrants[role][permission]
+
+			_, err := service.authorizeOrganisation(ctx, organisationId, user.ID, permission)
+			if (err == nil) != want || (err != nil && !errors.Is(err, ErrPermissionDenied)) {
+				t.Errorf("authorizeOrganisation %s %s: %v, expected allowed %v", role, permission, err, want)
+			}
+
+			_, err = service.authorizeWallet(ctx, wallet.PublicKey, user.ID, permission)
+			if (err == nil) != want || (err != nil && !errors.Is(err, ErrPermissionDenied)) {
+				t.Errorf("authorizeWallet %s %s: %v, expected allowed %v", role, permission, err, want)
+			}
+
+			// Users outside the organisation get nothing on its wallets
+			if _, err := service.authorizeWallet(ctx, wallet.PublicKey, outsider.ID, permission); !errors.Is(err, ErrPermissionDenied) {
+				t.Errorf("authorizeWallet outsider %s: %v, expected permission denied", permission, err)
+			}
+		}
+	}
+}
+
+func TestAuthorizePersonalWallet(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	owner := createTestUser(t, store, "alice")
+	other := createTestUser(t, store, "bob")
+	wallet := saveTestWallet(t, store, "0x1111111111111111111111111111111111111111", owner.ID, "")
+
+	for _, permission := range allPermissions {
+		if _, err := service.authorizeWallet(ctx, wallet.PublicKey, owner.ID, permission); err != nil {
+			t.Errorf("owner %s: %v", permission, err)
+		}
+		// Other users' personal wallets look missing rather than forbidden
+		_, err := service.authorizeWallet(ctx, wallet.PublicKey, other.ID, permission)
+		if err == nil || errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("other user %s: %v, expected the wallet not to be found", permission, err)
+		}
+	}
+}
+
+func TestCreateSafeNeedsTransactOnOwners(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	approver := createTestUser(t, store, "approver")
+	_, err := store.SaveOrganisationMember(ctx, &models.OrganisationMember{OrganisationID: "org1", UserID: approver.ID, Username: approver.Username, Role: models.RoleApprover})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := saveTestWallet(t, store, "0x1111111111111111111111111111111111111111", "", "org1")
+
+	_, err = service.CreateSafe(ctx, models.SafeCreateRequest{Owners: []string{wallet.PublicKey}, Threshold: 1}, approver.ID)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("CreateSafe by an approver returned %v, expected permission denied", err)
+	}
+}
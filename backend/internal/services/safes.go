@@ -0,0 +1,472 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/utils"
+	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// CreateSafe deploys a Safe proxy whose owners are KMS wallets of the user.
+func (s *Service) CreateSafe(ctx context.Context, request models.SafeCreateRequest, userId string) (models.SafeWallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	if len(request.Owners) == 0 {
+		return models.SafeWallet{}, fmt.Errorf("at least one owner is required")
+	}
+	if request.Threshold == 0 || request.Threshold > uint64(len(request.Owners)) {
+		return models.SafeWallet{}, fmt.Errorf("threshold must be between 1 and the number of owners")
+	}
+
+	owners := make([]common.Address, 0, len(request.Owners))
+	seen := map[common.Address]bool{}
+	for _, owner := range request.Owners {
+		if !common.IsHexAddress(owner) {
+			return models.SafeWallet{}, fmt.Errorf("invalid owner address: %s", owner)
+		}
+		address := common.HexToAddress(owner)
+		if seen[address] {
+			return models.SafeWallet{}, fmt.Errorf("duplicate owner: %s", address.Hex())
+		}
+		seen[address] = true
+
+		// Every owner must be a KMS wallet that can sign through the service.
+		// Making a wallet a Safe owner lets it move the Safe's funds, so only
+		// users who may transact from the wallet can do it, not approvers.
+		if _, err := s.authorizeWallet(ctx, address.Hex(), userId, PermissionTransact); err != nil {
+			if errors.Is(err, ErrPermissionDenied) {
+				return models.SafeWallet{}, fmt.Errorf("owner %s: %w", address.Hex(), err)
+			}
+			return models.SafeWallet{}, fmt.Errorf("owner %s is not one of your wallets", address.Hex())
+		}
+		owners = append(owners, address)
+	}
+
+	fromAddress := owners[0]
+	if request.FromAddress != "" {
+		if !common.IsHexAddress(request.FromAddress) {
+			return models.SafeWallet{}, fmt.Errorf("invalid address format")
+		}
+		fromAddress = common.HexToAddress(request.FromAddress)
+	}
+
+	initializer, err := web3.Pack(web3.SafeSetup,
+		owners,
+		new(big.Int).SetUint64(request.Threshold),
+		common.Address{},
+		[]byte{},
+		s.safe.FallbackHandler,
+		common.Address{},
+		new(big.Int),
+		common.Address{},
+	)
+	if err != nil {
+		return models.SafeWallet{}, err
+	}
+
+	saltNonce := big.NewInt(time.Now().UnixNano())
+	data, err := web3.Pack(web3.SafeCreateProxyWithNonce, s.safe.Singleton, initializer, saltNonce)
+	if err != nil {
+		return models.SafeWallet{}, err
+	}
+
+	result, receipt, err := s.signAndSend(ctx, outgoingTransaction{
+		From:   fromAddress,
+		To:     s.safe.ProxyFactory,
+		Data:   data,
+		Method: web3.SafeCreateProxyWithNonce.Sig,
+		UserID: userId,
+	})
+	if err != nil {
+		return models.SafeWallet{}, err
+	}
+	if result.Status != models.TransactionStatusSuccess {
+		return models.SafeWallet{}, fmt.Errorf("safe deployment reverted: %s", result.TransactionHash)
+	}
+
+	proxy, err := s.proxyFromReceipt(receipt)
+	if err != nil {
+		return models.SafeWallet{}, err
+	}
+
+	ownerHexes := make([]string, len(owners))
+	for i, owner := range owners {
+		ownerHexes[i] = owner.Hex()
+	}
+
+	safe := models.SafeWallet{
+		Name:             request.Name,
+		Address:          proxy.Hex(),
+		Owners:           ownerHexes,
+		Threshold:        request.Threshold,
+		Balance:          "0",
+		DeploymentTxHash: result.TransactionHash,
+		UserID:           userId,
+		CreatedAt:        time.Now().UTC(),
+	}
+
+	return s.repo.SaveSafe(ctx, &safe)
+}
+
+// proxyFromReceipt extracts the new Safe address from the factory's
+// ProxyCreation event. The proxy is indexed since v1.4.0 and part of the
+// event data before.
+func (s *Service) proxyFromReceipt(receipt *ethereumTypes.Receipt) (common.Address, error) {
+	for _, log := range receipt.Logs {
+		if log.Address != s.safe.ProxyFactory || len(log.Topics) == 0 || log.Topics[0] != web3.ProxyCreationTopic {
+			continue
+		}
+		if len(log.Topics) > 1 {
+			return common.BytesToAddress(log.Topics[1].Bytes()), nil
+		}
+		if len(log.Data) >= 32 {
+			return common.BytesToAddress(log.Data[:32]), nil
+		}
+	}
+	return common.Address{}, fmt.Errorf("ProxyCreation event not found in receipt")
+}
+
+func (s *Service) ListSafes(ctx context.Context, userId string) ([]models.SafeWallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// A Safe is listed when the user can see one of its owner wallets
+	wallets, err := s.accessibleWallets(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	safes, err := s.repo.ListSafes(ctx, walletAddresses(wallets))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, safe := range safes {
+		balance, err := s.web3Client.BalanceAt(ctx, common.HexToAddress(safe.Address), nil)
+		if err != nil {
+			return nil, err
+		}
+		safes[i].Balance = fmt.Sprintf("%f ETH", utils.WeiToEther(balance))
+	}
+
+	return safes, nil
+}
+
+func (s *Service) GetSafe(ctx context.Context, address string, userId string) (models.SafeWallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	safe, err := s.authorizeSafe(ctx, address, userId, PermissionViewWallet)
+	if err != nil {
+		return safe, err
+	}
+
+	balance, err := s.web3Client.BalanceAt(ctx, common.HexToAddress(safe.Address), nil)
+	if err != nil {
+		return safe, err
+	}
+	safe.Balance = fmt.Sprintf("%f ETH", utils.WeiToEther(balance))
+
+	return safe, nil
+}
+
+func (s *Service) ListSafeTransactions(ctx context.Context, address string, userId string) ([]models.SafeTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	safe, err := s.authorizeSafe(ctx, address, userId, PermissionViewWallet)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListSafeTransactions(ctx, safe.Address)
+}
+
+// ProposeSafeTransaction records a Safe transaction and its hash so owners can sign it.
+func (s *Service) ProposeSafeTransaction(ctx context.Context, address string, request models.SafeTransactionRequest, userId string) (models.SafeTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	safe, err := s.authorizeSafe(ctx, address, userId, PermissionTransact)
+	if err != nil {
+		return models.SafeTransaction{}, err
+	}
+	safeAddress := common.HexToAddress(safe.Address)
+
+	if !common.IsHexAddress(request.To) {
+		return models.SafeTransaction{}, fmt.Errorf("invalid address format")
+	}
+	if request.Operation > web3.SafeOperationDelegateCall {
+		return models.SafeTransaction{}, fmt.Errorf("invalid operation: %d", request.Operation)
+	}
+
+	value := new(big.Int)
+	if request.Value != "" {
+		var ok bool
+		if value, ok = value.SetString(request.Value, 10); !ok {
+			return models.SafeTransaction{}, fmt.Errorf("invalid value: %s", request.Value)
+		}
+	}
+
+	data := []byte{}
+	if request.Data != "" {
+		if data, err = hexutil.Decode(request.Data); err != nil {
+			return models.SafeTransaction{}, fmt.Errorf("invalid data: %v", err)
+		}
+	}
+
+	var nonce *big.Int
+	if request.Nonce != "" {
+		var ok bool
+		if nonce, ok = new(big.Int).SetString(request.Nonce, 10); !ok {
+			return models.SafeTransaction{}, fmt.Errorf("invalid nonce: %s", request.Nonce)
+		}
+	} else {
+		outputs, err := s.callMethod(ctx, safeAddress, web3.SafeNonce)
+		if err != nil {
+			return models.SafeTransaction{}, err
+		}
+		nonce = outputs[0].(*big.Int)
+	}
+
+	to := common.HexToAddress(request.To)
+	outputs, err := s.callMethod(ctx, safeAddress, web3.SafeGetTransactionHash,
+		to, value, data, request.Operation,
+		new(big.Int), new(big.Int), new(big.Int), common.Address{}, common.Address{},
+		nonce,
+	)
+	if err != nil {
+		return models.SafeTransaction{}, err
+	}
+	safeTxHash := outputs[0].([32]byte)
+
+	transaction := models.SafeTransaction{
+		SafeAddress: safe.Address,
+		To:          to.Hex(),
+		Value:       value.String(),
+		Data:        hexutil.Encode(data),
+		Operation:   request.Operation,
+		Nonce:       nonce.String(),
+		SafeTxHash:  hexutil.Encode(safeTxHash[:]),
+		Signatures:  []models.SafeSignature{},
+		Status:      models.SafeTransactionProposed,
+		UserID:      userId,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	return s.repo.SaveSafeTransaction(ctx, &transaction)
+}
+
+// ConfirmSafeTransaction signs the Safe transaction hash with an owner's KMS key.
+func (s *Service) ConfirmSafeTransaction(ctx context.Context, address string, id string, ownerAddress string, userId string) (models.SafeTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	safe, err := s.authorizeSafe(ctx, address, userId, PermissionApprove)
+	if err != nil {
+		return models.SafeTransaction{}, err
+	}
+	transaction, err := s.repo.GetSafeTransaction(ctx, id, safe.Address)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Status != models.SafeTransactionProposed {
+		return transaction, fmt.Errorf("safe transaction is %s", transaction.Status)
+	}
+
+	if !common.IsHexAddress(ownerAddress) {
+		return transaction, fmt.Errorf("invalid address format")
+	}
+	owner := common.HexToAddress(ownerAddress).Hex()
+
+	isOwner := false
+	for _, o := range safe.Owners {
+		isOwner = isOwner || o == owner
+	}
+	if !isOwner {
+		return transaction, fmt.Errorf("%s is not an owner of the safe", owner)
+	}
+
+	wallet, err := s.authorizeWallet(ctx, owner, userId, PermissionApprove)
+	if err != nil {
+		return transaction, err
+	}
+
+	hash, err := hexutil.Decode(transaction.SafeTxHash)
+	if err != nil {
+		return transaction, err
+	}
+
+	// Owners sign the Safe transaction hash directly (v = 27 or 28)
+	signature, err := s.signWithWallet(ctx, wallet, userId, hash, models.WebAuthnChallenge{})
+	if err != nil {
+		return transaction, err
+	}
+	signature[64] += 27
+	s.audit(ctx, models.AuditEntry{
+		Action:  models.AuditSafeTransactionSigned,
+		UserID:  userId,
+		Target:  transaction.SafeTxHash,
+		Details: map[string]string{"safe": safe.Address, "owner": owner},
+	})
+
+	err = s.repo.AddSafeSignature(ctx, transaction.ID, models.SafeSignature{
+		Owner:     owner,
+		Signature: hexutil.Encode(signature),
+	})
+	if err != nil {
+		return transaction, err
+	}
+
+	return s.repo.GetSafeTransaction(ctx, id, safe.Address)
+}
+
+// ExecuteSafeTransaction submits execTransaction once enough owners have signed.
+// The executor wallet pays the gas and defaults to the first signer. The
+// transaction is claimed before it is submitted, so concurrent calls cannot
+// submit it twice.
+func (s *Service) ExecuteSafeTransaction(ctx context.Context, address string, id string, executorAddress string, userId string) (models.SafeTransaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	safe, err := s.authorizeSafe(ctx, address, userId, PermissionTransact)
+	if err != nil {
+		return models.SafeTransaction{}, err
+	}
+	safeAddress := common.HexToAddress(safe.Address)
+
+	transaction, err := s.repo.GetSafeTransaction(ctx, id, safe.Address)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Status == models.SafeTransactionExecuting && transaction.ExecutionTxHash != "" {
+		// An earlier execution was submitted but its outcome not recorded
+		return s.finishSafeExecution(ctx, transaction)
+	}
+	if transaction.Status != models.SafeTransactionProposed {
+		return transaction, fmt.Errorf("safe transaction is %s", transaction.Status)
+	}
+
+	outputs, err := s.callMethod(ctx, safeAddress, web3.SafeGetThreshold)
+	if err != nil {
+		return transaction, err
+	}
+	threshold := outputs[0].(*big.Int)
+	if len(transaction.Signatures) == 0 || big.NewInt(int64(len(transaction.Signatures))).Cmp(threshold) < 0 {
+		return transaction, fmt.Errorf("%d of %s required signatures collected", len(transaction.Signatures), threshold)
+	}
+
+	// The Safe expects signatures sorted by owner address
+	signatures := append([]models.SafeSignature{}, transaction.Signatures...)
+	sort.Slice(signatures, func(i, j int) bool {
+		return bytes.Compare(common.HexToAddress(signatures[i].Owner).Bytes(), common.HexToAddress(signatures[j].Owner).Bytes()) < 0
+	})
+	var packedSignatures []byte
+	for _, signature := range signatures {
+		sig, err := hexutil.Decode(signature.Signature)
+		if err != nil {
+			return transaction, err
+		}
+		packedSignatures = append(packedSignatures, sig...)
+	}
+
+	value, ok := new(big.Int).SetString(transaction.Value, 10)
+	if !ok {
+		return transaction, fmt.Errorf("invalid value: %s", transaction.Value)
+	}
+	txData, err := hexutil.Decode(transaction.Data)
+	if err != nil {
+		return transaction, err
+	}
+
+	data, err := web3.Pack(web3.SafeExecTransaction,
+		common.HexToAddress(transaction.To), value, txData, transaction.Operation,
+		new(big.Int), new(big.Int), new(big.Int), common.Address{}, common.Address{},
+		packedSignatures,
+	)
+	if err != nil {
+		return transaction, err
+	}
+
+	executor := common.HexToAddress(signatures[0].Owner)
+	if executorAddress != "" {
+		if !common.IsHexAddress(executorAddress) {
+			return transaction, fmt.Errorf("invalid address format")
+		}
+		executor = common.HexToAddress(executorAddress)
+	}
+
+	if err := s.repo.ClaimSafeTransaction(ctx, transaction.ID); err != nil {
+		return transaction, fmt.Errorf("safe transaction is already being executed")
+	}
+
+	signed := false
+	result, _, err := s.signAndSend(ctx, outgoingTransaction{
+		From:   executor,
+		To:     safeAddress,
+		Data:   data,
+		Method: web3.SafeExecTransaction.Sig,
+		UserID: userId,
+		OnSigned: func(ctx context.Context, signedTx *ethereumTypes.Transaction) error {
+			if err := s.repo.SetSafeExecutionTxHash(ctx, transaction.ID, signedTx.Hash().Hex()); err != nil {
+				return err
+			}
+			signed = true
+			return nil
+		},
+	})
+	if err != nil {
+		// Nothing was broadcast, so the transaction can be executed again
+		if !signed {
+			if releaseErr := s.repo.ReleaseSafeTransaction(ctx, transaction.ID); releaseErr != nil {
+				log.Printf("Failed to release safe transaction %s: %v", transaction.ID, releaseErr)
+			}
+		}
+		return transaction, err
+	}
+
+	status := models.SafeTransactionExecuted
+	if result.Status != models.TransactionStatusSuccess {
+		status = models.SafeTransactionFailed
+	}
+	if err := s.repo.MarkSafeTransactionExecuted(ctx, transaction.ID, status, result.TransactionHash); err != nil {
+		return transaction, err
+	}
+
+	return s.repo.GetSafeTransaction(ctx, id, safe.Address)
+}
+
+// finishSafeExecution records the outcome of a Safe transaction whose
+// execution was submitted, once it has been mined.
+func (s *Service) finishSafeExecution(ctx context.Context, transaction models.SafeTransaction) (models.SafeTransaction, error) {
+	receipt, err := s.web3Client.TransactionReceipt(ctx, common.HexToHash(transaction.ExecutionTxHash))
+	if errors.Is(err, ethereum.NotFound) {
+		return transaction, fmt.Errorf("safe transaction is being executed in %s", transaction.ExecutionTxHash)
+	}
+	if err != nil {
+		return transaction, err
+	}
+
+	status := models.SafeTransactionExecuted
+	if receipt.Status != ethereumTypes.ReceiptStatusSuccessful {
+		status = models.SafeTransactionFailed
+	}
+	if err := s.repo.MarkSafeTransactionExecuted(ctx, transaction.ID, status, transaction.ExecutionTxHash); err != nil {
+		return transaction, err
+	}
+
+	return s.repo.GetSafeTransaction(ctx, transaction.ID, transaction.SafeAddress)
+}
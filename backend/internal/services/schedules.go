@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/tracing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// scheduleLease is how long a claimed schedule stays locked to one scheduler.
+// It must outlast the transaction timeout in runSchedule.
+const scheduleLease = 2 * time.Minute
+
+// ErrSchedulesNeedConfirmation is returned when a user who requires passkey
+// confirmation for transactions creates or resumes a schedule.
+var ErrSchedulesNeedConfirmation = errors.New("scheduled transfers cannot be confirmed with a passkey; turn off passkey confirmation to schedule transfers")
+
+func (s *Service) CreateSchedule(ctx context.Context, request models.ScheduleRequest, userId string) (models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if !common.IsHexAddress(request.FromAddress) || !common.IsHexAddress(request.ToAddress) {
+		return models.Schedule{}, fmt.Errorf("invalid address format")
+	}
+	if val, ok := new(big.Int).SetString(request.Value, 10); !ok || val.Sign() <= 0 {
+		return models.Schedule{}, fmt.Errorf("invalid value: %s", request.Value)
+	}
+
+	now := time.Now().UTC()
+	var nextRunAt time.Time
+	switch {
+	case request.RunAt != nil && request.Cron != "":
+		return models.Schedule{}, fmt.Errorf("runAt and cron are mutually exclusive")
+	case request.RunAt != nil:
+		if request.RunAt.Before(now) {
+			return models.Schedule{}, fmt.Errorf("runAt must be in the future")
+		}
+		nextRunAt = request.RunAt.UTC()
+	case request.Cron != "":
+		next, err := nextCronRun(request.Cron, now)
+		if err != nil {
+			return models.Schedule{}, err
+		}
+		nextRunAt = next
+	default:
+		return models.Schedule{}, fmt.Errorf("either runAt or cron is required")
+	}
+
+	// Make sure the source wallet belongs to the user
+	fromAddress := common.HexToAddress(request.FromAddress)
+	if _, err := s.authorizeWallet(ctx, fromAddress.Hex(), userId, PermissionTransact); err != nil {
+		return models.Schedule{}, err
+	}
+
+	// Scheduled transfers run unattended, so they cannot be confirmed with a
+	// passkey. Requiring confirmation also pauses existing schedules.
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	if user.RequireWebAuthnForTransactions {
+		return models.Schedule{}, ErrSchedulesNeedConfirmation
+	}
+
+	schedule := models.Schedule{
+		Name:        request.Name,
+		FromAddress: fromAddress.Hex(),
+		ToAddress:   common.HexToAddress(request.ToAddress).Hex(),
+		Value:       request.Value,
+		RunAt:       request.RunAt,
+		Cron:        request.Cron,
+		NextRunAt:   nextRunAt,
+		Status:      models.ScheduleStatusActive,
+		UserID:      userId,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	return s.repo.SaveSchedule(ctx, &schedule)
+}
+
+func (s *Service) ListSchedules(ctx context.Context, userId string) ([]models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// A schedule is listed when the user can see the wallet it sends from
+	wallets, err := s.accessibleWallets(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListSchedules(ctx, walletAddresses(wallets))
+}
+
+func (s *Service) GetSchedule(ctx context.Context, id string, userId string) (models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return s.authorizeSchedule(ctx, id, userId, PermissionViewWallet)
+}
+
+func (s *Service) ListScheduleRuns(ctx context.Context, id string, userId string) ([]models.ScheduleRun, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.authorizeSchedule(ctx, id, userId, PermissionViewWallet); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListScheduleRuns(ctx, id)
+}
+
+func (s *Service) PauseSchedule(ctx context.Context, id string, userId string) (models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	schedule, err := s.authorizeSchedule(ctx, id, userId, PermissionTransact)
+	if err != nil {
+		return schedule, err
+	}
+	if schedule.Status != models.ScheduleStatusActive {
+		return schedule, fmt.Errorf("schedule is %s", schedule.Status)
+	}
+
+	if err := s.repo.UpdateScheduleState(ctx, id, models.ScheduleStatusPaused, schedule.NextRunAt); err != nil {
+		return schedule, err
+	}
+	return s.repo.GetSchedule(ctx, id)
+}
+
+func (s *Service) ResumeSchedule(ctx context.Context, id string, userId string) (models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	schedule, err := s.authorizeSchedule(ctx, id, userId, PermissionTransact)
+	if err != nil {
+		return schedule, err
+	}
+	if schedule.Status != models.ScheduleStatusPaused {
+		return schedule, fmt.Errorf("schedule is %s", schedule.Status)
+	}
+	// Runs are signed as the user who created the schedule
+	user, err := s.users.GetUserByID(ctx, schedule.UserID)
+	if err != nil {
+		return schedule, err
+	}
+	if user.RequireWebAuthnForTransactions {
+		return schedule, ErrSchedulesNeedConfirmation
+	}
+
+	// Recurring schedules skip the runs missed while paused; a one-off
+	// schedule whose time has passed runs on the next scheduler tick.
+	nextRunAt := schedule.NextRunAt
+	if schedule.Cron != "" {
+		nextRunAt, err = nextCronRun(schedule.Cron, time.Now().UTC())
+		if err != nil {
+			return schedule, err
+		}
+	}
+
+	if err := s.repo.UpdateScheduleState(ctx, id, models.ScheduleStatusActive, nextRunAt); err != nil {
+		return schedule, err
+	}
+	return s.repo.GetSchedule(ctx, id)
+}
+
+func (s *Service) DeleteSchedule(ctx context.Context, id string, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := s.authorizeSchedule(ctx, id, userId, PermissionTransact); err != nil {
+		return err
+	}
+	return s.repo.DeleteSchedule(ctx, id)
+}
+
+// RunDueSchedules executes every schedule that is due and returns the number
+// of runs attempted.
+func (s *Service) RunDueSchedules(ctx context.Context) (int, error) {
+	runs := 0
+	for {
+		// Stop claiming schedules once shutting down; a claimed schedule is
+		// always run to the end
+		if ctx.Err() != nil {
+			return runs, nil
+		}
+
+		schedule, err := s.repo.ClaimDueSchedule(ctx, time.Now().UTC(), scheduleLease)
+		if err != nil {
+			return runs, err
+		}
+		if schedule == nil {
+			return runs, nil
+		}
+
+		s.runSchedule(schedule)
+		runs++
+	}
+}
+
+// runSchedule sends the scheduled transfer through the regular signing path
+// and records the outcome in the run history. Each run is traced on its own,
+// and is not cancelled when shutting down.
+//
+// A run whose transaction was broadcast but not seen mined is left pending
+// and keeps its lease; the next claim reconciles it with the chain.
+func (s *Service) runSchedule(schedule *models.Schedule) {
+	spanCtx, span := tracing.Start(context.Background(), "run schedule", attribute.String("schedule.id", schedule.ID))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(spanCtx, 60*time.Second)
+	defer cancel()
+
+	run := models.ScheduleRun{
+		ScheduleID: schedule.ID,
+		UserID:     schedule.UserID,
+		StartedAt:  time.Now().UTC(),
+	}
+
+	var result models.TransactionResult
+	var err error
+	if schedule.PendingRun != nil {
+		// A previous run signed its transaction but never finished, so it
+		// may already have been broadcast
+		run.StartedAt = schedule.PendingRun.StartedAt
+		result, err = s.resumeScheduleRun(ctx, schedule)
+	} else {
+		result, err = s.sendScheduleRun(ctx, schedule, run.StartedAt)
+	}
+	var unconfirmed *unconfirmedError
+	if errors.As(err, &unconfirmed) {
+		span.SetStatus(codes.Error, err.Error())
+		log.Printf("Run of schedule %s is still pending: %v", schedule.ID, err)
+		return
+	}
+
+	run.FinishedAt = time.Now().UTC()
+	if err != nil {
+		run.Error = err.Error()
+		span.SetStatus(codes.Error, run.Error)
+	} else {
+		run.Success = true
+		run.TransactionHash = result.TransactionHash
+	}
+
+	// The run is recorded even if sending used up its deadline
+	ctx, cancel = context.WithTimeout(spanCtx, 10*time.Second)
+	defer cancel()
+
+	if _, err := s.repo.SaveScheduleRun(ctx, &run); err != nil {
+		log.Printf("Failed to record run of schedule %s: %v", schedule.ID, err)
+	}
+
+	status := models.ScheduleStatusCompleted
+	nextRunAt := schedule.NextRunAt
+	if schedule.Cron != "" {
+		status = models.ScheduleStatusActive
+		nextRunAt, err = nextCronRun(schedule.Cron, run.FinishedAt)
+		if err != nil {
+			status = models.ScheduleStatusFailed
+		}
+	} else if !run.Success {
+		status = models.ScheduleStatusFailed
+	}
+
+	if err := s.repo.FinishScheduleRun(ctx, schedule.ID, run.StartedAt, status, nextRunAt); err != nil {
+		log.Printf("Failed to update schedule %s: %v", schedule.ID, err)
+	}
+}
+
+// sendScheduleRun sends the scheduled transfer, recording the signed
+// transaction on the schedule before it is broadcast. Once it is recorded,
+// any failure leaves the outcome to be reconciled with the chain.
+func (s *Service) sendScheduleRun(ctx context.Context, schedule *models.Schedule, startedAt time.Time) (models.TransactionResult, error) {
+	value, ok := new(big.Int).SetString(schedule.Value, 10)
+	if !ok {
+		return models.TransactionResult{}, fmt.Errorf("invalid value: %s", schedule.Value)
+	}
+
+	signed := false
+	result, _, err := s.signAndSend(ctx, outgoingTransaction{
+		From:   common.HexToAddress(schedule.FromAddress),
+		To:     common.HexToAddress(schedule.ToAddress),
+		Value:  value,
+		UserID: schedule.UserID,
+		OnSigned: func(ctx context.Context, signedTx *ethereumTypes.Transaction) error {
+			raw, err := signedTx.MarshalBinary()
+			if err != nil {
+				return err
+			}
+			err = s.repo.SetPendingRun(ctx, schedule.ID, models.PendingRun{
+				StartedAt:       startedAt,
+				TransactionHash: signedTx.Hash().Hex(),
+				RawTransaction:  hexutil.Encode(raw),
+				Nonce:           signedTx.Nonce(),
+			})
+			signed = err == nil
+			return err
+		},
+	})
+	if err != nil && signed {
+		return models.TransactionResult{}, &unconfirmedError{err}
+	}
+	return result, err
+}
+
+// resumeScheduleRun finishes a run whose transaction was signed before the
+// scheduler stopped. The recorded transaction is broadcast again while its
+// nonce is unused, so the transfer is never sent twice.
+func (s *Service) resumeScheduleRun(ctx context.Context, schedule *models.Schedule) (models.TransactionResult, error) {
+	pending := schedule.PendingRun
+	signedTx := new(ethereumTypes.Transaction)
+	raw, err := hexutil.Decode(pending.RawTransaction)
+	if err != nil {
+		return models.TransactionResult{}, fmt.Errorf("failed to decode pending transaction: %v", err)
+	}
+	if err := signedTx.UnmarshalBinary(raw); err != nil {
+		return models.TransactionResult{}, fmt.Errorf("failed to decode pending transaction: %v", err)
+	}
+
+	outgoing := outgoingTransaction{
+		From:   common.HexToAddress(schedule.FromAddress),
+		To:     common.HexToAddress(schedule.ToAddress),
+		Value:  signedTx.Value(),
+		UserID: schedule.UserID,
+	}
+
+	_, err = s.web3Client.TransactionReceipt(ctx, signedTx.Hash())
+	switch {
+	case err == nil:
+		// Already mined
+	case errors.Is(err, ethereum.NotFound):
+		nonce, err := s.web3Client.NonceAt(ctx, outgoing.From, nil)
+		if err != nil {
+			return models.TransactionResult{}, &unconfirmedError{err}
+		}
+		if nonce > pending.Nonce {
+			// The transaction may have been mined since its receipt was
+			// looked up
+			_, err := s.web3Client.TransactionReceipt(ctx, signedTx.Hash())
+			if errors.Is(err, ethereum.NotFound) {
+				return models.TransactionResult{}, fmt.Errorf("transaction %s was replaced by another transaction from the wallet", pending.TransactionHash)
+			}
+			if err != nil {
+				return models.TransactionResult{}, &unconfirmedError{err}
+			}
+			break
+		}
+		if err := s.web3Client.SendTransaction(ctx, signedTx); err != nil && !isKnownTransaction(err) {
+			metrics.CountTransaction(metrics.TransactionStatusFailed)
+			return models.TransactionResult{}, err
+		}
+	default:
+		return models.TransactionResult{}, &unconfirmedError{err}
+	}
+
+	result, _, err := s.recordTransaction(ctx, outgoing, signedTx, time.Now())
+	return result, err
+}
+
+// isKnownTransaction reports whether a node rejected a transaction because it
+// is already in its pool.
+func isKnownTransaction(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "already known")
+}
+
+// nextCronRun returns the first activation of a standard five-field cron
+// expression (evaluated in UTC) after the given time.
+func nextCronRun(expression string, after time.Time) (time.Time, error) {
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression: %v", err)
+	}
+	return schedule.Next(after.UTC()), nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/memory"
+	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeChain answers the receipt and nonce lookups of a resumed schedule run.
+// Other calls panic.
+type fakeChain struct {
+	web3.Client
+
+	mu       sync.Mutex
+	receipts []error // results of successive receipt lookups, the last repeats
+	nonce    uint64
+}
+
+func (f *fakeChain) TransactionReceipt(ctx context.Context, hash common.Hash) (*ethereumTypes.Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.receipts[0]
+	if len(f.receipts) > 1 {
+		f.receipts = f.receipts[1:]
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ethereumTypes.Receipt{Status: ethereumTypes.ReceiptStatusSuccessful, BlockNumber: big.NewInt(7), TxHash: hash}, nil
+}
+
+func (f *fakeChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return f.nonce, nil
+}
+
+// savePendingSchedule stores a due one-off schedule whose run signed a
+// transaction with nonce 0 and then stopped.
+func savePendingSchedule(t *testing.T, store *memory.Store) models.Schedule {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x00000000000000000000000000000000000000e3")
+	signedTx, err := ethereumTypes.SignTx(ethereumTypes.NewTransaction(0, to, big.NewInt(1), 21000, big.NewInt(1), nil),
+		ethereumTypes.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	schedule, err := store.SaveSchedule(context.Background(), &models.Schedule{
+		FromAddress: crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		ToAddress:   to.Hex(),
+		Value:       "1",
+		RunAt:       &now,
+		NextRunAt:   now,
+		Status:      models.ScheduleStatusActive,
+		UserID:      "user-1",
+		PendingRun: &models.PendingRun{
+			StartedAt:       now,
+			TransactionHash: signedTx.Hash().Hex(),
+			RawTransaction:  hexutil.Encode(raw),
+			Nonce:           0,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schedule
+}
+
+func TestResumedScheduleRun(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		chain       *fakeChain
+		wantPending bool
+		wantStatus  string
+		wantSuccess bool
+	}{
+		{
+			name:        "mined between the receipt and nonce lookups",
+			chain:       &fakeChain{receipts: []error{ethereum.NotFound, nil}, nonce: 1},
+			wantStatus:  models.ScheduleStatusCompleted,
+			wantSuccess: true,
+		},
+		{
+			name:       "replaced by another transaction",
+			chain:      &fakeChain{receipts: []error{ethereum.NotFound}, nonce: 1},
+			wantStatus: models.ScheduleStatusFailed,
+		},
+		{
+			name:        "node unreachable",
+			chain:       &fakeChain{receipts: []error{errors.New("connection refused")}},
+			wantPending: true,
+			wantStatus:  models.ScheduleStatusActive,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			service, store := newTestService(t)
+			service.web3Client = test.chain
+			schedule := savePendingSchedule(t, store)
+
+			service.runSchedule(&schedule)
+
+			ctx := context.Background()
+			stored, err := store.GetSchedule(ctx, schedule.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (stored.PendingRun != nil) != test.wantPending || stored.Status != test.wantStatus {
+				t.Errorf("schedule status = %s, pending run = %+v", stored.Status, stored.PendingRun)
+			}
+			runs, err := store.ListScheduleRuns(ctx, schedule.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.wantPending {
+				if len(runs) != 0 {
+					t.Errorf("an unfinished run was recorded: %+v", runs)
+				}
+				return
+			}
+			if len(runs) != 1 || runs[0].Success != test.wantSuccess {
+				t.Errorf("runs = %+v", runs)
+			}
+		})
+	}
+}
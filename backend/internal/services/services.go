@@ -1,97 +1,125 @@
 package services
 
 import (
+	"bytes"
 	"context"
-	"encoding/asn1"
 	"errors"
 	"fmt"
 	"math/big"
+	"sync/atomic"
 	"time"
 
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+	"github.com/natneam/crypto-wallet-app/backend/internal/kms"
+	"github.com/natneam/crypto-wallet-app/backend/internal/mailer"
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
 	"github.com/natneam/crypto-wallet-app/backend/internal/models"
 	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+	"github.com/natneam/crypto-wallet-app/backend/internal/tracing"
 	"github.com/natneam/crypto-wallet-app/backend/internal/utils"
+	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
 
-	"github.com/aws/aws-sdk-go-v2/service/kms"
-	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v4"
-	ethawskmssigner "github.com/welthee/go-ethereum-aws-kms-tx-signer/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-	repo       *repositories.Repository
-	web3Client *ethclient.Client
-	kmsClient  *kms.Client
-	jwtSecret  []byte
+	repo repositories.Store
+
+	// Users, wallets and transactions go through their own stores, so another
+	// backend can hold them; everything else is kept by repo
+	users        repositories.UserStore
+	wallets      repositories.WalletStore
+	transactions repositories.TransactionStore
+
+	web3Client         web3.Client
+	keys               kms.KeyManager
+	bundler            *web3.BundlerClient
+	jwtKeys            config.JWTKeys
+	webAuthn           *webauthn.WebAuthn
+	mailer             mailer.Mailer
+	oidc               *config.OIDCProvider
+	appURL             string
+	totpIssuer         string
+	safe               web3.SafeDeployment
+	accountAbstraction web3.AccountAbstraction
+
+	ready  atomic.Bool
+	health healthState
 }
 
-func NewService(repo *repositories.Repository, web3Client *ethclient.Client, kmsClient *kms.Client) *Service {
+// Dependencies are what a Service is built from.
+type Dependencies struct {
+	Config *config.Config
+
+	// Repo keeps everything but users, wallets and transactions, which are
+	// kept by Stores
+	Repo   repositories.Store
+	Stores repositories.Stores
+
+	Web3 web3.Client
+	Keys kms.KeyManager
+	// Bundler is nil when smart accounts are not configured
+	Bundler  *web3.BundlerClient
+	WebAuthn *webauthn.WebAuthn
+	Mailer   mailer.Mailer
+	// OIDC is nil when single sign-on is off
+	OIDC *config.OIDCProvider
+}
+
+func NewService(deps Dependencies) *Service {
+	cfg := deps.Config
 	return &Service{
-		repo:       repo,
-		web3Client: web3Client,
-		kmsClient:  kmsClient,
+		repo:         deps.Repo,
+		users:        deps.Stores.Users,
+		wallets:      deps.Stores.Wallets,
+		transactions: deps.Stores.Transactions,
+		web3Client:   deps.Web3,
+		keys:         deps.Keys,
+		bundler:      deps.Bundler,
+		jwtKeys:      cfg.JWT.SigningKeys(),
+		webAuthn:     deps.WebAuthn,
+		mailer:       deps.Mailer,
+		oidc:         deps.OIDC,
+		appURL:       cfg.AppURL,
+		totpIssuer:   cfg.TOTPIssuer,
+		health: healthState{
+			startedAt:      time.Now().UTC(),
+			storageBackend: cfg.Storage.Backend,
+			kmsProvider:    cfg.KMS.Provider,
+			maxBlockAge:    cfg.Ethereum.MaxBlockAge.Duration,
+		},
+		safe:               web3.NewSafeDeployment(cfg.Contracts),
+		accountAbstraction: web3.NewAccountAbstraction(cfg.Contracts),
 	}
 }
 
-func (s *Service) CreateWallet(walletName string, userId string) (models.Wallet, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
+// CreateWallet creates a personal wallet, or an organisation wallet when
+// organisationId is set.
+func (s *Service) CreateWallet(ctx context.Context, walletName string, organisationId string, userId string) (models.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 	var newWallet models.Wallet
 
-	// Create a new KMS key
-	createKeyInput := &kms.CreateKeyInput{
-		Description: aws.String("Ethereum wallet key"),
-		KeyUsage:    kmsTypes.KeyUsageTypeSignVerify,
-		KeySpec:     kmsTypes.KeySpecEccSecgP256k1, // Ethereum uses secp256k1 curve
-	}
-
-	createKeyOutput, err := s.kmsClient.CreateKey(ctx, createKeyInput)
-	if err != nil {
-		return newWallet, fmt.Errorf("failed to create KMS key: %v", err)
-	}
-
-	// Get the public key
-	getPublicKeyInput := &kms.GetPublicKeyInput{
-		KeyId: createKeyOutput.KeyMetadata.KeyId,
-	}
-
-	getPublicKeyOutput, err := s.kmsClient.GetPublicKey(ctx, getPublicKeyInput)
-	if err != nil {
-		return newWallet, fmt.Errorf("failed to get public key: %v", err)
-	}
-
-	// Parse the ASN.1 DER encoded public key
-	var publicKey struct {
-		Algorithm struct {
-			Algorithm  asn1.ObjectIdentifier
-			Parameters asn1.RawValue
+	if organisationId != "" {
+		if _, err := s.authorizeOrganisation(ctx, organisationId, userId, PermissionManageWallets); err != nil {
+			return newWallet, err
 		}
-		PublicKey asn1.BitString
-	}
-	_, err = asn1.Unmarshal(getPublicKeyOutput.PublicKey, &publicKey)
-	if err != nil {
-		return newWallet, fmt.Errorf("failed to parse public key: %v", err)
 	}
 
-	// Extract the actual public key bytes
-	publicKeyBytes := publicKey.PublicKey.Bytes
-	if len(publicKeyBytes) == 65 && publicKeyBytes[0] == 4 {
-		// Remove the leading 0x04 byte (uncompressed point indicator)
-		publicKeyBytes = publicKeyBytes[1:]
-	} else if len(publicKeyBytes) != 64 {
-		return newWallet, fmt.Errorf("unexpected public key length after parsing: %d", len(publicKeyBytes))
+	// Create a new key for the wallet
+	keyID, address, err := s.keys.CreateKey(ctx)
+	if err != nil {
+		return newWallet, err
 	}
 
-	// Calculate the Ethereum address
-	hash := crypto.Keccak256(publicKeyBytes)
-	address := common.BytesToAddress(hash[12:])
-
 	publicKeyHex := address.Hex()
 
 	// Check the balance on Sepolia
@@ -101,25 +129,34 @@ func (s *Service) CreateWallet(walletName string, userId string) (models.Wallet,
 	}
 
 	newWallet = models.Wallet{
-		Name:      walletName,
-		PublicKey: publicKeyHex,
-		Balance:   balance.String(),
-		KMSKeyID:  *createKeyOutput.KeyMetadata.KeyId,
-		UserID:    userId,
+		Name:           walletName,
+		PublicKey:      publicKeyHex,
+		Balance:        balance.String(),
+		KMSKeyID:       keyID,
+		UserID:         userId,
+		OrganisationID: organisationId,
 	}
 
 	// Save the wallet to the database
-	newWallet, err = s.repo.SaveWallet(ctx, &newWallet)
+	newWallet, err = s.wallets.SaveWallet(ctx, &newWallet)
 	if err != nil {
 		return newWallet, fmt.Errorf("failed to save wallet: %v", err)
 	}
+	s.audit(ctx, models.AuditEntry{
+		Action:  models.AuditWalletCreated,
+		UserID:  userId,
+		Target:  newWallet.PublicKey,
+		Details: map[string]string{"organisation": organisationId, "kms_key": newWallet.KMSKeyID},
+	})
 	return newWallet, nil
 }
 
-func (s *Service) ListWallets(userId string) ([]models.Wallet, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
+func (s *Service) ListWallets(ctx context.Context, userId string) ([]models.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
-	wallets, err := s.repo.ListWallets(ctx, userId)
+	// Include the wallets of every organisation the user belongs to
+	wallets, err := s.accessibleWallets(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +167,7 @@ func (s *Service) ListWallets(userId string) ([]models.Wallet, error) {
 		address := common.HexToAddress(wallet.PublicKey)
 
 		// Fetch balance from Sepolia
-		balance, err := s.web3Client.BalanceAt(context.Background(), address, nil)
+		balance, err := s.web3Client.BalanceAt(ctx, address, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -142,9 +179,10 @@ func (s *Service) ListWallets(userId string) ([]models.Wallet, error) {
 	return wallets, nil
 }
 
-func (s *Service) GetWallet(address string, userId string) (*models.Wallet, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	wallet, err := s.repo.GetWallet(ctx, address, userId)
+func (s *Service) GetWallet(ctx context.Context, address string, userId string) (*models.Wallet, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	wallet, err := s.authorizeWallet(ctx, address, userId, PermissionViewWallet)
 	if err != nil {
 		return nil, err
 	}
@@ -163,107 +201,291 @@ func (s *Service) GetWallet(address string, userId string) (*models.Wallet, erro
 	return &wallet, nil
 }
 
-func (s *Service) SignAndSendTransaction(fromAddress common.Address, toAddress common.Address, value string, userId string) (models.TransactionResult, error) {
+func (s *Service) SignAndSendTransaction(ctx context.Context, fromAddress common.Address, toAddress common.Address, value string, userId string) (models.TransactionResult, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	val, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return models.TransactionResult{}, fmt.Errorf("invalid value: %s", value)
+	}
+
+	result, _, err := s.signAndSend(ctx, outgoingTransaction{
+		From:   fromAddress,
+		To:     toAddress,
+		Value:  val,
+		UserID: userId,
+	})
+	return result, err
+}
+
+// outgoingTransaction describes a transaction to be signed with the KMS key
+// of one of the user's wallets and broadcast.
+type outgoingTransaction struct {
+	From   common.Address
+	To     common.Address
+	Value  *big.Int
+	Data   []byte
+	Method string
+	UserID string
+
+	// OnSigned, when set, is called with the signed transaction before it is
+	// broadcast. The transaction is not sent if it returns an error.
+	OnSigned func(ctx context.Context, signedTx *ethereumTypes.Transaction) error
+}
+
+// signAndSend signs the transaction with the sender wallet's KMS key, sends it,
+// waits for it to be mined and records the result in the transaction history.
+func (s *Service) signAndSend(ctx context.Context, outgoing outgoingTransaction) (result models.TransactionResult, receipt *ethereumTypes.Receipt, err error) {
+	ctx, span := tracing.Start(ctx, "send transaction",
+		attribute.String("wallet.address", outgoing.From.Hex()),
+		attribute.String("transaction.to", outgoing.To.Hex()),
+		attribute.String("transaction.method", outgoing.Method),
+	)
+	defer func() { tracing.End(span, err) }()
 
-	ctx, _ := context.WithTimeout(context.Background(), 60*time.Second)
 	// Get user's wallet details
-	wallet, err := s.repo.GetWallet(ctx, fromAddress.Hex(), userId)
+	wallet, err := s.authorizeWallet(ctx, outgoing.From.Hex(), outgoing.UserID, PermissionTransact)
 	if err != nil {
-		return models.TransactionResult{}, err
+		return models.TransactionResult{}, nil, err
 	}
 
-	// Get the chain ID
-	chainID, err := s.web3Client.NetworkID(ctx)
+	// A request retried with a passkey assertion sends the transaction that
+	// was confirmed rather than a rebuilt one, whose gas price may differ
+	tx, chainID, ok := s.confirmedTransaction(ctx, outgoing)
+	if !ok {
+		tx, chainID, err = s.buildTransaction(ctx, outgoing)
+		if err != nil {
+			return models.TransactionResult{}, nil, err
+		}
+	}
+
+	return s.sendTransaction(ctx, wallet, outgoing, tx, chainID)
+}
+
+// confirmedTransaction returns the transaction prepared for the passkey
+// assertion the request carries, if it is the outgoing transaction.
+func (s *Service) confirmedTransaction(ctx context.Context, outgoing outgoingTransaction) (*ethereumTypes.Transaction, *big.Int, bool) {
+	challenge := s.pendingConfirmation(ctx, outgoing.UserID)
+	if challenge == nil || challenge.Transaction == nil || challenge.Transaction.From != outgoing.From.Hex() {
+		return nil, nil, false
+	}
+	tx, chainID, err := decodePreparedTransaction(challenge.Transaction)
 	if err != nil {
-		return models.TransactionResult{}, err
+		return nil, nil, false
+	}
+
+	value := outgoing.Value
+	if value == nil {
+		value = new(big.Int)
 	}
+	if tx.To() == nil || *tx.To() != outgoing.To || tx.Value().Cmp(value) != 0 || !bytes.Equal(tx.Data(), outgoing.Data) {
+		return nil, nil, false
+	}
+	return tx, chainID, true
+}
 
-	// Create AWS KMS transactor
-	transactOpts, err := ethawskmssigner.NewAwsKmsTransactorWithChainIDCtx(ctx, s.kmsClient, wallet.KMSKeyID, chainID)
+// buildTransaction fills in the nonce, gas price and gas limit of an unsigned
+// transaction.
+func (s *Service) buildTransaction(ctx context.Context, outgoing outgoingTransaction) (tx *ethereumTypes.Transaction, chainID *big.Int, err error) {
+	ctx, span := tracing.Start(ctx, "build transaction")
+	defer func() { tracing.End(span, err) }()
+
+	// Get the chain ID
+	chainID, err = s.web3Client.ChainID(ctx)
 	if err != nil {
-		return models.TransactionResult{}, err
+		return nil, nil, err
 	}
 
 	// Get the latest nonce for the fromAddress
-	nonce, err := s.web3Client.PendingNonceAt(ctx, fromAddress)
+	nonce, err := s.web3Client.PendingNonceAt(ctx, outgoing.From)
 	if err != nil {
-		return models.TransactionResult{}, err
+		return nil, nil, err
+	}
+
+	// A node lagging behind the one that mined the wallet's last transaction
+	// reports a nonce that is already used
+	wallet, err := s.wallets.GetWallet(ctx, outgoing.From.Hex())
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, err
 	}
+	nonce = max(nonce, wallet.NextNonce)
 
 	// Get the current gas price
 	gasPrice, err := s.web3Client.SuggestGasPrice(ctx)
 	if err != nil {
-		return models.TransactionResult{}, err
+		return nil, nil, err
+	}
+
+	value := outgoing.Value
+	if value == nil {
+		value = new(big.Int)
 	}
 
 	// Estimate gas limit
 	gasLimit, err := s.web3Client.EstimateGas(ctx, ethereum.CallMsg{
-		From: fromAddress,
-		To:   &toAddress,
-		Data: nil,
+		From:  outgoing.From,
+		To:    &outgoing.To,
+		Value: value,
+		Data:  outgoing.Data,
 	})
 	if err != nil {
-		return models.TransactionResult{}, err
+		return nil, nil, err
 	}
 
 	// Create the transaction
-	val, _ := new(big.Int).SetString(value, 10)
-	tx := ethereumTypes.NewTransaction(nonce, toAddress, val, gasLimit, gasPrice, nil)
+	return ethereumTypes.NewTransaction(nonce, outgoing.To, value, gasLimit, gasPrice, outgoing.Data), chainID, nil
+}
 
-	// Sign the transaction using AWS KMS
-	signedTx, err := transactOpts.Signer(transactOpts.From, tx)
+// signWithWallet signs a digest with the wallet's key. Every signature goes
+// through it, so users who require WebAuthn confirmation only get digests
+// signed that they confirmed. pending holds the transaction or user
+// operation the digest is the hash of, kept for the confirmed retry.
+func (s *Service) signWithWallet(ctx context.Context, wallet models.Wallet, userId string, digest []byte, pending models.WebAuthnChallenge) ([]byte, error) {
+	user, err := s.users.GetUserByID(ctx, userId)
 	if err != nil {
-		return models.TransactionResult{}, err
+		return nil, err
+	}
+	if user.RequireWebAuthnForTransactions {
+		if err := s.confirmSignature(ctx, userId, digest, pending); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.keys.SignDigest(ctx, wallet.KMSKeyID, digest)
+}
+
+// sendTransaction signs a built transaction with the wallet's KMS key, sends
+// it, waits for it to be mined and records the result.
+func (s *Service) sendTransaction(ctx context.Context, wallet models.Wallet, outgoing outgoingTransaction, tx *ethereumTypes.Transaction, chainID *big.Int) (models.TransactionResult, *ethereumTypes.Receipt, error) {
+	prepared, err := prepareTransaction(outgoing.From, tx, chainID)
+	if err != nil {
+		return models.TransactionResult{}, nil, err
+	}
+
+	// Sign the transaction hash with the wallet's key
+	signer := ethereumTypes.LatestSignerForChainID(chainID)
+	signature, err := s.signWithWallet(ctx, wallet, outgoing.UserID, signer.Hash(tx).Bytes(), models.WebAuthnChallenge{
+		Transaction: prepared,
+	})
+	if err != nil {
+		return models.TransactionResult{}, nil, err
+	}
+	signedTx, err := tx.WithSignature(signer, signature)
+	if err != nil {
+		return models.TransactionResult{}, nil, err
+	}
+	s.audit(ctx, models.AuditEntry{
+		Action: models.AuditTransactionSigned,
+		UserID: outgoing.UserID,
+		Target: signedTx.Hash().Hex(),
+		Details: map[string]string{
+			"from":   outgoing.From.Hex(),
+			"to":     outgoing.To.Hex(),
+			"value":  tx.Value().String(),
+			"method": outgoing.Method,
+		},
+	})
+
+	if outgoing.OnSigned != nil {
+		if err := outgoing.OnSigned(ctx, signedTx); err != nil {
+			return models.TransactionResult{}, nil, err
+		}
 	}
 
 	// Send the transaction
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("transaction.hash", signedTx.Hash().Hex()))
+	sentAt := time.Now()
 	err = s.web3Client.SendTransaction(ctx, signedTx)
 	if err != nil {
-		return models.TransactionResult{}, err
+		metrics.CountTransaction(metrics.TransactionStatusFailed)
+		return models.TransactionResult{}, nil, err
 	}
 
+	return s.recordTransaction(ctx, outgoing, signedTx, sentAt)
+}
+
+// unconfirmedError is returned for a broadcast transaction whose outcome
+// could not be recorded, so it may still be mined.
+type unconfirmedError struct {
+	err error
+}
+
+func (e *unconfirmedError) Error() string { return e.err.Error() }
+func (e *unconfirmedError) Unwrap() error { return e.err }
+
+// recordTransaction waits for a broadcast transaction to be mined and saves
+// the result in the transaction history.
+func (s *Service) recordTransaction(ctx context.Context, outgoing outgoingTransaction, signedTx *ethereumTypes.Transaction, sentAt time.Time) (models.TransactionResult, *ethereumTypes.Receipt, error) {
 	// Wait for the transaction to be mined
-	var receipt *ethereumTypes.Receipt
+	receipt, err := s.waitMined(ctx, signedTx.Hash())
+	if err != nil {
+		metrics.CountTransaction(metrics.TransactionStatusFailed)
+		return models.TransactionResult{}, nil, &unconfirmedError{err}
+	}
+	metrics.ObserveConfirmation(time.Since(sentAt))
 
-	for {
-		receipt, err = s.web3Client.TransactionReceipt(ctx, signedTx.Hash())
-		if err == nil {
-			break
-		}
-		if err != ethereum.NotFound {
-			return models.TransactionResult{}, err
-		}
-		// Transaction not yet mined, wait and retry
-		select {
-		case <-ctx.Done():
-			return models.TransactionResult{}, ctx.Err()
-		case <-time.After(time.Second * 5):
-			// Continue waiting
-		}
+	status := models.TransactionStatusSuccess
+	if receipt.Status != ethereumTypes.ReceiptStatusSuccessful {
+		status = models.TransactionStatusReverted
 	}
+	metrics.CountTransaction(status)
 
 	// Create the transaction result
 	result := models.TransactionResult{
 		TransactionHash: signedTx.Hash().Hex(),
 		BlockNumber:     receipt.BlockNumber.Uint64(),
 		GasUsed:         receipt.GasUsed,
-		From:            fromAddress.Hex(),
-		To:              toAddress.Hex(),
-		GasPrice:        gasPrice.String(),
-		Value:           value,
-		UserID:          userId,
+		From:            outgoing.From.Hex(),
+		To:              outgoing.To.Hex(),
+		GasPrice:        signedTx.GasPrice().String(),
+		Value:           signedTx.Value().String(),
+		Nonce:           signedTx.Nonce(),
+		Status:          status,
+		Method:          outgoing.Method,
+		UserID:          outgoing.UserID,
+	}
+	if len(outgoing.Data) > 0 {
+		result.Data = hexutil.Encode(outgoing.Data)
 	}
 
 	// Save the transaction result to the database
-	savedTrx, err := s.repo.SaveTransaction(ctx, &result)
+	savedTrx, err := s.transactions.SaveTransaction(ctx, &result)
 	if err != nil {
-		return models.TransactionResult{}, err
+		return models.TransactionResult{}, nil, &unconfirmedError{err}
 	}
 
-	return savedTrx, nil
+	return savedTrx, receipt, nil
 }
 
-func (s *Service) SignUp(username, email, password string) error {
+// waitMined polls for the receipt of a sent transaction until it is mined or
+// ctx expires.
+func (s *Service) waitMined(ctx context.Context, hash common.Hash) (receipt *ethereumTypes.Receipt, err error) {
+	ctx, span := tracing.Start(ctx, "wait for receipt", attribute.String("transaction.hash", hash.Hex()))
+	defer func() { tracing.End(span, err) }()
+
+	for {
+		receipt, err = s.web3Client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			span.SetAttributes(attribute.Int64("transaction.block", receipt.BlockNumber.Int64()))
+			return receipt, nil
+		}
+		if err != ethereum.NotFound {
+			return nil, err
+		}
+		// Transaction not yet mined, wait and retry
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second * 5):
+			// Continue waiting
+		}
+	}
+}
+
+// SignUp creates an account that is activated once its email address is verified.
+func (s *Service) SignUp(ctx context.Context, username, email, password string) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -275,53 +497,114 @@ func (s *Service) SignUp(username, email, password string) error {
 		PasswordHash: string(hashedPassword),
 		CreatedAt:    time.Now().Format(time.RFC3339),
 		UpdatedAt:    time.Now().Format(time.RFC3339),
+		Status:       models.UserStatusPendingVerification,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := s.users.CreateUser(ctx, user); err != nil {
+		return err
 	}
 
-	return s.repo.CreateUser(user)
+	created, err := s.users.GetUserByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	s.sendVerificationEmailAfterSignUp(ctx, created)
+	return nil
 }
 
-func (s *Service) Login(username, password string) (string, error) {
-	user, err := s.repo.GetUserByUsername(username)
+// Login checks the password of a user logging in from clientIP. Failed
+// attempts are throttled per username and per IP.
+func (s *Service) Login(ctx context.Context, username, password string, clientIP string) (models.LoginResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := s.checkLoginAllowed(ctx, username, clientIP); err != nil {
+		return models.LoginResult{}, err
+	}
+
+	user, err := s.users.GetUserByUsername(ctx, username)
 	if err != nil {
-		return "", err
+		s.recordLoginFailure(ctx, username, clientIP)
+		return models.LoginResult{}, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", errors.New("invalid credentials")
+		s.recordLoginFailure(ctx, username, clientIP)
+		return models.LoginResult{}, errors.New("invalid credentials")
+	}
+	if user.Status == models.UserStatusPendingVerification {
+		return models.LoginResult{}, ErrEmailNotVerified
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": user.ID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
-	})
+	// Users with TOTP enabled finish logging in with CompleteLogin, and their
+	// failures are only cleared once the second factor is verified
+	if user.TOTPEnabled {
+		mfaToken, err := s.issueMFAToken(user.ID)
+		if err != nil {
+			return models.LoginResult{}, err
+		}
+		return models.LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+	s.recordLoginSuccess(ctx, username)
+	s.auditLogin(ctx, user.ID, clientIP, "password")
 
-	return token.SignedString(s.jwtSecret)
+	tokens, err := s.startSession(ctx, user.ID)
+	if err != nil {
+		return models.LoginResult{}, err
+	}
+	return models.LoginResult{AuthTokens: &tokens}, nil
 }
 
-func (s *Service) ValidateToken(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.jwtSecret, nil
-	})
+// ValidateToken verifies an access token and the session it belongs to, and
+// returns the user and session ids.
+func (s *Service) ValidateToken(ctx context.Context, tokenString string) (string, string, error) {
+	token, err := jwt.Parse(tokenString, s.jwtKey)
 
 	if err != nil {
-		return "", err
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			// If user_id is not a string, try to convert it to a string
-			userIDFloat, ok := claims["user_id"].(float64)
-			if !ok {
-				return "", fmt.Errorf("invalid user_id in token")
-			}
-			userID = fmt.Sprintf("%.0f", userIDFloat)
-		}
-		return userID, nil
+		return "", "", err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return "", "", fmt.Errorf("invalid token")
+	}
+
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return "", "", fmt.Errorf("invalid user_id in token")
+	}
+	sessionID, ok := claims["sid"].(string)
+	if !ok || claims["purpose"] != nil {
+		return "", "", fmt.Errorf("invalid sid in token")
+	}
+
+	// Revoked sessions invalidate their access tokens immediately
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+	if session.RevokedAt != nil || session.UserID != userID {
+		return "", "", fmt.Errorf("session revoked")
 	}
 
-	return "", fmt.Errorf("invalid token")
+	return userID, sessionID, nil
+}
+
+// jwtKey selects the verification key of a token by its kid header.
+func (s *Service) jwtKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.jwtKeys.Keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	return key, nil
 }
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+	"github.com/natneam/crypto-wallet-app/backend/internal/kms"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/memory"
+)
+
+// newTestService returns a service on the memory store, without a chain.
+func newTestService(t *testing.T) (*Service, *memory.Store) {
+	t.Helper()
+
+	cfg := config.Defaults()
+	cfg.JWT.Secret = "a-signing-key-of-at-least-32-bytes"
+	webAuthn, err := cfg.WebAuthn.RelyingParty()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := memory.NewStore()
+	return NewService(Dependencies{
+		Config:   &cfg,
+		Repo:     store,
+		Stores:   store.Stores(),
+		Keys:     kms.NewLocalKeyManager(),
+		WebAuthn: webAuthn,
+	}), store
+}
+
+// createTestUser stores an active user and returns it.
+func createTestUser(t *testing.T, store *memory.Store, username string) *models.User {
+	t.Helper()
+
+	user := &models.User{Username: username, Email: username + "@example.com", Status: models.UserStatusActive}
+	if err := store.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.GetUserByUsername(context.Background(), username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// saveTestWallet stores a wallet at address, personal if organisationId is empty.
+func saveTestWallet(t *testing.T, store *memory.Store, address string, userId string, organisationId string) models.Wallet {
+	t.Helper()
+
+	wallet, err := store.SaveWallet(context.Background(), &models.Wallet{
+		PublicKey:      address,
+		UserID:         userId,
+		OrganisationID: organisationId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wallet
+}
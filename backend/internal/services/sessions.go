@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var errInvalidRefreshToken = errors.New("invalid refresh token")
+
+// startSession creates a session for the user and issues its first token pair.
+// Accounts awaiting email verification cannot sign in.
+func (s *Service) startSession(ctx context.Context, userId string) (models.AuthTokens, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+	if user.Status == models.UserStatusPendingVerification {
+		return models.AuthTokens{}, ErrEmailNotVerified
+	}
+
+	refreshToken, err := generateToken()
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	now := time.Now().UTC()
+	session, err := s.repo.CreateSession(ctx, &models.Session{
+		UserID:           userId,
+		RefreshTokenHash: hashToken(refreshToken),
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	return s.issueTokens(session, refreshToken)
+}
+
+// RefreshSession exchanges a refresh token for a new access token and a new
+// refresh token. Presenting an already rotated refresh token means it was
+// leaked, so the whole session is revoked.
+func (s *Service) RefreshSession(ctx context.Context, refreshToken string) (models.AuthTokens, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tokenHash := hashToken(refreshToken)
+	session, err := s.repo.GetSessionByRefreshToken(ctx, tokenHash)
+	if err != nil {
+		return models.AuthTokens{}, errInvalidRefreshToken
+	}
+
+	if session.RefreshTokenHash != tokenHash {
+		if err := s.repo.RevokeSession(ctx, session.ID); err != nil {
+			return models.AuthTokens{}, err
+		}
+		return models.AuthTokens{}, errInvalidRefreshToken
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return models.AuthTokens{}, errInvalidRefreshToken
+	}
+
+	newRefreshToken, err := generateToken()
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+	if err := s.repo.RotateSessionToken(ctx, session.ID, tokenHash, hashToken(newRefreshToken), time.Now().UTC().Add(refreshTokenTTL)); err != nil {
+		return models.AuthTokens{}, errInvalidRefreshToken
+	}
+
+	return s.issueTokens(session, newRefreshToken)
+}
+
+// Logout revokes a single session of the user.
+func (s *Service) Logout(ctx context.Context, sessionId string, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.RevokeSession(ctx, sessionId); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditLogout, UserID: userId, Target: sessionId})
+	return nil
+}
+
+// LogoutAll revokes every session of the user.
+func (s *Service) LogoutAll(ctx context.Context, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := s.repo.RevokeUserSessions(ctx, userId); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditLogout, UserID: userId, Details: map[string]string{"sessions": "all"}})
+	return nil
+}
+
+// issueTokens signs a short-lived access token for the session with the
+// active key, identified by the kid header.
+func (s *Service) issueTokens(session models.Session, refreshToken string) (models.AuthTokens, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": session.UserID,
+		"sid":     session.ID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(accessTokenTTL).Unix(),
+	})
+	token.Header["kid"] = s.jwtKeys.ActiveKID
+
+	accessToken, err := token.SignedString(s.jwtKeys.Keys[s.jwtKeys.ActiveKID])
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	return models.AuthTokens{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// generateToken returns a random URL-safe token.
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a token, as stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, store, "alice")
+
+	first, err := service.startSession(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sessionId, err := service.ValidateToken(ctx, first.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every refresh hands out a new refresh token for the same session
+	current := first
+	for range 3 {
+		next, err := service.RefreshSession(ctx, current.RefreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.RefreshToken == current.RefreshToken {
+			t.Fatal("the refresh token was not rotated")
+		}
+		userId, nextSessionId, err := service.ValidateToken(ctx, next.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userId != user.ID || nextSessionId != sessionId {
+			t.Fatalf("refreshed token is for user %s session %s, expected %s %s", userId, nextSessionId, user.ID, sessionId)
+		}
+		current = next
+	}
+}
+
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, store, "alice")
+
+	stolen, err := service.startSession(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := service.RefreshSession(ctx, stolen.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rotated-out token being presented again means one copy leaked
+	if _, err := service.RefreshSession(ctx, stolen.RefreshToken); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("reusing a rotated refresh token returned %v, expected it to be rejected", err)
+	}
+	if _, err := service.RefreshSession(ctx, rotated.RefreshToken); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("the current refresh token still works after reuse: %v", err)
+	}
+	if _, _, err := service.ValidateToken(ctx, rotated.Token); err == nil {
+		t.Fatal("the access token still works after reuse revoked its session")
+	}
+
+	// Other sessions of the user are not affected
+	other, err := service.startSession(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.RefreshSession(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("another session was revoked: %v", err)
+	}
+}
+
+func TestRefreshTokenRejectsUnknownAndLoggedOut(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, store, "alice")
+
+	if _, err := service.RefreshSession(ctx, "not-a-refresh-token"); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("an unknown refresh token returned %v", err)
+	}
+
+	tokens, err := service.startSession(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sessionId, err := service.ValidateToken(ctx, tokens.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Logout(ctx, sessionId, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.RefreshSession(ctx, tokens.RefreshToken); !errors.Is(err, errInvalidRefreshToken) {
+		t.Fatalf("the refresh token of a logged out session returned %v", err)
+	}
+}
+
+func TestStartSessionNeedsVerifiedEmail(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+
+	err := store.CreateUser(ctx, &models.User{Username: "alice", Email: "alice@example.com", Status: models.UserStatusPendingVerification})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.startSession(ctx, user.ID); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("startSession for an unverified user returned %v", err)
+	}
+}
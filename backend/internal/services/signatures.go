@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// SignPersonalMessage signs an EIP-191 (personal_sign) message with the wallet's KMS key.
+func (s *Service) SignPersonalMessage(ctx context.Context, request models.SignMessageRequest, userId string) (models.SignatureResult, error) {
+	message, err := decodeMessage(request.Message, request.Encoding)
+	if err != nil {
+		return models.SignatureResult{}, err
+	}
+
+	return s.signHash(ctx, request.Address, accounts.TextHash(message), userId)
+}
+
+// SignTypedData signs EIP-712 typed data with the wallet's KMS key.
+func (s *Service) SignTypedData(ctx context.Context, request models.SignTypedDataRequest, userId string) (models.SignatureResult, error) {
+	hash, _, err := apitypes.TypedDataAndHash(request.TypedData)
+	if err != nil {
+		return models.SignatureResult{}, fmt.Errorf("invalid typed data: %v", err)
+	}
+
+	return s.signHash(ctx, request.Address, hash, userId)
+}
+
+// VerifySignature recovers the signer of a personal message or typed data
+// signature and compares it with the expected address.
+func (s *Service) VerifySignature(request models.VerifySignatureRequest) (models.VerifySignatureResult, error) {
+	if !common.IsHexAddress(request.Address) {
+		return models.VerifySignatureResult{}, fmt.Errorf("invalid address format")
+	}
+
+	signature, err := hexutil.Decode(request.Signature)
+	if err != nil || len(signature) != 65 {
+		return models.VerifySignatureResult{}, fmt.Errorf("invalid signature")
+	}
+
+	var hash []byte
+	if request.TypedData != nil {
+		hash, _, err = apitypes.TypedDataAndHash(*request.TypedData)
+		if err != nil {
+			return models.VerifySignatureResult{}, fmt.Errorf("invalid typed data: %v", err)
+		}
+	} else {
+		message, err := decodeMessage(request.Message, request.Encoding)
+		if err != nil {
+			return models.VerifySignatureResult{}, err
+		}
+		hash = accounts.TextHash(message)
+	}
+
+	// Accept both {0, 1} and {27, 28} recovery ids
+	signature = append([]byte{}, signature...)
+	if signature[64] >= 27 {
+		signature[64] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return models.VerifySignatureResult{}, fmt.Errorf("failed to recover signer: %v", err)
+	}
+	recovered := crypto.PubkeyToAddress(*publicKey)
+
+	return models.VerifySignatureResult{
+		Valid:            recovered == common.HexToAddress(request.Address),
+		RecoveredAddress: recovered.Hex(),
+	}, nil
+}
+
+// signHash signs a 32-byte hash with the KMS key of one of the user's wallets.
+func (s *Service) signHash(ctx context.Context, address string, hash []byte, userId string) (models.SignatureResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if !common.IsHexAddress(address) {
+		return models.SignatureResult{}, fmt.Errorf("invalid address format")
+	}
+	walletAddress := common.HexToAddress(address)
+
+	wallet, err := s.authorizeWallet(ctx, walletAddress.Hex(), userId, PermissionSignMessages)
+	if err != nil {
+		return models.SignatureResult{}, err
+	}
+
+	signature, err := s.signWithWallet(ctx, wallet, userId, hash, models.WebAuthnChallenge{})
+	if err != nil {
+		return models.SignatureResult{}, err
+	}
+	signature[64] += 27
+	s.audit(ctx, models.AuditEntry{
+		Action:  models.AuditMessageSigned,
+		UserID:  userId,
+		Target:  walletAddress.Hex(),
+		Details: map[string]string{"hash": hexutil.Encode(hash)},
+	})
+
+	return models.SignatureResult{
+		Address:   walletAddress.Hex(),
+		Hash:      hexutil.Encode(hash),
+		Signature: hexutil.Encode(signature),
+	}, nil
+}
+
+func decodeMessage(message string, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", "utf8", "utf-8":
+		return []byte(message), nil
+	case "hex":
+		data, err := hexutil.Decode(message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex message: %v", err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unsupported message encoding: %s", encoding)
+}
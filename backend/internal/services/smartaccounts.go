@@ -0,0 +1,388 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/utils"
+	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// CreateSmartAccount registers the counterfactual address of a SimpleAccount
+// owned by one of the user's KMS wallets. The account contract is deployed by
+// its first user operation.
+func (s *Service) CreateSmartAccount(ctx context.Context, request models.SmartAccountCreateRequest, userId string) (models.SmartAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if !common.IsHexAddress(request.OwnerAddress) {
+		return models.SmartAccount{}, fmt.Errorf("invalid address format")
+	}
+	owner := common.HexToAddress(request.OwnerAddress)
+	if _, err := s.authorizeWallet(ctx, owner.Hex(), userId, PermissionTransact); err != nil {
+		return models.SmartAccount{}, fmt.Errorf("owner %s is not one of your wallets", owner.Hex())
+	}
+
+	salt := new(big.Int)
+	if request.Salt != "" {
+		var ok bool
+		if salt, ok = salt.SetString(request.Salt, 10); !ok || salt.Sign() < 0 {
+			return models.SmartAccount{}, fmt.Errorf("invalid salt: %s", request.Salt)
+		}
+	}
+
+	outputs, err := s.callMethod(ctx, s.accountAbstraction.AccountFactory, web3.AccountFactoryGetAddress, owner, salt)
+	if err != nil {
+		return models.SmartAccount{}, err
+	}
+	address := outputs[0].(common.Address)
+
+	if _, err := s.repo.GetSmartAccount(ctx, address.Hex()); err == nil {
+		return models.SmartAccount{}, fmt.Errorf("smart account %s already exists", address.Hex())
+	}
+
+	account := models.SmartAccount{
+		Name:         request.Name,
+		Address:      address.Hex(),
+		OwnerAddress: owner.Hex(),
+		Salt:         salt.String(),
+		Factory:      s.accountAbstraction.AccountFactory.Hex(),
+		EntryPoint:   s.accountAbstraction.EntryPoint.Hex(),
+		Balance:      "0",
+		UserID:       userId,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	return s.repo.SaveSmartAccount(ctx, &account)
+}
+
+func (s *Service) ListSmartAccounts(ctx context.Context, userId string) ([]models.SmartAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// A smart account is listed when the user can see its owner wallet
+	wallets, err := s.accessibleWallets(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.repo.ListSmartAccounts(ctx, walletAddresses(wallets))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range accounts {
+		if err := s.refreshSmartAccount(ctx, &accounts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return accounts, nil
+}
+
+func (s *Service) GetSmartAccount(ctx context.Context, address string, userId string) (models.SmartAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	account, _, err := s.authorizeSmartAccount(ctx, address, userId, PermissionViewWallet)
+	if err != nil {
+		return account, err
+	}
+
+	err = s.refreshSmartAccount(ctx, &account)
+	return account, err
+}
+
+// refreshSmartAccount fills in the on-chain balance and deployment state.
+func (s *Service) refreshSmartAccount(ctx context.Context, account *models.SmartAccount) error {
+	address := common.HexToAddress(account.Address)
+
+	balance, err := s.web3Client.BalanceAt(ctx, address, nil)
+	if err != nil {
+		return err
+	}
+	account.Balance = fmt.Sprintf("%f ETH", utils.WeiToEther(balance))
+
+	code, err := s.web3Client.CodeAt(ctx, address, nil)
+	if err != nil {
+		return err
+	}
+	account.Deployed = len(code) > 0
+
+	return nil
+}
+
+// SendUserOperation builds a user operation for the given calls, signs it with
+// the owner's KMS key and submits it to the bundler. The operation is recorded
+// as a pending transaction until GetUserOperation sees it included.
+func (s *Service) SendUserOperation(ctx context.Context, address string, request models.UserOperationRequest, userId string) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if s.bundler == nil {
+		return models.TransactionResult{}, fmt.Errorf("no ERC-4337 bundler configured")
+	}
+	if request.Sponsored && !s.bundler.CanSponsor() {
+		return models.TransactionResult{}, fmt.Errorf("no paymaster configured for sponsored operations")
+	}
+
+	account, owner, err := s.authorizeSmartAccount(ctx, address, userId, PermissionTransact)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+	sender := common.HexToAddress(account.Address)
+	entryPoint := common.HexToAddress(account.EntryPoint)
+
+	callData, method, err := encodeAccountCalls(request.Calls)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	// A single call is recorded as a transfer to its target
+	to := entryPoint
+	value := new(big.Int)
+	if len(request.Calls) == 1 {
+		to = common.HexToAddress(request.Calls[0].To)
+		if request.Calls[0].Value != "" {
+			if _, ok := value.SetString(request.Calls[0].Value, 10); !ok {
+				return models.TransactionResult{}, fmt.Errorf("invalid value: %s", request.Calls[0].Value)
+			}
+		}
+	}
+
+	outputs, err := s.callMethod(ctx, entryPoint, web3.EntryPointGetNonce, sender, new(big.Int))
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+	nonce := outputs[0].(*big.Int)
+
+	// Deploy the account with its first operation
+	var initCode []byte
+	code, err := s.web3Client.CodeAt(ctx, sender, nil)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+	if len(code) == 0 {
+		salt, _ := new(big.Int).SetString(account.Salt, 10)
+		createAccount, err := web3.Pack(web3.AccountFactoryCreateAccount, common.HexToAddress(account.OwnerAddress), salt)
+		if err != nil {
+			return models.TransactionResult{}, err
+		}
+		initCode = append(common.HexToAddress(account.Factory).Bytes(), createAccount...)
+	}
+
+	header, err := s.web3Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+	tip, err := s.web3Client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+	maxFee := new(big.Int).Add(new(big.Int).Mul(header.BaseFee, big.NewInt(2)), tip)
+
+	op := &web3.UserOperation{
+		Sender:               sender,
+		Nonce:                (*hexutil.Big)(nonce),
+		InitCode:             initCode,
+		CallData:             callData,
+		CallGasLimit:         new(hexutil.Big),
+		VerificationGasLimit: new(hexutil.Big),
+		PreVerificationGas:   new(hexutil.Big),
+		MaxFeePerGas:         (*hexutil.Big)(maxFee),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tip),
+		PaymasterAndData:     []byte{},
+		Signature:            web3.DummySignature,
+	}
+
+	var estimate web3.GasEstimate
+	if request.Sponsored {
+		op.PaymasterAndData, estimate, err = s.bundler.SponsorUserOperation(ctx, op, entryPoint)
+	} else {
+		estimate, err = s.bundler.EstimateUserOperationGas(ctx, op, entryPoint)
+	}
+	if err != nil {
+		return models.TransactionResult{}, fmt.Errorf("failed to estimate user operation gas: %v", err)
+	}
+	if estimate.CallGasLimit != nil {
+		op.CallGasLimit = (*hexutil.Big)(estimate.CallGasLimit)
+	}
+	if estimate.VerificationGasLimit != nil {
+		op.VerificationGasLimit = (*hexutil.Big)(estimate.VerificationGasLimit)
+	}
+	if estimate.PreVerificationGas != nil {
+		op.PreVerificationGas = (*hexutil.Big)(estimate.PreVerificationGas)
+	}
+
+	chainID, err := s.web3Client.ChainID(ctx)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	// A request retried with a passkey assertion sends the operation that was
+	// confirmed rather than one with fresh gas estimates
+	if confirmed := s.confirmedUserOperation(ctx, userId, op); confirmed != nil {
+		op = confirmed
+	}
+	pending, err := json.Marshal(op)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	// SimpleAccount expects an EIP-191 signature over the user operation hash
+	hash := op.Hash(entryPoint, chainID)
+	signature, err := s.signWithWallet(ctx, owner, userId, accounts.TextHash(hash.Bytes()), models.WebAuthnChallenge{
+		UserOperation: string(pending),
+	})
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+	signature[64] += 27
+	op.Signature = signature
+	s.audit(ctx, models.AuditEntry{
+		Action:  models.AuditUserOperationSigned,
+		UserID:  userId,
+		Target:  hash.Hex(),
+		Details: map[string]string{"account": sender.Hex(), "owner": owner.PublicKey},
+	})
+
+	userOpHash, err := s.bundler.SendUserOperation(ctx, op, entryPoint)
+	if err != nil {
+		return models.TransactionResult{}, fmt.Errorf("bundler rejected user operation: %v", err)
+	}
+
+	result := models.TransactionResult{
+		From:       sender.Hex(),
+		To:         to.Hex(),
+		GasPrice:   maxFee.String(),
+		Value:      value.String(),
+		Status:     models.TransactionStatusPending,
+		Method:     method,
+		Data:       hexutil.Encode(callData),
+		UserOpHash: userOpHash.Hex(),
+		UserID:     userId,
+	}
+
+	return s.transactions.SaveTransaction(ctx, &result)
+}
+
+// confirmedUserOperation returns the user operation prepared for the passkey
+// assertion the request carries, if it makes the same calls from the same
+// account as op.
+func (s *Service) confirmedUserOperation(ctx context.Context, userId string, op *web3.UserOperation) *web3.UserOperation {
+	challenge := s.pendingConfirmation(ctx, userId)
+	if challenge == nil || challenge.UserOperation == "" {
+		return nil
+	}
+	var confirmed web3.UserOperation
+	if err := json.Unmarshal([]byte(challenge.UserOperation), &confirmed); err != nil {
+		return nil
+	}
+	if confirmed.Sender != op.Sender || !bytes.Equal(confirmed.CallData, op.CallData) || (len(confirmed.PaymasterAndData) > 0) != (len(op.PaymasterAndData) > 0) {
+		return nil
+	}
+	return &confirmed
+}
+
+// GetUserOperation returns the transaction record of a user operation,
+// updating it from the bundler while it is pending.
+func (s *Service) GetUserOperation(ctx context.Context, address string, userOpHash string, userId string) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	account, _, err := s.authorizeSmartAccount(ctx, address, userId, PermissionViewWallet)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	transaction, err := s.transactions.GetTransactionByUserOpHash(ctx, userOpHash, account.Address)
+	if err != nil {
+		return transaction, err
+	}
+	if transaction.Status != models.TransactionStatusPending || s.bundler == nil {
+		return transaction, nil
+	}
+
+	receipt, err := s.bundler.GetUserOperationReceipt(ctx, common.HexToHash(userOpHash))
+	if err != nil {
+		return transaction, err
+	}
+	if receipt == nil {
+		return transaction, nil
+	}
+
+	transaction.TransactionHash = receipt.Receipt.TransactionHash.Hex()
+	if receipt.Receipt.BlockNumber != nil {
+		transaction.BlockNumber = receipt.Receipt.BlockNumber.ToInt().Uint64()
+	}
+	if receipt.ActualGasUsed != nil {
+		transaction.GasUsed = receipt.ActualGasUsed.ToInt().Uint64()
+	}
+	transaction.Status = models.TransactionStatusSuccess
+	if !receipt.Success {
+		transaction.Status = models.TransactionStatusReverted
+	}
+
+	if err := s.transactions.UpdateTransactionReceipt(ctx, &transaction); err != nil {
+		return transaction, err
+	}
+	metrics.CountTransaction(transaction.Status)
+	return transaction, nil
+}
+
+// encodeAccountCalls builds the SimpleAccount calldata for one or more calls.
+// executeBatch in SimpleAccount v0.6 cannot forward value, so batched calls
+// must not send ether.
+func encodeAccountCalls(calls []models.UserOperationCall) ([]byte, string, error) {
+	if len(calls) == 0 {
+		return nil, "", fmt.Errorf("at least one call is required")
+	}
+
+	targets := make([]common.Address, len(calls))
+	values := make([]*big.Int, len(calls))
+	payloads := make([][]byte, len(calls))
+	for i, call := range calls {
+		if !common.IsHexAddress(call.To) {
+			return nil, "", fmt.Errorf("call %d: invalid address format", i)
+		}
+		targets[i] = common.HexToAddress(call.To)
+
+		values[i] = new(big.Int)
+		if call.Value != "" {
+			if _, ok := values[i].SetString(call.Value, 10); !ok {
+				return nil, "", fmt.Errorf("call %d: invalid value: %s", i, call.Value)
+			}
+		}
+
+		payloads[i] = []byte{}
+		if call.Data != "" {
+			data, err := hexutil.Decode(call.Data)
+			if err != nil {
+				return nil, "", fmt.Errorf("call %d: invalid data: %v", i, err)
+			}
+			payloads[i] = data
+		}
+	}
+
+	if len(calls) == 1 {
+		data, err := web3.Pack(web3.SimpleAccountExecute, targets[0], values[0], payloads[0])
+		return data, web3.SimpleAccountExecute.Sig, err
+	}
+
+	for i, value := range values {
+		if value.Sign() != 0 {
+			return nil, "", fmt.Errorf("call %d: batched calls cannot send value", i)
+		}
+	}
+	data, err := web3.Pack(web3.SimpleAccountExecuteBatch, targets, payloads)
+	return data, web3.SimpleAccountExecuteBatch.Sig, err
+}
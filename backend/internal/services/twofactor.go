@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod        = 30
+	totpSkew          = 1
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+// ErrSecondFactorRequired is returned when an action needs a TOTP or
+// recovery code and none, or an invalid one, was given.
+var ErrSecondFactorRequired = errors.New("a valid two-factor code is required")
+
+// SetupTOTP generates a new TOTP secret for the user. It only takes effect
+// once a code generated from it is confirmed with EnableTOTP.
+func (s *Service) SetupTOTP(ctx context.Context, userId string) (models.TOTPSetup, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return models.TOTPSetup{}, err
+	}
+	if user.TOTPEnabled {
+		return models.TOTPSetup{}, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.totpIssuer,
+		AccountName: user.Username,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return models.TOTPSetup{}, fmt.Errorf("failed to generate TOTP secret: %v", err)
+	}
+
+	if err := s.users.SetPendingTOTPSecret(ctx, userId, key.Secret()); err != nil {
+		return models.TOTPSetup{}, err
+	}
+
+	return models.TOTPSetup{Secret: key.Secret(), ProvisioningURI: key.URL()}, nil
+}
+
+// EnableTOTP confirms the pending secret with a code and returns a fresh set
+// of recovery codes.
+func (s *Service) EnableTOTP(ctx context.Context, userId string, code string) (models.RecoveryCodes, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if user.TOTPEnabled {
+		return models.RecoveryCodes{}, fmt.Errorf("two-factor authentication is already enabled")
+	}
+	if user.TOTPPendingSecret == "" {
+		return models.RecoveryCodes{}, fmt.Errorf("two-factor setup has not been started")
+	}
+
+	step, ok := matchTOTP(user.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return models.RecoveryCodes{}, ErrSecondFactorRequired
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if err := s.users.EnableTOTP(ctx, userId, user.TOTPPendingSecret, step, hashes); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditTOTPEnabled, UserID: userId})
+
+	return models.RecoveryCodes{Codes: codes}, nil
+}
+
+// DisableTOTP turns two-factor authentication off after checking a code.
+func (s *Service) DisableTOTP(ctx context.Context, userId string, code string, clientIP string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return fmt.Errorf("two-factor authentication is not enabled")
+	}
+	if err := s.verifyStepUpCode(ctx, user, code, clientIP); err != nil {
+		return err
+	}
+
+	if err := s.users.DisableTOTP(ctx, userId); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditTOTPDisabled, UserID: userId})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a code.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userId string, code string, clientIP string) (models.RecoveryCodes, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if !user.TOTPEnabled {
+		return models.RecoveryCodes{}, fmt.Errorf("two-factor authentication is not enabled")
+	}
+	if err := s.verifyStepUpCode(ctx, user, code, clientIP); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if err := s.users.SetRecoveryCodes(ctx, userId, hashes); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+
+	return models.RecoveryCodes{Codes: codes}, nil
+}
+
+// CompleteLogin finishes a login that returned an MFA token, starting a
+// session once the TOTP or recovery code is verified. Wrong codes count as
+// failed logins.
+func (s *Service) CompleteLogin(ctx context.Context, mfaToken string, code string, clientIP string) (models.AuthTokens, error) {
+	token, err := jwt.Parse(mfaToken, s.jwtKey)
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != "mfa" {
+		return models.AuthTokens{}, fmt.Errorf("invalid mfa token")
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return models.AuthTokens{}, fmt.Errorf("invalid user_id in token")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+	if err := s.checkLoginAllowed(ctx, user.Username, clientIP); err != nil {
+		return models.AuthTokens{}, err
+	}
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		s.recordLoginFailure(ctx, user.Username, clientIP)
+		return models.AuthTokens{}, err
+	}
+	s.recordLoginSuccess(ctx, user.Username)
+	s.auditLogin(ctx, user.ID, clientIP, "totp")
+
+	return s.startSession(ctx, user.ID)
+}
+
+// VerifyStepUp checks the second factor for a sensitive action. Users who have
+// not enabled TOTP are let through.
+func (s *Service) VerifyStepUp(ctx context.Context, userId string, code string, clientIP string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return nil
+	}
+
+	return s.verifyStepUpCode(ctx, user, code, clientIP)
+}
+
+// verifyStepUpCode checks the second factor of a signed-in user. Wrong codes
+// count as failed logins, so guessing them is throttled and locks the
+// account out like guessing its password. A missing code is not a failure.
+func (s *Service) verifyStepUpCode(ctx context.Context, user *models.User, code string, clientIP string) error {
+	if strings.TrimSpace(code) == "" {
+		return ErrSecondFactorRequired
+	}
+	if err := s.checkLoginAllowed(ctx, user.Username, clientIP); err != nil {
+		return err
+	}
+	if err := s.verifySecondFactor(ctx, user, code); err != nil {
+		s.recordLoginFailure(ctx, user.Username, clientIP)
+		return err
+	}
+	s.recordLoginSuccess(ctx, user.Username)
+	return nil
+}
+
+// verifySecondFactor accepts either a current TOTP code, which can only be
+// used once, or an unused recovery code, which is consumed.
+func (s *Service) verifySecondFactor(ctx context.Context, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrSecondFactorRequired
+	}
+
+	if step, ok := matchTOTP(user.TOTPSecret, code, time.Now()); ok {
+		if err := s.users.AdvanceTOTPStep(ctx, user.ID, step); err != nil {
+			return ErrSecondFactorRequired
+		}
+		return nil
+	}
+
+	if err := s.users.UseRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		return ErrSecondFactorRequired
+	}
+	return nil
+}
+
+// issueMFAToken signs a short-lived token that only CompleteLogin accepts.
+func (s *Service) issueMFAToken(userId string) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userId,
+		"purpose": "mfa",
+		"iat":     now.Unix(),
+		"exp":     now.Add(mfaTokenTTL).Unix(),
+	})
+	token.Header["kid"] = s.jwtKeys.ActiveKID
+
+	return token.SignedString(s.jwtKeys.Keys[s.jwtKeys.ActiveKID])
+}
+
+// matchTOTP checks the code against the steps around t and returns the
+// matching step.
+func matchTOTP(secret string, code string, t time.Time) (int64, bool) {
+	if secret == "" {
+		return 0, false
+	}
+
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := hotp.GenerateCodeCustom(secret, uint64(step), hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateRecoveryCodes returns recovery codes formatted as xxxxx-xxxxx and
+// their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
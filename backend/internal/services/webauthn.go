@@ -0,0 +1,654 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const webAuthnChallengeTTL = 5 * time.Minute
+
+// ErrTransactionConfirmationRequired is returned when a user who requires
+// WebAuthn confirmation sends a transaction without one.
+var ErrTransactionConfirmationRequired = errors.New("transaction must be confirmed with a passkey")
+
+// webAuthnUser adapts a user and their credentials to webauthn.User.
+type webAuthnUser struct {
+	user        *models.User
+	credentials []models.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte          { return u.user.WebAuthnHandle }
+func (u *webAuthnUser) WebAuthnName() string        { return u.user.Username }
+func (u *webAuthnUser) WebAuthnDisplayName() string { return u.user.Username }
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, credential := range u.credentials {
+		credentials[i] = credential.Credential
+	}
+	return credentials
+}
+
+// BeginWebAuthnRegistration starts registering a passkey for a signed in user.
+func (s *Service) BeginWebAuthnRegistration(ctx context.Context, userId string) (models.WebAuthnCeremony, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.loadWebAuthnUser(ctx, userId)
+	if err != nil {
+		return models.WebAuthnCeremony{}, err
+	}
+
+	// Assign a user handle on the first registration
+	if len(user.user.WebAuthnHandle) == 0 {
+		handle, err := newWebAuthnHandle()
+		if err != nil {
+			return models.WebAuthnCeremony{}, err
+		}
+		if err := s.users.SetWebAuthnHandle(ctx, userId, handle); err != nil {
+			return models.WebAuthnCeremony{}, err
+		}
+		user.user.WebAuthnHandle = handle
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, len(user.credentials))
+	for i, credential := range user.credentials {
+		exclusions[i] = credential.Credential.Descriptor()
+	}
+
+	options, session, err := s.webAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return models.WebAuthnCeremony{}, fmt.Errorf("failed to begin registration: %v", err)
+	}
+
+	return s.saveWebAuthnChallenge(ctx, models.WebAuthnChallenge{
+		UserID:  userId,
+		Purpose: models.WebAuthnPurposeRegistration,
+		Session: *session,
+	}, options)
+}
+
+// FinishWebAuthnRegistration verifies the attestation and stores the passkey.
+func (s *Service) FinishWebAuthnRegistration(ctx context.Context, request models.WebAuthnFinishRequest, userId string) (models.WebAuthnCredential, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	challenge, err := s.repo.TakeWebAuthnChallenge(ctx, request.ChallengeID, models.WebAuthnPurposeRegistration)
+	if err != nil {
+		return models.WebAuthnCredential{}, err
+	}
+	if challenge.UserID != userId {
+		return models.WebAuthnCredential{}, fmt.Errorf("challenge not found or expired")
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, userId)
+	if err != nil {
+		return models.WebAuthnCredential{}, err
+	}
+
+	credential, err := s.createWebAuthnCredential(ctx, user, challenge, request)
+	if err != nil {
+		return models.WebAuthnCredential{}, err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditPasskeyAdded, UserID: userId, Target: credential.ID})
+	return credential, nil
+}
+
+// ListWebAuthnCredentials lists the user's passkeys.
+func (s *Service) ListWebAuthnCredentials(ctx context.Context, userId string) ([]models.WebAuthnCredential, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return s.repo.ListWebAuthnCredentials(ctx, userId)
+}
+
+// DeleteWebAuthnCredential removes a passkey. Passkey-only accounts must keep
+// at least one.
+func (s *Service) DeleteWebAuthnCredential(ctx context.Context, id string, userId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	user, err := s.loadWebAuthnUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if len(user.credentials) == 1 && user.credentials[0].ID == id {
+		if user.user.PasswordHash == "" {
+			return fmt.Errorf("cannot remove the only passkey of a passkey-only account")
+		}
+		if user.user.RequireWebAuthnForTransactions {
+			return fmt.Errorf("disable transaction confirmation before removing the last passkey")
+		}
+	}
+
+	if err := s.repo.DeleteWebAuthnCredential(ctx, id, userId); err != nil {
+		return err
+	}
+	s.audit(ctx, models.AuditEntry{Action: models.AuditPasskeyRemoved, UserID: userId, Target: id})
+	return nil
+}
+
+// SetWebAuthnTransactionConfirmation turns the requirement to confirm
+// transactions with a passkey on or off.
+func (s *Service) SetWebAuthnTransactionConfirmation(ctx context.Context, userId string, required bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if required {
+		credentials, err := s.repo.ListWebAuthnCredentials(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if len(credentials) == 0 {
+			return fmt.Errorf("register a passkey first")
+		}
+	}
+
+	if err := s.users.SetRequireWebAuthnForTransactions(ctx, userId, required); err != nil {
+		return err
+	}
+	if required {
+		// Scheduled transfers run unattended and cannot be confirmed
+		if _, err := s.repo.PauseUserSchedules(ctx, userId); err != nil {
+			return err
+		}
+	}
+	s.audit(ctx, models.AuditEntry{
+		Action:  models.AuditTransactionPolicy,
+		UserID:  userId,
+		Details: map[string]string{"required": strconv.FormatBool(required)},
+	})
+	return nil
+}
+
+// BeginPasskeySignUp starts creating a passkey-only account. The account is
+// only created once the passkey is registered.
+func (s *Service) BeginPasskeySignUp(ctx context.Context, username, email string) (models.WebAuthnCeremony, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	handle, err := newWebAuthnHandle()
+	if err != nil {
+		return models.WebAuthnCeremony{}, err
+	}
+	user := &webAuthnUser{user: &models.User{Username: username, WebAuthnHandle: handle}}
+
+	options, session, err := s.webAuthn.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return models.WebAuthnCeremony{}, fmt.Errorf("failed to begin registration: %v", err)
+	}
+
+	return s.saveWebAuthnChallenge(ctx, models.WebAuthnChallenge{
+		Purpose:    models.WebAuthnPurposeSignUp,
+		Session:    *session,
+		Username:   username,
+		Email:      email,
+		UserHandle: handle,
+	}, options)
+}
+
+// FinishPasskeySignUp verifies the attestation and creates the account with no
+// password. Like password sign ups, it is activated by verifying the email.
+func (s *Service) FinishPasskeySignUp(ctx context.Context, request models.WebAuthnFinishRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	challenge, err := s.repo.TakeWebAuthnChallenge(ctx, request.ChallengeID, models.WebAuthnPurposeSignUp)
+	if err != nil {
+		return err
+	}
+
+	// Verify the attestation before the account exists
+	pending := &webAuthnUser{user: &models.User{Username: challenge.Username, WebAuthnHandle: challenge.UserHandle}}
+	credential, err := s.verifyAttestation(pending, challenge, request)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.users.CreateUser(ctx, &models.User{
+		Username:       challenge.Username,
+		Email:          challenge.Email,
+		WebAuthnHandle: challenge.UserHandle,
+		CreatedAt:      now.Format(time.RFC3339),
+		UpdatedAt:      now.Format(time.RFC3339),
+		Status:         models.UserStatusPendingVerification,
+	}); err != nil {
+		return err
+	}
+	user, err := s.users.GetUserByWebAuthnHandle(ctx, challenge.UserHandle)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.saveCredential(ctx, user.ID, request.Name, credential); err != nil {
+		return err
+	}
+
+	s.sendVerificationEmailAfterSignUp(ctx, user)
+	return nil
+}
+
+// BeginPasskeyLogin starts a login with a discoverable credential.
+func (s *Service) BeginPasskeyLogin(ctx context.Context) (models.WebAuthnCeremony, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	options, session, err := s.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return models.WebAuthnCeremony{}, fmt.Errorf("failed to begin login: %v", err)
+	}
+
+	return s.saveWebAuthnChallenge(ctx, models.WebAuthnChallenge{
+		Purpose: models.WebAuthnPurposeLogin,
+		Session: *session,
+	}, options)
+}
+
+// FinishPasskeyLogin verifies the assertion and signs the user in. Passkeys
+// verify the user, so no TOTP code is asked for.
+func (s *Service) FinishPasskeyLogin(ctx context.Context, request models.WebAuthnFinishRequest, clientIP string) (models.AuthTokens, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	challenge, err := s.repo.TakeWebAuthnChallenge(ctx, request.ChallengeID, models.WebAuthnPurposeLogin)
+	if err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(request.Credential)
+	if err != nil {
+		return models.AuthTokens{}, fmt.Errorf("invalid credential: %v", err)
+	}
+
+	var user *webAuthnUser
+	credential, err := s.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		account, err := s.users.GetUserByWebAuthnHandle(ctx, userHandle)
+		if err != nil {
+			return nil, err
+		}
+		user, err = s.loadWebAuthnUser(ctx, account.ID)
+		return user, err
+	}, challenge.Session, parsed)
+	if err != nil {
+		return models.AuthTokens{}, fmt.Errorf("passkey verification failed: %v", err)
+	}
+
+	if err := s.recordCredentialUse(ctx, user, credential); err != nil {
+		return models.AuthTokens{}, err
+	}
+
+	s.auditLogin(ctx, user.user.ID, clientIP, "passkey")
+
+	return s.startSession(ctx, user.user.ID)
+}
+
+// PrepareTransaction builds an unsigned transaction, which may call a
+// contract, and starts a WebAuthn assertion whose challenge is the
+// transaction's signing hash, so the passkey confirms exactly the
+// transaction that will be signed.
+func (s *Service) PrepareTransaction(ctx context.Context, request models.TransactionPrepareRequest, userId string) (models.WebAuthnCeremony, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	value, ok := new(big.Int).SetString(request.Value, 10)
+	if !ok {
+		return models.WebAuthnCeremony{}, fmt.Errorf("invalid value: %s", request.Value)
+	}
+	var data []byte
+	if request.Data != "" {
+		var err error
+		if data, err = hexutil.Decode(request.Data); err != nil {
+			return models.WebAuthnCeremony{}, fmt.Errorf("invalid data: %v", err)
+		}
+	}
+
+	from := common.HexToAddress(request.FromAddress)
+	if _, err := s.authorizeWallet(ctx, from.Hex(), userId, PermissionTransact); err != nil {
+		return models.WebAuthnCeremony{}, err
+	}
+
+	tx, chainID, err := s.buildTransaction(ctx, outgoingTransaction{
+		From:   from,
+		To:     common.HexToAddress(request.ToAddress),
+		Value:  value,
+		Data:   data,
+		UserID: userId,
+	})
+	if err != nil {
+		return models.WebAuthnCeremony{}, err
+	}
+	prepared, err := prepareTransaction(from, tx, chainID)
+	if err != nil {
+		return models.WebAuthnCeremony{}, err
+	}
+
+	txHash := ethereumTypes.LatestSignerForChainID(chainID).Hash(tx)
+	ceremony, err := s.beginSignatureConfirmation(ctx, userId, models.WebAuthnPurposeTransaction, txHash.Bytes(), models.WebAuthnChallenge{
+		Transaction: prepared,
+	})
+	ceremony.TransactionHash = txHash.Hex()
+	return ceremony, err
+}
+
+// ConfirmTransaction verifies the passkey assertion over a prepared
+// transaction, then signs it with KMS and sends it.
+func (s *Service) ConfirmTransaction(ctx context.Context, request models.WebAuthnFinishRequest, userId string) (models.TransactionResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	challenge, err := s.repo.TakeWebAuthnChallenge(ctx, request.ChallengeID, models.WebAuthnPurposeTransaction)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+	if challenge.UserID != userId || challenge.Transaction == nil {
+		return models.TransactionResult{}, fmt.Errorf("challenge not found or expired")
+	}
+	if err := s.verifyAssertion(ctx, userId, challenge, request.Credential); err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	// Sign exactly the transaction the assertion was made over
+	tx, chainID, err := decodePreparedTransaction(challenge.Transaction)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	wallet, err := s.authorizeWallet(ctx, challenge.Transaction.From, userId, PermissionTransact)
+	if err != nil {
+		return models.TransactionResult{}, err
+	}
+
+	ctx = withConfirmedDigest(ctx, ethereumTypes.LatestSignerForChainID(chainID).Hash(tx).Bytes())
+	result, _, err := s.sendTransaction(ctx, wallet, outgoingTransaction{
+		From:   common.HexToAddress(challenge.Transaction.From),
+		To:     *tx.To(),
+		Value:  tx.Value(),
+		Data:   tx.Data(),
+		UserID: userId,
+	}, tx, chainID)
+	return result, err
+}
+
+// ConfirmationRequiredError is returned instead of signing for a user who
+// requires passkey confirmation. The request is confirmed by completing the
+// ceremony and sending it again with the assertion in the
+// X-WebAuthn-Assertion header.
+type ConfirmationRequiredError struct {
+	Ceremony models.WebAuthnCeremony
+}
+
+func (e *ConfirmationRequiredError) Error() string {
+	return ErrTransactionConfirmationRequired.Error()
+}
+
+func (e *ConfirmationRequiredError) Unwrap() error {
+	return ErrTransactionConfirmationRequired
+}
+
+type webAuthnAssertionKey struct{}
+
+type confirmedDigestKey struct{}
+
+// WithWebAuthnAssertion attaches the passkey assertion a request was sent
+// with, a JSON encoded WebAuthnFinishRequest, to confirm the signature the
+// request makes.
+func WithWebAuthnAssertion(ctx context.Context, assertion string) context.Context {
+	if assertion == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, webAuthnAssertionKey{}, assertion)
+}
+
+// withConfirmedDigest marks a digest as confirmed with a passkey, once the
+// assertion over it has been verified.
+func withConfirmedDigest(ctx context.Context, digest []byte) context.Context {
+	return context.WithValue(ctx, confirmedDigestKey{}, digest)
+}
+
+// requestAssertion decodes the assertion attached to ctx, if any.
+func requestAssertion(ctx context.Context) (*models.WebAuthnFinishRequest, error) {
+	assertion, ok := ctx.Value(webAuthnAssertionKey{}).(string)
+	if !ok {
+		return nil, nil
+	}
+	var request models.WebAuthnFinishRequest
+	if err := json.Unmarshal([]byte(assertion), &request); err != nil || request.ChallengeID == "" {
+		return nil, fmt.Errorf("invalid X-WebAuthn-Assertion header")
+	}
+	return &request, nil
+}
+
+// confirmSignature checks that the user confirmed the digest with a passkey:
+// either the digest was confirmed earlier in the request, or the request
+// carries an assertion over it. Otherwise a ceremony over the digest begins,
+// storing pending so the retried request can sign the same payload.
+func (s *Service) confirmSignature(ctx context.Context, userId string, digest []byte, pending models.WebAuthnChallenge) error {
+	if confirmed, ok := ctx.Value(confirmedDigestKey{}).([]byte); ok && bytes.Equal(confirmed, digest) {
+		return nil
+	}
+
+	assertion, err := requestAssertion(ctx)
+	if err != nil {
+		return err
+	}
+	if assertion == nil {
+		ceremony, err := s.beginSignatureConfirmation(ctx, userId, models.WebAuthnPurposeSignature, digest, pending)
+		if err != nil {
+			return err
+		}
+		return &ConfirmationRequiredError{Ceremony: ceremony}
+	}
+
+	challenge, err := s.repo.TakeWebAuthnChallenge(ctx, assertion.ChallengeID, models.WebAuthnPurposeSignature)
+	if err != nil {
+		return err
+	}
+	if challenge.UserID != userId {
+		return fmt.Errorf("challenge not found or expired")
+	}
+	if challenge.Digest != hexutil.Encode(digest) {
+		return fmt.Errorf("the passkey confirmed a different request")
+	}
+	return s.verifyAssertion(ctx, userId, challenge, assertion.Credential)
+}
+
+// pendingConfirmation returns the challenge the assertion attached to ctx
+// answers, without consuming it, so that a retried request can reuse the
+// transaction or user operation that was confirmed.
+func (s *Service) pendingConfirmation(ctx context.Context, userId string) *models.WebAuthnChallenge {
+	assertion, err := requestAssertion(ctx)
+	if err != nil || assertion == nil {
+		return nil
+	}
+	challenge, err := s.repo.GetWebAuthnChallenge(ctx, assertion.ChallengeID, models.WebAuthnPurposeSignature)
+	if err != nil || challenge.UserID != userId {
+		return nil
+	}
+	return &challenge
+}
+
+// beginSignatureConfirmation starts a WebAuthn assertion whose challenge is
+// the digest to be signed.
+func (s *Service) beginSignatureConfirmation(ctx context.Context, userId string, purpose string, digest []byte, challenge models.WebAuthnChallenge) (models.WebAuthnCeremony, error) {
+	user, err := s.loadWebAuthnUser(ctx, userId)
+	if err != nil {
+		return models.WebAuthnCeremony{}, err
+	}
+	if len(user.credentials) == 0 {
+		return models.WebAuthnCeremony{}, fmt.Errorf("register a passkey first")
+	}
+
+	options, session, err := s.webAuthn.BeginLogin(user, webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return models.WebAuthnCeremony{}, fmt.Errorf("failed to begin assertion: %v", err)
+	}
+
+	// Bind the assertion to the digest
+	options.Response.Challenge = protocol.URLEncodedBase64(digest)
+	session.Challenge = base64.RawURLEncoding.EncodeToString(digest)
+
+	challenge.UserID = userId
+	challenge.Purpose = purpose
+	challenge.Session = *session
+	challenge.Digest = hexutil.Encode(digest)
+	ceremony, err := s.saveWebAuthnChallenge(ctx, challenge, options)
+	ceremony.Digest = challenge.Digest
+	return ceremony, err
+}
+
+// verifyAssertion validates a passkey assertion against a challenge taken
+// from the store.
+func (s *Service) verifyAssertion(ctx context.Context, userId string, challenge models.WebAuthnChallenge, response json.RawMessage) error {
+	user, err := s.loadWebAuthnUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return fmt.Errorf("invalid credential: %v", err)
+	}
+	credential, err := s.webAuthn.ValidateLogin(user, challenge.Session, parsed)
+	if err != nil {
+		return fmt.Errorf("passkey verification failed: %v", err)
+	}
+	return s.recordCredentialUse(ctx, user, credential)
+}
+
+// prepareTransaction stores an unsigned transaction for confirmation.
+func prepareTransaction(from common.Address, tx *ethereumTypes.Transaction, chainID *big.Int) (*models.PreparedTransaction, error) {
+	rawTx, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &models.PreparedTransaction{
+		From:    from.Hex(),
+		RawTx:   hexutil.Encode(rawTx),
+		ChainID: chainID.String(),
+	}, nil
+}
+
+func decodePreparedTransaction(prepared *models.PreparedTransaction) (*ethereumTypes.Transaction, *big.Int, error) {
+	rawTx, err := hexutil.Decode(prepared.RawTx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx := new(ethereumTypes.Transaction)
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		return nil, nil, err
+	}
+	chainID, ok := new(big.Int).SetString(prepared.ChainID, 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid chain id: %s", prepared.ChainID)
+	}
+	return tx, chainID, nil
+}
+
+// createWebAuthnCredential verifies an attestation for an existing user and
+// stores the credential.
+func (s *Service) createWebAuthnCredential(ctx context.Context, user *webAuthnUser, challenge models.WebAuthnChallenge, request models.WebAuthnFinishRequest) (models.WebAuthnCredential, error) {
+	credential, err := s.verifyAttestation(user, challenge, request)
+	if err != nil {
+		return models.WebAuthnCredential{}, err
+	}
+	return s.saveCredential(ctx, user.user.ID, request.Name, credential)
+}
+
+func (s *Service) verifyAttestation(user *webAuthnUser, challenge models.WebAuthnChallenge, request models.WebAuthnFinishRequest) (*webauthn.Credential, error) {
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(request.Credential)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credential: %v", err)
+	}
+
+	credential, err := s.webAuthn.CreateCredential(user, challenge.Session, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("passkey registration failed: %v", err)
+	}
+	return credential, nil
+}
+
+func (s *Service) saveCredential(ctx context.Context, userId string, name string, credential *webauthn.Credential) (models.WebAuthnCredential, error) {
+	if name == "" {
+		name = "Passkey"
+	}
+
+	return s.repo.SaveWebAuthnCredential(ctx, &models.WebAuthnCredential{
+		UserID:       userId,
+		Name:         name,
+		CredentialID: base64.RawURLEncoding.EncodeToString(credential.ID),
+		Credential:   *credential,
+		CreatedAt:    time.Now().UTC(),
+	})
+}
+
+// recordCredentialUse stores the updated sign counter of the credential used
+// for an assertion, rejecting authenticators that look cloned.
+func (s *Service) recordCredentialUse(ctx context.Context, user *webAuthnUser, credential *webauthn.Credential) error {
+	if credential.Authenticator.CloneWarning {
+		return fmt.Errorf("passkey verification failed: authenticator may be cloned")
+	}
+
+	for _, stored := range user.credentials {
+		if bytes.Equal(stored.Credential.ID, credential.ID) {
+			now := time.Now().UTC()
+			stored.Credential = *credential
+			stored.LastUsedAt = &now
+			return s.repo.UpdateWebAuthnCredentialUse(ctx, &stored)
+		}
+	}
+	return fmt.Errorf("credential not found")
+}
+
+func (s *Service) loadWebAuthnUser(ctx context.Context, userId string) (*webAuthnUser, error) {
+	user, err := s.users.GetUserByID(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := s.repo.ListWebAuthnCredentials(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+func (s *Service) saveWebAuthnChallenge(ctx context.Context, challenge models.WebAuthnChallenge, options interface{}) (models.WebAuthnCeremony, error) {
+	challenge.ExpiresAt = time.Now().UTC().Add(webAuthnChallengeTTL)
+	saved, err := s.repo.SaveWebAuthnChallenge(ctx, &challenge)
+	if err != nil {
+		return models.WebAuthnCeremony{}, err
+	}
+	return models.WebAuthnCeremony{ChallengeID: saved.ID, Options: options}, nil
+}
+
+// newWebAuthnHandle returns a random 64 byte user handle.
+func newWebAuthnHandle() ([]byte, error) {
+	handle := make([]byte, 64)
+	if _, err := rand.Read(handle); err != nil {
+		return nil, err
+	}
+	return handle, nil
+}
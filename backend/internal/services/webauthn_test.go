@@ -0,0 +1,239 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/models"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/memory"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethereumTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// testAuthenticator is a software passkey for the relying party of
+// newTestService.
+type testAuthenticator struct {
+	id     []byte
+	handle []byte
+	key    *ecdsa.PrivateKey
+	count  uint32
+}
+
+// registerTestPasskey stores a passkey credential for the user.
+func registerTestPasskey(t *testing.T, store *memory.Store, userId string) *testAuthenticator {
+	t.Helper()
+	ctx := context.Background()
+
+	handle, err := newWebAuthnHandle()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetWebAuthnHandle(ctx, userId, handle); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: key.X.FillBytes(make([]byte, 32)),
+		YCoord: key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator := &testAuthenticator{id: []byte("test-passkey-" + userId), handle: handle, key: key}
+	_, err = store.SaveWebAuthnCredential(ctx, &models.WebAuthnCredential{
+		UserID:       userId,
+		Name:         "Test passkey",
+		CredentialID: base64.RawURLEncoding.EncodeToString(authenticator.id),
+		Credential: webauthn.Credential{
+			ID:              authenticator.id,
+			PublicKey:       publicKey,
+			AttestationType: "none",
+			Flags:           webauthn.CredentialFlags{UserPresent: true, UserVerified: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return authenticator
+}
+
+// assert answers the ceremony with an assertion over challenge, which a
+// genuine authenticator takes from the ceremony's options.
+func (a *testAuthenticator) assert(t *testing.T, challengeId string, challenge []byte) models.WebAuthnFinishRequest {
+	t.Helper()
+
+	clientData, err := json.Marshal(map[string]string{
+		"type":      "webauthn.get",
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    "http://localhost:3000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.count++
+	rpIdHash := sha256.Sum256([]byte("localhost"))
+	authenticatorData := append(rpIdHash[:], byte(protocol.FlagUserPresent|protocol.FlagUserVerified))
+	authenticatorData = binary.BigEndian.AppendUint32(authenticatorData, a.count)
+
+	clientDataHash := sha256.Sum256(clientData)
+	signed := sha256.Sum256(append(bytes.Clone(authenticatorData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, signed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	credential, err := json.Marshal(map[string]any{
+		"id":    encode(a.id),
+		"rawId": encode(a.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authenticatorData),
+			"signature":         encode(signature),
+			"userHandle":        encode(a.handle),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models.WebAuthnFinishRequest{ChallengeID: challengeId, Credential: credential}
+}
+
+// beginConfirmation asks for a passkey confirmation of digest and returns
+// the ceremony.
+func beginConfirmation(t *testing.T, service *Service, userId string, digest []byte) models.WebAuthnCeremony {
+	t.Helper()
+
+	var required *ConfirmationRequiredError
+	err := service.confirmSignature(context.Background(), userId, digest, models.WebAuthnChallenge{})
+	if !errors.As(err, &required) {
+		t.Fatalf("confirmSignature without an assertion returned %v", err)
+	}
+
+	// The authenticator signs the digest itself as the challenge
+	options, ok := required.Ceremony.Options.(*protocol.CredentialAssertion)
+	if !ok || !bytes.Equal(options.Response.Challenge, digest) || required.Ceremony.Digest != hexutil.Encode(digest) {
+		t.Fatalf("ceremony %+v is not bound to digest %x", required.Ceremony, digest)
+	}
+	return required.Ceremony
+}
+
+// confirmWith runs confirmSignature for digest with the assertion attached
+// to the request, as the X-WebAuthn-Assertion header does.
+func confirmWith(service *Service, userId string, digest []byte, assertion models.WebAuthnFinishRequest) error {
+	header, _ := json.Marshal(assertion)
+	ctx := WithWebAuthnAssertion(context.Background(), string(header))
+	return service.confirmSignature(ctx, userId, digest, models.WebAuthnChallenge{})
+}
+
+func TestSignatureConfirmationIsBoundToDigest(t *testing.T) {
+	service, store := newTestService(t)
+	user := createTestUser(t, store, "alice")
+	passkey := registerTestPasskey(t, store, user.ID)
+
+	digest := sha256.Sum256([]byte("send 1 ether to bob"))
+	other := sha256.Sum256([]byte("send 100 ether to mallory"))
+
+	t.Run("assertion over the digest", func(t *testing.T) {
+		ceremony := beginConfirmation(t, service, user.ID, digest[:])
+		assertion := passkey.assert(t, ceremony.ChallengeID, digest[:])
+		if err := confirmWith(service, user.ID, digest[:], assertion); err != nil {
+			t.Fatal(err)
+		}
+		// The challenge is used up
+		if err := confirmWith(service, user.ID, digest[:], assertion); err == nil {
+			t.Fatal("an assertion was accepted twice")
+		}
+	})
+
+	t.Run("assertion used for another request", func(t *testing.T) {
+		ceremony := beginConfirmation(t, service, user.ID, digest[:])
+		assertion := passkey.assert(t, ceremony.ChallengeID, digest[:])
+		if err := confirmWith(service, user.ID, other[:], assertion); err == nil {
+			t.Fatal("an assertion over one digest confirmed another")
+		}
+	})
+
+	t.Run("assertion over another challenge", func(t *testing.T) {
+		ceremony := beginConfirmation(t, service, user.ID, digest[:])
+		assertion := passkey.assert(t, ceremony.ChallengeID, other[:])
+		if err := confirmWith(service, user.ID, digest[:], assertion); err == nil {
+			t.Fatal("an assertion signed over another challenge was accepted")
+		}
+	})
+
+	t.Run("assertion by another user", func(t *testing.T) {
+		ceremony := beginConfirmation(t, service, user.ID, digest[:])
+		mallory := createTestUser(t, store, "mallory")
+		registerTestPasskey(t, store, mallory.ID)
+		assertion := passkey.assert(t, ceremony.ChallengeID, digest[:])
+		if err := confirmWith(service, mallory.ID, digest[:], assertion); err == nil {
+			t.Fatal("another user's challenge was accepted")
+		}
+	})
+}
+
+func TestConfirmTransactionChecksTheAssertion(t *testing.T) {
+	service, store := newTestService(t)
+	ctx := context.Background()
+	user := createTestUser(t, store, "alice")
+	passkey := registerTestPasskey(t, store, user.ID)
+
+	from := common.HexToAddress("0x1111111111111111111111111111111111111111")
+	chainID := big.NewInt(1337)
+	tx := ethereumTypes.NewTransaction(0, common.HexToAddress("0x2222222222222222222222222222222222222222"), big.NewInt(1), 21000, big.NewInt(1), nil)
+	prepared, err := prepareTransaction(from, tx, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txHash := ethereumTypes.LatestSignerForChainID(chainID).Hash(tx)
+
+	// What PrepareTransaction begins after building the transaction
+	ceremony, err := service.beginSignatureConfirmation(ctx, user.ID, models.WebAuthnPurposeTransaction, txHash.Bytes(), models.WebAuthnChallenge{Transaction: prepared})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := ceremony.Options.(*protocol.CredentialAssertion)
+	if !bytes.Equal(options.Response.Challenge, txHash.Bytes()) {
+		t.Fatalf("challenge %x is not the transaction's signing hash %x", []byte(options.Response.Challenge), txHash.Bytes())
+	}
+
+	// A passkey shown another transaction cannot confirm this one
+	otherTx := ethereumTypes.NewTransaction(0, common.HexToAddress("0x3333333333333333333333333333333333333333"), big.NewInt(100), 21000, big.NewInt(1), nil)
+	otherHash := ethereumTypes.LatestSignerForChainID(chainID).Hash(otherTx)
+	if _, err := service.ConfirmTransaction(ctx, passkey.assert(t, ceremony.ChallengeID, otherHash.Bytes()), user.ID); err == nil {
+		t.Fatal("an assertion over another transaction was accepted")
+	}
+
+	// The failed attempt used up the challenge
+	if _, err := service.ConfirmTransaction(ctx, passkey.assert(t, ceremony.ChallengeID, txHash.Bytes()), user.ID); err == nil {
+		t.Fatal("a challenge was accepted after a failed attempt")
+	}
+}
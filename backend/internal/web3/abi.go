@@ -0,0 +1,375 @@
+package web3
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ResolveMethod looks up a contract method. When abiJSON is set, method is
+// either the function name or its full signature (to pick an overload).
+// Without an ABI, method must be a function signature such as
+// "balanceOf(address)(uint256)" or "transfer(address,uint256) returns (bool)".
+func ResolveMethod(abiJSON string, method string) (abi.Method, error) {
+	if abiJSON == "" {
+		return ParseSignature(method)
+	}
+
+	contractABI, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return abi.Method{}, fmt.Errorf("invalid ABI: %v", err)
+	}
+
+	if m, ok := contractABI.Methods[method]; ok {
+		return m, nil
+	}
+	signature := strings.ReplaceAll(method, " ", "")
+	for _, m := range contractABI.Methods {
+		if m.Sig == signature {
+			return m, nil
+		}
+	}
+	return abi.Method{}, fmt.Errorf("method %s not found in ABI", method)
+}
+
+// ParseSignature builds a method from a human-readable function signature.
+// Output types are optional and only needed to decode call results.
+func ParseSignature(signature string) (abi.Method, error) {
+	signature = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(signature), "function "))
+
+	open := strings.Index(signature, "(")
+	if open <= 0 {
+		return abi.Method{}, fmt.Errorf("invalid function signature: %s", signature)
+	}
+	name := signature[:open]
+
+	inputList, rest, err := splitParenthesized(signature[open:])
+	if err != nil {
+		return abi.Method{}, fmt.Errorf("invalid function signature: %v", err)
+	}
+
+	var outputList string
+	rest = skipModifiers(rest)
+	if rest != "" {
+		outputList, rest, err = splitParenthesized(rest)
+		if err != nil || strings.TrimSpace(rest) != "" {
+			return abi.Method{}, fmt.Errorf("invalid function signature: %s", signature)
+		}
+	}
+
+	inputs, err := parseArguments(inputList)
+	if err != nil {
+		return abi.Method{}, err
+	}
+	outputs, err := parseArguments(outputList)
+	if err != nil {
+		return abi.Method{}, err
+	}
+
+	return abi.NewMethod(name, name, abi.Function, "", false, false, inputs, outputs), nil
+}
+
+// functionModifiers may appear between the inputs and outputs of a signature.
+var functionModifiers = map[string]bool{
+	"external": true, "public": true, "view": true, "pure": true, "nonpayable": true, "payable": true,
+}
+
+// skipModifiers drops the function modifiers and the returns keyword that
+// lead s, leaving the output list.
+func skipModifiers(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+		end := strings.IndexAny(s, " \t(")
+		if end < 0 {
+			end = len(s)
+		}
+		word := s[:end]
+		switch {
+		case functionModifiers[word]:
+			s = s[end:]
+		case word == "returns":
+			return strings.TrimSpace(s[end:])
+		default:
+			return s
+		}
+	}
+}
+
+// splitParenthesized returns the contents of the leading balanced
+// parenthesised group of s and the remainder after it.
+func splitParenthesized(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return "", "", fmt.Errorf("expected '(' in %q", s)
+	}
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[1:i], s[i+1:], nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("unbalanced parentheses in %q", s)
+}
+
+func parseArguments(list string) (abi.Arguments, error) {
+	var arguments abi.Arguments
+	if strings.TrimSpace(list) == "" {
+		return arguments, nil
+	}
+
+	for i, part := range strings.Split(list, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("empty argument type")
+		}
+		if strings.HasPrefix(fields[0], "(") || fields[0] == "tuple" {
+			return nil, fmt.Errorf("tuple arguments require a JSON ABI")
+		}
+
+		typ, err := abi.NewType(fields[0], "", nil)
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("arg%d", i)
+		if len(fields) > 1 {
+			name = fields[len(fields)-1]
+		}
+		arguments = append(arguments, abi.Argument{Name: name, Type: typ})
+	}
+	return arguments, nil
+}
+
+// EncodeCall ABI-encodes a call to method with JSON arguments, including the
+// four byte selector.
+func EncodeCall(method abi.Method, args []json.RawMessage) ([]byte, error) {
+	if len(args) != len(method.Inputs) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", method.Sig, len(method.Inputs), len(args))
+	}
+
+	values := make([]interface{}, len(args))
+	for i, raw := range args {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+
+		var arg interface{}
+		if err := decoder.Decode(&arg); err != nil {
+			return nil, fmt.Errorf("argument %d: %v", i, err)
+		}
+
+		value, err := convertArgument(method.Inputs[i].Type, arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s): %v", i, method.Inputs[i].Type.String(), err)
+		}
+		values[i] = value.Interface()
+	}
+
+	return Pack(method, values...)
+}
+
+// convertArgument turns a JSON-decoded value into the Go type the ABI packer expects.
+func convertArgument(typ abi.Type, arg interface{}) (reflect.Value, error) {
+	goType := typ.GetType()
+
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := toBigInt(arg)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if goType == reflect.TypeOf(&big.Int{}) {
+			return reflect.ValueOf(n), nil
+		}
+		if typ.T == abi.UintTy {
+			if n.Sign() < 0 || n.BitLen() > typ.Size {
+				return reflect.Value{}, fmt.Errorf("value out of range")
+			}
+			return reflect.ValueOf(n.Uint64()).Convert(goType), nil
+		}
+		if !n.IsInt64() || !fitsSigned(n, typ.Size) {
+			return reflect.Value{}, fmt.Errorf("value out of range")
+		}
+		return reflect.ValueOf(n.Int64()).Convert(goType), nil
+
+	case abi.BoolTy:
+		b, ok := arg.(bool)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected boolean")
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.StringTy:
+		s, ok := arg.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected string")
+		}
+		return reflect.ValueOf(s), nil
+
+	case abi.AddressTy:
+		s, ok := arg.(string)
+		if !ok || !common.IsHexAddress(s) {
+			return reflect.Value{}, fmt.Errorf("expected hex address")
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+
+	case abi.BytesTy:
+		s, ok := arg.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected hex string")
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+
+	case abi.FixedBytesTy:
+		s, ok := arg.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected hex string")
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if len(b) != typ.Size {
+			return reflect.Value{}, fmt.Errorf("expected %d bytes, got %d", typ.Size, len(b))
+		}
+		value := reflect.New(goType).Elem()
+		reflect.Copy(value, reflect.ValueOf(b))
+		return value, nil
+
+	case abi.SliceTy, abi.ArrayTy:
+		items, ok := arg.([]interface{})
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected array")
+		}
+		var value reflect.Value
+		if typ.T == abi.SliceTy {
+			value = reflect.MakeSlice(goType, len(items), len(items))
+		} else {
+			if len(items) != typ.Size {
+				return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", typ.Size, len(items))
+			}
+			value = reflect.New(goType).Elem()
+		}
+		for i, item := range items {
+			elem, err := convertArgument(*typ.Elem, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %v", i, err)
+			}
+			value.Index(i).Set(elem)
+		}
+		return value, nil
+
+	case abi.TupleTy:
+		value := reflect.New(goType).Elem()
+		for i, elemType := range typ.TupleElems {
+			var item interface{}
+			switch fields := arg.(type) {
+			case map[string]interface{}:
+				item = fields[typ.TupleRawNames[i]]
+			case []interface{}:
+				if len(fields) != len(typ.TupleElems) {
+					return reflect.Value{}, fmt.Errorf("expected %d tuple fields, got %d", len(typ.TupleElems), len(fields))
+				}
+				item = fields[i]
+			default:
+				return reflect.Value{}, fmt.Errorf("expected object or array")
+			}
+			field, err := convertArgument(*elemType, item)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %v", typ.TupleRawNames[i], err)
+			}
+			value.Field(i).Set(field)
+		}
+		return value, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("unsupported type")
+}
+
+// fitsSigned reports whether n is in the range of a two's complement integer
+// of the given number of bits.
+func fitsSigned(n *big.Int, bits int) bool {
+	if n.Sign() < 0 {
+		// -2^(bits-1) is the smallest value, so -n-1 must fit in bits-1 bits
+		return new(big.Int).Sub(new(big.Int).Neg(n), big.NewInt(1)).BitLen() < bits
+	}
+	return n.BitLen() < bits
+}
+
+func toBigInt(arg interface{}) (*big.Int, error) {
+	var s string
+	switch v := arg.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("expected integer")
+	}
+
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer: %s", s)
+	}
+	return n, nil
+}
+
+// DecodeOutputs unpacks the return data of a call into JSON-friendly values.
+// Integers are rendered as decimal strings and byte values as hex.
+func DecodeOutputs(method abi.Method, data []byte) ([]interface{}, error) {
+	values, err := method.Outputs.Unpack(data)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]interface{}, len(values))
+	for i, value := range values {
+		outputs[i] = formatOutput(method.Outputs[i].Type, reflect.ValueOf(value))
+	}
+	return outputs, nil
+}
+
+func formatOutput(typ abi.Type, value reflect.Value) interface{} {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		if n, ok := value.Interface().(*big.Int); ok {
+			return n.String()
+		}
+		return fmt.Sprintf("%d", value.Interface())
+	case abi.AddressTy:
+		return value.Interface().(common.Address).Hex()
+	case abi.BytesTy:
+		return hexutil.Encode(value.Bytes())
+	case abi.FixedBytesTy, abi.HashTy, abi.FunctionTy:
+		b := make([]byte, value.Len())
+		reflect.Copy(reflect.ValueOf(b), value)
+		return hexutil.Encode(b)
+	case abi.SliceTy, abi.ArrayTy:
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = formatOutput(*typ.Elem, value.Index(i))
+		}
+		return items
+	case abi.TupleTy:
+		fields := make(map[string]interface{}, len(typ.TupleElems))
+		for i, elemType := range typ.TupleElems {
+			fields[typ.TupleRawNames[i]] = formatOutput(*elemType, value.Field(i))
+		}
+		return fields
+	}
+	return value.Interface()
+}
@@ -0,0 +1,118 @@
+package web3
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		signature string
+		sig       string
+		inputs    []string
+		outputs   []string
+	}{
+		{
+			signature: "balanceOf(address)(uint256)",
+			sig:       "balanceOf(address)",
+			inputs:    []string{"arg0"},
+			outputs:   []string{"arg0"},
+		},
+		{
+			signature: "function transfer(address to, uint256 amount) external returns (bool)",
+			sig:       "transfer(address,uint256)",
+			inputs:    []string{"to", "amount"},
+			outputs:   []string{"arg0"},
+		},
+		{
+			// Names containing modifiers are kept intact
+			signature: "previewRedeem(uint256 viewCount) public view returns (uint256 previewAmount)",
+			sig:       "previewRedeem(uint256)",
+			inputs:    []string{"viewCount"},
+			outputs:   []string{"previewAmount"},
+		},
+		{
+			signature: "deposit(address payableTo) payable",
+			sig:       "deposit(address)",
+			inputs:    []string{"payableTo"},
+		},
+		{
+			signature: "purePrice() pure returns(uint256 purePrice)",
+			sig:       "purePrice()",
+			outputs:   []string{"purePrice"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.signature, func(t *testing.T) {
+			method, err := ParseSignature(test.signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if method.Sig != test.sig {
+				t.Errorf("sig = %q, want %q", method.Sig, test.sig)
+			}
+			if len(method.Inputs) != len(test.inputs) || len(method.Outputs) != len(test.outputs) {
+				t.Fatalf("inputs = %v, outputs = %v", method.Inputs, method.Outputs)
+			}
+			for i, name := range test.inputs {
+				if method.Inputs[i].Name != name {
+					t.Errorf("input %d = %q, want %q", i, method.Inputs[i].Name, name)
+				}
+			}
+			for i, name := range test.outputs {
+				if method.Outputs[i].Name != name {
+					t.Errorf("output %d = %q, want %q", i, method.Outputs[i].Name, name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSignatureErrors(t *testing.T) {
+	for _, signature := range []string{
+		"transfer",
+		"transfer(address",
+		"transfer(address) returns (bool) extra",
+		"swap((address,uint256))",
+	} {
+		if _, err := ParseSignature(signature); err == nil {
+			t.Errorf("ParseSignature(%q) succeeded", signature)
+		}
+	}
+}
+
+func TestEncodeCallIntegerRange(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		ok    bool
+	}{
+		{"int8", "-128", true},
+		{"int8", "127", true},
+		{"int8", "-129", false},
+		{"int8", "128", false},
+		{"int64", "-9223372036854775808", true},
+		{"int64", "9223372036854775807", true},
+		{"int64", "9223372036854775808", false},
+		{"uint8", "255", true},
+		{"uint8", "256", false},
+		{"uint8", "-1", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.typ+" "+test.value, func(t *testing.T) {
+			method, err := ParseSignature("set(" + test.typ + ")")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = EncodeCall(method, []json.RawMessage{json.RawMessage(`"` + test.value + `"`)})
+			if test.ok && err != nil {
+				t.Errorf("encoding failed: %v", err)
+			}
+			if !test.ok && err == nil {
+				t.Error("out of range value was encoded")
+			}
+		})
+	}
+}
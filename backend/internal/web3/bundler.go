@@ -0,0 +1,151 @@
+package web3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BundlerClient talks to an ERC-4337 bundler and, optionally, a paymaster
+// service that sponsors user operations.
+type BundlerClient struct {
+	bundler   *rpc.Client
+	paymaster *rpc.Client
+}
+
+// ConnectBundler dials the bundler and the optional paymaster. It returns nil
+// when no bundler is configured.
+func ConnectBundler(bundlerURL string, paymasterURL string) (*BundlerClient, error) {
+	if bundlerURL == "" {
+		return nil, nil
+	}
+
+	bundler, err := dialRPC(bundlerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to bundler: %v", err)
+	}
+
+	client := &BundlerClient{bundler: bundler}
+	if paymasterURL != "" {
+		client.paymaster, err = dialRPC(paymasterURL)
+		if err != nil {
+			bundler.Close()
+			return nil, fmt.Errorf("failed to connect to paymaster: %v", err)
+		}
+	}
+
+	fmt.Println("Connected to ERC-4337 bundler")
+	return client, nil
+}
+
+func (c *BundlerClient) Close() {
+	c.bundler.Close()
+	if c.paymaster != nil {
+		c.paymaster.Close()
+	}
+}
+
+// CanSponsor reports whether a paymaster service is configured.
+func (c *BundlerClient) CanSponsor() bool {
+	return c.paymaster != nil
+}
+
+// GasEstimate holds the gas limits returned by the bundler or paymaster.
+type GasEstimate struct {
+	PreVerificationGas   *big.Int
+	VerificationGasLimit *big.Int
+	CallGasLimit         *big.Int
+}
+
+// EstimateUserOperationGas calls eth_estimateUserOperationGas.
+func (c *BundlerClient) EstimateUserOperationGas(ctx context.Context, op *UserOperation, entryPoint common.Address) (GasEstimate, error) {
+	var result map[string]json.RawMessage
+	if err := c.bundler.CallContext(ctx, &result, "eth_estimateUserOperationGas", op, entryPoint); err != nil {
+		return GasEstimate{}, err
+	}
+	return parseGasEstimate(result)
+}
+
+// SponsorUserOperation asks the paymaster to sponsor the operation through
+// pm_sponsorUserOperation and returns its paymasterAndData and gas limits.
+func (c *BundlerClient) SponsorUserOperation(ctx context.Context, op *UserOperation, entryPoint common.Address) ([]byte, GasEstimate, error) {
+	if c.paymaster == nil {
+		return nil, GasEstimate{}, fmt.Errorf("no paymaster configured")
+	}
+
+	var result map[string]json.RawMessage
+	if err := c.paymaster.CallContext(ctx, &result, "pm_sponsorUserOperation", op, entryPoint); err != nil {
+		return nil, GasEstimate{}, err
+	}
+
+	var paymasterAndData hexutil.Bytes
+	if err := json.Unmarshal(result["paymasterAndData"], &paymasterAndData); err != nil {
+		return nil, GasEstimate{}, fmt.Errorf("invalid paymasterAndData: %v", err)
+	}
+	estimate, err := parseGasEstimate(result)
+	return paymasterAndData, estimate, err
+}
+
+// SendUserOperation calls eth_sendUserOperation and returns the user operation hash.
+func (c *BundlerClient) SendUserOperation(ctx context.Context, op *UserOperation, entryPoint common.Address) (common.Hash, error) {
+	var hash common.Hash
+	err := c.bundler.CallContext(ctx, &hash, "eth_sendUserOperation", op, entryPoint)
+	return hash, err
+}
+
+// UserOperationReceipt is the subset of eth_getUserOperationReceipt used by the service.
+type UserOperationReceipt struct {
+	Success       bool         `json:"success"`
+	ActualGasUsed *hexutil.Big `json:"actualGasUsed"`
+	Reason        string       `json:"reason"`
+	Receipt       struct {
+		TransactionHash common.Hash  `json:"transactionHash"`
+		BlockNumber     *hexutil.Big `json:"blockNumber"`
+	} `json:"receipt"`
+}
+
+// GetUserOperationReceipt returns the receipt of an included user operation,
+// or nil while it is still pending.
+func (c *BundlerClient) GetUserOperationReceipt(ctx context.Context, hash common.Hash) (*UserOperationReceipt, error) {
+	var receipt *UserOperationReceipt
+	err := c.bundler.CallContext(ctx, &receipt, "eth_getUserOperationReceipt", hash)
+	return receipt, err
+}
+
+// parseGasEstimate reads gas values that bundlers return either as hex
+// strings or as JSON numbers.
+func parseGasEstimate(result map[string]json.RawMessage) (GasEstimate, error) {
+	var estimate GasEstimate
+	fields := map[string]**big.Int{
+		"preVerificationGas":   &estimate.PreVerificationGas,
+		"verificationGasLimit": &estimate.VerificationGasLimit,
+		"callGasLimit":         &estimate.CallGasLimit,
+	}
+
+	for name, target := range fields {
+		raw, ok := result[name]
+		if !ok {
+			continue
+		}
+
+		// Quoted values may be hex or decimal strings
+		text := string(raw)
+		var quoted string
+		if err := json.Unmarshal(raw, &quoted); err == nil {
+			text = quoted
+		}
+
+		value, ok := new(big.Int).SetString(text, 0)
+		if !ok {
+			return estimate, fmt.Errorf("invalid %s: %s", name, raw)
+		}
+		*target = value
+	}
+
+	return estimate, nil
+}
@@ -3,16 +3,32 @@ package web3
 import (
 	"context"
 	"fmt"
-	"os"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
-func Connect() (*ethclient.Client, error) {
-	web3Client, err := ethclient.Dial(os.Getenv("SEPOLIA_URL"))
+// Client is the part of the Ethereum JSON-RPC API the services use. It is
+// implemented by *ethclient.Client and by go-ethereum's simulated backend.
+type Client interface {
+	ethereum.ChainReader
+	ethereum.ChainIDReader
+	ethereum.ChainStateReader
+	ethereum.PendingStateReader
+	ethereum.ContractCaller
+	ethereum.GasEstimator
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+}
+
+func Connect(rpcURL string) (*ethclient.Client, error) {
+	rpcClient, err := dialRPC(rpcURL)
 	if err != nil {
 		return nil, err
 	}
+	web3Client := ethclient.NewClient(rpcClient)
 
 	// Get the current block number to confirm connection
 	blockNumber, err := web3Client.BlockNumber(context.Background())
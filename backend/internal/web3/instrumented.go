@@ -0,0 +1,105 @@
+package web3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
+	"github.com/natneam/crypto-wallet-app/backend/internal/tracing"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// dialRPC connects to a JSON-RPC endpoint, tracing the calls sent over HTTP
+// and counting them in the metrics. Calls over WebSocket or IPC are not
+// instrumented.
+func dialRPC(rawURL string) (*rpc.Client, error) {
+	transport := &rpcTransport{node: nodeName(rawURL), next: http.DefaultTransport}
+	return rpc.DialOptions(context.Background(), rawURL, rpc.WithHTTPClient(&http.Client{Transport: transport}))
+}
+
+// nodeName labels the calls to an endpoint with its host only, as provider
+// URLs often carry an API key in the path.
+func nodeName(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "unknown"
+	}
+	return parsed.Host
+}
+
+// rpcTransport traces each request it sends and counts its JSON-RPC
+// methods, including the calls in batches.
+type rpcTransport struct {
+	node string
+	next http.RoundTripper
+}
+
+func (t *rpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var methods []string
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		methods = rpcMethods(body)
+		for _, method := range methods {
+			metrics.CountRPCCall(t.node, method)
+		}
+	}
+
+	name := "rpc batch"
+	if len(methods) == 1 {
+		name = "rpc " + methods[0]
+	}
+	ctx, span := tracing.StartClient(req.Context(), name,
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.StringSlice("rpc.methods", methods),
+		attribute.String("server.address", t.node),
+	)
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	failure := err
+	if err == nil && resp.StatusCode/100 != 2 {
+		failure = fmt.Errorf("node responded with %s", resp.Status)
+	}
+	if failure != nil {
+		metrics.CountRPCError(t.node)
+	}
+	tracing.End(span, failure)
+	return resp, err
+}
+
+// rpcMethods returns the methods called by a JSON-RPC request or batch.
+func rpcMethods(body []byte) []string {
+	type call struct {
+		Method string `json:"method"`
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []call
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil
+		}
+		methods := make([]string, len(batch))
+		for i, c := range batch {
+			methods[i] = c.Method
+		}
+		return methods
+	}
+
+	var single call
+	if err := json.Unmarshal(body, &single); err != nil {
+		return nil
+	}
+	return []string{single.Method}
+}
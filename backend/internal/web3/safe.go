@@ -0,0 +1,59 @@
+package web3
+
+import (
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Safe{Wallet} v1.4.1 canonical deployments, available on Sepolia and most
+// other networks.
+const (
+	defaultSafeProxyFactory    = "0x4e1DCf7AD4e460CfD30791CCC4F9c8a4f820ec67"
+	defaultSafeSingleton       = "0x41675C099F32341bf84BFc5382aF534df5C7461a"
+	defaultSafeFallbackHandler = "0xfd0732Dc9E303f09fCEf3a7388Ad10A83459Ec99"
+)
+
+// SafeDeployment holds the contracts used to deploy new Safe proxies.
+type SafeDeployment struct {
+	ProxyFactory    common.Address
+	Singleton       common.Address
+	FallbackHandler common.Address
+}
+
+// NewSafeDeployment returns the configured Safe contracts, falling back to
+// the canonical v1.4.1 deployment.
+func NewSafeDeployment(contracts config.Contracts) SafeDeployment {
+	return SafeDeployment{
+		ProxyFactory:    addressOr(contracts.SafeProxyFactory, defaultSafeProxyFactory),
+		Singleton:       addressOr(contracts.SafeSingleton, defaultSafeSingleton),
+		FallbackHandler: addressOr(contracts.SafeFallbackHandler, defaultSafeFallbackHandler),
+	}
+}
+
+func addressOr(address string, fallback string) common.Address {
+	if address != "" {
+		return common.HexToAddress(address)
+	}
+	return common.HexToAddress(fallback)
+}
+
+// Safe operations.
+const (
+	SafeOperationCall         uint8 = 0
+	SafeOperationDelegateCall uint8 = 1
+)
+
+// ProxyCreationTopic is the topic of the SafeProxyFactory ProxyCreation event.
+var ProxyCreationTopic = crypto.Keccak256Hash([]byte("ProxyCreation(address,address)"))
+
+// Methods of the Safe proxy factory and Safe singleton.
+var (
+	SafeCreateProxyWithNonce = mustParseSignature("createProxyWithNonce(address,bytes,uint256)(address)")
+	SafeSetup                = mustParseSignature("setup(address[],uint256,address,bytes,address,address,uint256,address)")
+	SafeNonce                = mustParseSignature("nonce()(uint256)")
+	SafeGetThreshold         = mustParseSignature("getThreshold()(uint256)")
+	SafeGetTransactionHash   = mustParseSignature("getTransactionHash(address,uint256,bytes,uint8,uint256,uint256,uint256,address,address,uint256)(bytes32)")
+	SafeExecTransaction      = mustParseSignature("execTransaction(address,uint256,bytes,uint8,uint256,uint256,uint256,address,address,bytes)(bool)")
+)
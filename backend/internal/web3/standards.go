@@ -0,0 +1,55 @@
+package web3
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// Token standards supported by the wallet.
+const (
+	StandardERC721  = "erc721"
+	StandardERC1155 = "erc1155"
+)
+
+// ERC-165 interface identifiers.
+var (
+	InterfaceIDERC721           = [4]byte{0x80, 0xac, 0x58, 0xcd}
+	InterfaceIDERC721Enumerable = [4]byte{0x78, 0x0e, 0x9d, 0x63}
+	InterfaceIDERC1155          = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+)
+
+// Methods of the token standards used by the services.
+var (
+	SupportsInterface = mustParseSignature("supportsInterface(bytes4)(bool)")
+
+	ERC20Allowance = mustParseSignature("allowance(address,address)(uint256)")
+	ERC20Approve   = mustParseSignature("approve(address,uint256)(bool)")
+	ERC20Symbol    = mustParseSignature("symbol()(string)")
+	ERC20Decimals  = mustParseSignature("decimals()(uint8)")
+
+	ERC721BalanceOf           = mustParseSignature("balanceOf(address)(uint256)")
+	ERC721TokenOfOwnerByIndex = mustParseSignature("tokenOfOwnerByIndex(address,uint256)(uint256)")
+	ERC721TokenURI            = mustParseSignature("tokenURI(uint256)(string)")
+	ERC721SafeTransferFrom    = mustParseSignature("safeTransferFrom(address,address,uint256,bytes)")
+
+	ERC1155BalanceOfBatch   = mustParseSignature("balanceOfBatch(address[],uint256[])(uint256[])")
+	ERC1155URI              = mustParseSignature("uri(uint256)(string)")
+	ERC1155SafeTransferFrom = mustParseSignature("safeTransferFrom(address,address,uint256,uint256,bytes)")
+)
+
+func mustParseSignature(signature string) abi.Method {
+	method, err := ParseSignature(signature)
+	if err != nil {
+		panic(err)
+	}
+	return method
+}
+
+// Pack ABI-encodes a call to method with Go-typed arguments, including the
+// four byte selector.
+func Pack(method abi.Method, args ...interface{}) ([]byte, error) {
+	packed, err := method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, method.ID...), packed...), nil
+}
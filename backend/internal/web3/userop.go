@@ -0,0 +1,98 @@
+package web3
+
+import (
+	"math/big"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ERC-4337 v0.6 canonical deployments of the EntryPoint and the reference
+// SimpleAccount factory.
+const (
+	defaultEntryPoint           = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+	defaultSimpleAccountFactory = "0x9406Cc6185a346906296840746125a0E44976454"
+)
+
+// AccountAbstraction holds the contracts used for smart accounts.
+type AccountAbstraction struct {
+	EntryPoint     common.Address
+	AccountFactory common.Address
+}
+
+// NewAccountAbstraction returns the configured ERC-4337 contracts, falling
+// back to the canonical v0.6 deployment.
+func NewAccountAbstraction(contracts config.Contracts) AccountAbstraction {
+	return AccountAbstraction{
+		EntryPoint:     addressOr(contracts.EntryPoint, defaultEntryPoint),
+		AccountFactory: addressOr(contracts.AccountFactory, defaultSimpleAccountFactory),
+	}
+}
+
+// Methods of the EntryPoint, SimpleAccountFactory and SimpleAccount.
+var (
+	EntryPointGetNonce          = mustParseSignature("getNonce(address,uint192)(uint256)")
+	AccountFactoryGetAddress    = mustParseSignature("getAddress(address,uint256)(address)")
+	AccountFactoryCreateAccount = mustParseSignature("createAccount(address,uint256)(address)")
+	SimpleAccountExecute        = mustParseSignature("execute(address,uint256,bytes)")
+	SimpleAccountExecuteBatch   = mustParseSignature("executeBatch(address[],bytes[])")
+)
+
+// DummySignature is a well-formed ECDSA signature used while estimating gas,
+// before the real signature over the final gas values can be produced.
+var DummySignature = hexutil.MustDecode("0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c")
+
+// UserOperation is an ERC-4337 v0.6 user operation in its JSON-RPC form.
+type UserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+var (
+	userOpPackArguments = mustArguments("address", "uint256", "bytes32", "bytes32", "uint256", "uint256", "uint256", "uint256", "uint256", "bytes32")
+	userOpHashArguments = mustArguments("bytes32", "address", "uint256")
+)
+
+// Hash returns the user operation hash the account owner signs, as computed
+// by EntryPoint.getUserOpHash.
+func (op *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) common.Hash {
+	packed, _ := userOpPackArguments.Pack(
+		op.Sender,
+		op.Nonce.ToInt(),
+		crypto.Keccak256Hash(op.InitCode),
+		crypto.Keccak256Hash(op.CallData),
+		op.CallGasLimit.ToInt(),
+		op.VerificationGasLimit.ToInt(),
+		op.PreVerificationGas.ToInt(),
+		op.MaxFeePerGas.ToInt(),
+		op.MaxPriorityFeePerGas.ToInt(),
+		crypto.Keccak256Hash(op.PaymasterAndData),
+	)
+	encoded, _ := userOpHashArguments.Pack(crypto.Keccak256Hash(packed), entryPoint, chainID)
+	return crypto.Keccak256Hash(encoded)
+}
+
+func mustArguments(types ...string) abi.Arguments {
+	arguments := make(abi.Arguments, len(types))
+	for i, t := range types {
+		typ, err := abi.NewType(t, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		arguments[i] = abi.Argument{Type: typ}
+	}
+	return arguments
+}
@@ -2,84 +2,219 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/natneam/crypto-wallet-app/backend/internal/config"
 	"github.com/natneam/crypto-wallet-app/backend/internal/db"
 	"github.com/natneam/crypto-wallet-app/backend/internal/handlers"
 	"github.com/natneam/crypto-wallet-app/backend/internal/kms"
+	"github.com/natneam/crypto-wallet-app/backend/internal/mailer"
+	"github.com/natneam/crypto-wallet-app/backend/internal/metrics"
 	"github.com/natneam/crypto-wallet-app/backend/internal/middlewares"
 	"github.com/natneam/crypto-wallet-app/backend/internal/repositories"
+	"github.com/natneam/crypto-wallet-app/backend/internal/repositories/postgres"
 	"github.com/natneam/crypto-wallet-app/backend/internal/services"
+	"github.com/natneam/crypto-wallet-app/backend/internal/tracing"
 	"github.com/natneam/crypto-wallet-app/backend/internal/web3"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 type Application struct {
-	Router  *gin.Engine
+	Router *gin.Engine
+	// AdminRouter serves the operator routes on the admin port
+	AdminRouter *gin.Engine
+	Service     *services.Service
+
+	// Workers run in the background while the server is up
+	Workers []Worker
 	Cleanup func()
 }
 
-func NewApplication() (*Application, error) {
-	// Load configuration
-	config.Load()
+func NewApplication(cfg *config.Config) (*Application, error) {
+	// Tracing is set up first, so the clients created below are traced
+	shutdownTracing, err := tracing.Setup(cfg.Tracing, services.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set up the passkey relying party and discover the identity provider
+	webAuthn, err := cfg.WebAuthn.RelyingParty()
+	if err != nil {
+		return nil, err
+	}
+	oidcProvider, err := cfg.OIDC.Provider()
+	if err != nil {
+		return nil, err
+	}
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize database connection
-	dbClient, err := db.Connect()
+	dbClient, err := db.Connect(cfg.Mongo.URI)
 	if err != nil {
 		return nil, err
 	}
 
 	// Initialize web3 connection
-	web3Client, err := web3.Connect()
+	web3Client, err := web3.Connect(cfg.Ethereum.RPCURL)
 	if err != nil {
 		return nil, err
 	}
 
-	// Initialize KMS client
-	kmsClient, err := kms.NewKMSClient()
+	// Initialize the optional ERC-4337 bundler connection
+	bundlerClient, err := web3.ConnectBundler(cfg.Ethereum.BundlerURL, cfg.Ethereum.PaymasterURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initialize the wallet key manager
+	keyManager, err := kms.NewKeyManager(cfg.KMS.Provider)
 	if err != nil {
 		return nil, err
 	}
 
 	// Initialize database
-	err = db.InitDatabase(dbClient)
+	err = db.InitDatabase(dbClient, cfg.Mongo.Database)
 	if err != nil {
 		return nil, err
 	}
 
 	// Initialize repository
-	repository := repositories.NewRepository(dbClient)
+	repository := repositories.NewRepository(dbClient, cfg.Mongo.Database)
+
+	// Initialize the user, wallet and transaction stores
+	stores, closeStores, err := openStores(cfg.Storage, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report the number of wallets on every metrics scrape
+	metrics.RegisterWalletCount(stores.Wallets.CountWallets)
 
 	// Initialize services
-	service := services.NewService(repository, web3Client, kmsClient)
+	service := services.NewService(services.Dependencies{
+		Config:   cfg,
+		Repo:     repository,
+		Stores:   stores,
+		Web3:     web3Client,
+		Keys:     keyManager,
+		Bundler:  bundlerClient,
+		WebAuthn: webAuthn,
+		Mailer:   mail,
+		OIDC:     oidcProvider,
+	})
+
+	// Background scheduler for scheduled transfers
+	scheduler := services.NewScheduler(service, 30*time.Second)
 
 	// Set up the router
 	router := gin.Default()
-	router.Use(middlewares.CORSMiddleware())
+	router.Use(
+		otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(tracedRequest)),
+		middlewares.MetricsMiddleware(),
+		middlewares.CORSMiddleware(),
+	)
+
+	// Client IPs are used to restrict API keys, so forwarded headers are only
+	// trusted from the proxies listed in TRUSTED_PROXIES
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
 
 	// Initialize handlers
 	handlers.NewHandler(router, service)
 
+	// Operator routes are kept off the public router
+	adminRouter := gin.New()
+	adminRouter.Use(gin.Recovery())
+	handlers.NewAdminHandler(adminRouter, service)
+
 	return &Application{
-		Router: router,
+		Router:      router,
+		AdminRouter: adminRouter,
+		Service:     service,
+		Workers:     []Worker{scheduler},
 		Cleanup: func() {
+			closeStores()
 			dbClient.Disconnect(context.Background())
 			web3Client.Close()
+			if bundlerClient != nil {
+				bundlerClient.Close()
+			}
+
+			// Export the spans of the last requests
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				log.Printf("Failed to flush traces: %v", err)
+			}
 		},
 	}, nil
 }
 
+// tracedRequest leaves probes out of the traces.
+func tracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz":
+		return false
+	}
+	return true
+}
+
+// openStores returns the stores of the configured backend: "mongo" or
+// "postgres", which keeps users, wallets and transactions in PostgreSQL. The
+// repository keeps everything else in MongoDB either way.
+func openStores(storage config.Storage, repository *repositories.Repository) (repositories.Stores, func(), error) {
+	switch storage.Backend {
+	case "", "mongo":
+		return repository.Stores(), func() {}, nil
+	case "postgres":
+		store, err := postgres.Open(storage.PostgresURL)
+		if err != nil {
+			return repositories.Stores{}, nil, err
+		}
+		log.Println("Storing users, wallets and transactions in PostgreSQL and everything else in MongoDB")
+		return store.Stores(), store.Close, nil
+	default:
+		return repositories.Stores{}, nil, fmt.Errorf("unknown STORAGE_BACKEND %q, expected mongo or postgres", storage.Backend)
+	}
+}
+
 func main() {
-	app, err := NewApplication()
+	// Arguments that are not flags name a maintenance command
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app, err := NewApplication(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize application: %v", err)
 	}
 	defer app.Cleanup()
 
-	// Start the Gin server
-	if err := app.Router.Run(":8085"); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	if err := serve(cfg, app); err != nil {
+		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/natneam/crypto-wallet-app/backend/internal/config"
+)
+
+// Worker is a background loop that runs while the server is up.
+type Worker interface {
+	Start()
+
+	// Shutdown stops the worker from taking new work and waits for the work
+	// in progress to finish, or for ctx to expire.
+	Shutdown(ctx context.Context) error
+}
+
+// serve runs the HTTP servers and the workers until SIGINT or SIGTERM. It
+// then reports the instance not ready for the drain delay, so the load
+// balancer stops sending it requests, and waits up to the shutdown timeout
+// for in-flight requests, such as transactions waiting to be mined, and the
+// workers to finish. A second signal exits immediately.
+func serve(cfg *config.Config, app *Application) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           app.Router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	adminServer := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.AdminPort),
+		Handler:           app.AdminRouter,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	adminListener, err := net.Listen("tcp", adminServer.Addr)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	for _, worker := range app.Workers {
+		worker.Start()
+	}
+	serveErr := make(chan error, 2)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	go func() {
+		serveErr <- adminServer.Serve(adminListener)
+	}()
+	app.Service.SetReady(true)
+	log.Printf("Listening on %s, admin routes on %s", listener.Addr(), adminListener.Addr())
+
+	select {
+	case err = <-serveErr:
+		// A server stopped on its own, so the instance is not worth draining
+		app.Service.SetReady(false)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+		defer cancel()
+		return errors.Join(err, server.Close(), adminServer.Close(), shutdownWorkers(shutdownCtx, app.Workers))
+	case <-ctx.Done():
+	}
+
+	// Restore the default signal handling, so a second signal kills the process
+	stop()
+	log.Printf("Shutting down, draining for %s", cfg.DrainDelay)
+	app.Service.SetReady(false)
+	time.Sleep(cfg.DrainDelay.Duration)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+
+	// Requests and workers are drained together, under the same deadline
+	var serverErr, workersErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			serverErr = fmt.Errorf("failed to drain requests: %v", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		workersErr = shutdownWorkers(shutdownCtx, app.Workers)
+	}()
+	wg.Wait()
+
+	// Metrics stay scrapable until the requests are drained
+	if err := adminServer.Shutdown(shutdownCtx); err != nil {
+		serverErr = errors.Join(serverErr, fmt.Errorf("failed to stop the admin server: %v", err))
+	}
+
+	if err := errors.Join(serverErr, workersErr); err != nil {
+		return err
+	}
+	log.Println("Shutdown complete")
+	return nil
+}
+
+func shutdownWorkers(ctx context.Context, workers []Worker) error {
+	errs := make([]error, len(workers))
+	var wg sync.WaitGroup
+	for i, worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := worker.Shutdown(ctx); err != nil {
+				errs[i] = fmt.Errorf("failed to stop worker: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}